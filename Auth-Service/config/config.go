@@ -2,13 +2,17 @@ package config
 
 import (
 	"context"
+	"errors"
 	"os"
 	"time"
 	"fmt"
 
+	"github.com/ansh0014/auth/metrics"
+	"github.com/ansh0014/auth/tracing"
 	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var (
@@ -26,6 +30,8 @@ func InitRedis() error {
     }
     
     RedisClient = redis.NewClient(opt)
+    RedisClient.AddHook(tracing.RedisHook{})
+    RedisClient.AddHook(metrics.RedisHook{})
     
     // Ping Redis to verify connection
     _, err = RedisClient.Ping(Ctx).Result()
@@ -42,7 +48,7 @@ func InitMongo() error {
         return fmt.Errorf("MONGODB_URI environment variable is not set")
     }
 
-    client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+    client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI).SetMonitor(metrics.MongoMonitor(otelmongo.NewMonitor())))
     if err != nil {
         return err
     }
@@ -62,4 +68,16 @@ func InitMongo() error {
     MongoClient = client
     MongoDB = client.Database(dbName)
     return nil
+}
+
+// Close releases the Mongo and Redis connections.
+func Close(ctx context.Context) error {
+    var errs []error
+    if RedisClient != nil {
+        errs = append(errs, RedisClient.Close())
+    }
+    if MongoClient != nil {
+        errs = append(errs, MongoClient.Disconnect(ctx))
+    }
+    return errors.Join(errs...)
 }
\ No newline at end of file
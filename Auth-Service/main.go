@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ansh0014/auth/config"
 	"github.com/ansh0014/auth/router"
+	"github.com/ansh0014/auth/tracing"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	// Load .env file
 	err := godotenv.Load("D:\\Ticket-System\\Ticket-system\\Auth-Service\\.env")
 	if err != nil {
@@ -18,6 +26,12 @@ func main() {
 		// Continue execution, don't exit
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatalf("Tracing setup failed: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	config.InitRedis()
 	if err := config.InitMongo(); err != nil {
 		log.Fatalf("MongoDB connection failed: %v", err)
@@ -29,6 +43,35 @@ func main() {
 	if port == "" {
 		port = "8001"
 	}
-	log.Printf("Auth service running on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      r,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Auth service running on port %s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	// drain in-flight requests, then release the data stores
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	log.Println("Shutting down auth service...")
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Forced shutdown: %v", err)
+	}
+	if err := config.Close(shutdownCtx); err != nil {
+		log.Printf("Closing data stores: %v", err)
+	}
 }
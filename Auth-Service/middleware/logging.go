@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ansh0014/auth/metrics"
+	"github.com/ansh0014/auth/utils"
+)
+
+// RequestID reuses the X-Request-ID set by the gateway or generates one,
+// stores it in the context and echoes it on the response
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(utils.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = utils.NewRequestID()
+			r.Header.Set(utils.RequestIDHeader, requestID)
+		}
+		w.Header().Set(utils.RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(utils.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// Logging writes one JSON access log line per request and records it in the
+// HTTP metrics. When next is a ServeMux the matched pattern is used as the route.
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(start)
+		route := ""
+		if m, ok := next.(*http.ServeMux); ok {
+			if _, pattern := m.Handler(r); pattern != "" {
+				route = pattern
+			}
+		}
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("request_id", utils.GetRequestIDFromContext(r.Context())),
+			slog.String("user_id", r.Header.Get("X-User-ID")),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+		)
+		metrics.ObserveHTTP(r.Method, route, rec.status, elapsed)
+	})
+}
+
+// statusRecorder captures the status code and response size for access logs
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming responses
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		s.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack supports upgraded connections
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := s.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijack not supported")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -3,7 +3,13 @@ package router
 import (
 	"net/http"
 
+	"github.com/ansh0014/auth/config"
 	"github.com/ansh0014/auth/handler"
+	"github.com/ansh0014/auth/health"
+	"github.com/ansh0014/auth/metrics"
+	"github.com/ansh0014/auth/middleware"
+	"github.com/ansh0014/auth/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func SetupRoutes() http.Handler {
@@ -11,6 +17,20 @@ func SetupRoutes() http.Handler {
 	mux.HandleFunc("/auth/register", handler.RegisterHandler)
 	mux.HandleFunc("/auth/verify-otp", handler.VerifyOTPHandler)
 	mux.HandleFunc("/auth/login", handler.LoginHandler)           // If you have JWT login
-	
-	return mux
+	mux.Handle("/metrics", metrics.Handler())
+
+	checker := health.New(tracing.ServiceName).
+		Add("mongo", health.Mongo(config.MongoClient)).
+		Add("redis", health.Redis(config.RedisClient))
+	mux.HandleFunc("/health/live", checker.Live)
+	mux.HandleFunc("/health/ready", checker.Ready)
+
+	traced := otelhttp.NewHandler(middleware.Logging(mux), tracing.ServiceName,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				return r.Method + " " + pattern
+			}
+			return r.Method
+		}))
+	return middleware.RequestID(traced)
 }
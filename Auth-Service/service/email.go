@@ -6,6 +6,8 @@ import (
 	"net/smtp"
 	"os"
 	"strings"
+
+	"github.com/ansh0014/auth/metrics"
 )
 
 // SendOTP sends an OTP code to the provided email address
@@ -17,6 +19,7 @@ func SendOTP(email, otpCode string) error {
 	// If SMTP is not configured or explicitly set to console mode, log to console
 	if smtpHost == "" || smtpUsername == "" || os.Getenv("OTP_DELIVERY_METHOD") == "console" {
 		log.Printf("[TEST MODE] OTP for %s: %s", email, otpCode)
+		metrics.OTPsSent.WithLabelValues("console").Inc()
 		return nil
 	}
 
@@ -51,9 +54,11 @@ func SendOTP(email, otpCode string) error {
 	if err != nil {
 		log.Printf("Failed to send email: %v. Falling back to console delivery.", err)
 		log.Printf("[FALLBACK] OTP for %s: %s", email, otpCode)
+		metrics.OTPsSent.WithLabelValues("fallback").Inc()
 		return nil // Return nil to prevent authentication failures when SMTP fails
 	}
 
+	metrics.OTPsSent.WithLabelValues("email").Inc()
 	return nil
 }
 
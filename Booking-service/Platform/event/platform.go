@@ -0,0 +1,133 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	_ platform.Platform   = (*Service)(nil)
+	_ platform.Reconciler = (*Service)(nil)
+)
+
+// Name implements platform.Platform
+func (s *Service) Name() string {
+	return "event"
+}
+
+// HoldPolicy implements platform.Platform
+func (s *Service) HoldPolicy() hold.Policy {
+	return hold.Policy{TTL: s.seatLockTime, Max: s.maxHoldTime}
+}
+
+// Search implements platform.Platform; the body is a SearchEventsRequest
+func (s *Service) Search(ctx context.Context, q platform.SearchQuery) (*platform.SearchResult, error) {
+	var req SearchEventsRequest
+	if len(q.Body) > 0 {
+		if err := json.Unmarshal(q.Body, &req); err != nil {
+			return nil, err
+		}
+	}
+	items, total, err := s.SearchEvents(ctx, req, q.Page, q.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &platform.SearchResult{Items: items, Total: total}, nil
+}
+
+// Inventory implements platform.Platform
+func (s *Service) Inventory(ctx context.Context, eventID string) (*platform.Inventory, error) {
+	details, err := s.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	seats, err := s.GetEventSeats(ctx, eventID, "")
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.holds.Locked(ctx, s.Name(), eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &platform.Inventory{ID: eventID, Platform: s.Name(), Details: details, Seats: make([]platform.Seat, 0, len(seats))}
+	inv.Display = platform.Display{Title: details.Event.Title, Venue: details.Event.Venue.Name, StartsAt: details.Event.StartTime}
+
+	// Seats without their own price sell at their ticket type's price
+	ticketPrices := make(map[primitive.ObjectID]float64, len(details.Event.TicketTypes))
+	for _, tt := range details.Event.TicketTypes {
+		ticketPrices[tt.ID] = tt.Price
+		if inv.Currency == "" {
+			inv.Currency = tt.Currency
+		}
+	}
+	for _, seat := range seats {
+		id := seat.ID.Hex()
+		price := seat.Price
+		if price == 0 {
+			price = ticketPrices[seat.TicketTypeID]
+		}
+		inv.Seats = append(inv.Seats, platform.Seat{
+			ID:     id,
+			Label:  seat.Section + "-" + seat.SeatNumber,
+			Class:  seat.Section,
+			Price:  price,
+			Status: platform.SeatStatus(seat.IsAvailable, locked[id] != ""),
+		})
+	}
+	return inv, nil
+}
+
+// Lock implements platform.Platform
+func (s *Service) Lock(ctx context.Context, eventID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	return s.LockEventSeats(ctx, eventID, "", seatIDs, userID)
+}
+
+// Confirm implements platform.Platform
+func (s *Service) Confirm(ctx context.Context, eventID string, seatIDs []string, userID, holdID string) error {
+	return s.ConfirmSeats(ctx, eventID, "", seatIDs, userID, holdID)
+}
+
+// Release implements platform.Platform
+func (s *Service) Release(ctx context.Context, eventID string, seatIDs []string, userID string) error {
+	return s.ReleaseSeats(ctx, eventID, seatIDs, userID)
+}
+
+// Unsell implements platform.Platform
+func (s *Service) Unsell(ctx context.Context, eventID string, seatIDs []string) error {
+	return s.UnsellSeats(ctx, eventID, seatIDs)
+}
+
+// Quote implements platform.Platform at the listed seat prices
+func (s *Service) Quote(ctx context.Context, eventID string, seatIDs []string) (*platform.Quote, error) {
+	inv, err := s.Inventory(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return platform.QuoteSeats(inv, seatIDs)
+}
+
+// UnavailableSeats implements platform.Reconciler
+func (s *Service) UnavailableSeats(ctx context.Context) (map[string][]string, error) {
+	return platform.HexSeats(s.repo.GetUnavailableSeats(ctx))
+}
+
+// MarkSeats implements platform.Reconciler
+func (s *Service) MarkSeats(ctx context.Context, eventID string, seatIDs []string, available bool) (int, error) {
+	eventObjID, seatObjIDs, err := platform.ParseObjectIDs("event", eventID, seatIDs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.repo.SetSeatsAvailability(ctx, eventObjID, seatObjIDs, available)
+	return int(n), err
+}
+
+// RecountSeats implements platform.Reconciler
+func (s *Service) RecountSeats(ctx context.Context) ([]string, error) {
+	fixed, err := s.repo.RecountEventSeats(ctx)
+	return platform.HexIDs(fixed), err
+}
@@ -281,7 +281,6 @@ func (r *Repository) GetTicketTypes(ctx context.Context, eventID primitive.Objec
 func (r *Repository) LockEventSeats(ctx context.Context, eventID primitive.ObjectID, ticketTypeID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
     filter := bson.M{
         "event_id":     eventID,
-        "_id":          bson.M{"$in": seatIDs},
         "is_available": true,
     }
 
@@ -297,33 +296,24 @@ func (r *Repository) LockEventSeats(ctx context.Context, eventID primitive.Objec
         },
     }
 
-    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
-    if err != nil {
-        return err
-    }
-
-    // Check if all seats were updated
-    if result.ModifiedCount != int64(len(seatIDs)) {
-        // Revert the changes
-        revertFilter := bson.M{
-            "event_id": eventID,
-            "_id":      bson.M{"$in": seatIDs},
+    // Take the seats one at a time, so a seat that is already gone undoes
+    // only the seats this call took and never one another booking owns
+    taken := make([]primitive.ObjectID, 0, len(seatIDs))
+    for _, seatID := range seatIDs {
+        filter["_id"] = seatID
+        result, err := r.seatsColl.UpdateOne(ctx, filter, update)
+        if err == nil && result.ModifiedCount == 0 {
+            err = errors.New("some seats are no longer available")
         }
-
-        revertUpdate := bson.M{
-            "$set": bson.M{
-                "is_available": true,
-                "updated_at":   time.Now(),
-            },
+        if err != nil {
+            r.revertSeats(ctx, eventID, taken)
+            return err
         }
-
-        _, _ = r.seatsColl.UpdateMany(ctx, revertFilter, revertUpdate)
-
-        return errors.New("some seats are no longer available")
+        taken = append(taken, seatID)
     }
 
     // Update available seats count on the event
-    _, err = r.eventsColl.UpdateOne(
+    _, err := r.eventsColl.UpdateOne(
         ctx,
         bson.M{"_id": eventID},
         bson.M{
@@ -335,11 +325,42 @@ func (r *Repository) LockEventSeats(ctx context.Context, eventID primitive.Objec
     return err
 }
 
+// SeatsAvailable reports whether every seat in seatIDs is still on sale
+func (r *Repository) SeatsAvailable(ctx context.Context, eventID primitive.ObjectID, seatIDs []primitive.ObjectID) (bool, error) {
+    n, err := r.seatsColl.CountDocuments(ctx, bson.M{
+        "event_id":     eventID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": true,
+    })
+    if err != nil {
+        return false, err
+    }
+    return n == int64(len(seatIDs)), nil
+}
+
+// revertSeats puts back on sale the seats a failed lock already took
+func (r *Repository) revertSeats(ctx context.Context, eventID primitive.ObjectID, seatIDs []primitive.ObjectID) {
+    if len(seatIDs) == 0 {
+        return
+    }
+    _, _ = r.seatsColl.UpdateMany(ctx, bson.M{
+        "event_id":     eventID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": false,
+    }, bson.M{
+        "$set": bson.M{
+            "is_available": true,
+            "updated_at":   time.Now(),
+        },
+    })
+}
+
 // UnlockEventSeats unlocks previously locked seats
 func (r *Repository) UnlockEventSeats(ctx context.Context, eventID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
     filter := bson.M{
-        "event_id": eventID,
-        "_id":      bson.M{"$in": seatIDs},
+        "event_id":     eventID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": false,
     }
 
     update := bson.M{
@@ -349,17 +370,21 @@ func (r *Repository) UnlockEventSeats(ctx context.Context, eventID primitive.Obj
         },
     }
 
-    _, err := r.seatsColl.UpdateMany(ctx, filter, update)
+    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
     if err != nil {
         return err
     }
+    // Seats already on sale must not move the counters again
+    if result.ModifiedCount == 0 {
+        return nil
+    }
 
     // Update available seats count on the event
     _, err = r.eventsColl.UpdateOne(
         ctx,
         bson.M{"_id": eventID},
         bson.M{
-            "$inc": bson.M{"available_seats": len(seatIDs)},
+            "$inc": bson.M{"available_seats": result.ModifiedCount},
             "$set": bson.M{"updated_at": time.Now()},
         },
     )
@@ -437,4 +462,96 @@ func (r *Repository) GetSeatsByIDs(ctx context.Context, eventID primitive.Object
     }
 
     return seats, nil
-}
\ No newline at end of file
+}
+
+// GetUnavailableSeats lists the seats marked unavailable, by event
+func (r *Repository) GetUnavailableSeats(ctx context.Context) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+    opts := options.Find().SetProjection(bson.M{"_id": 1, "event_id": 1})
+
+    cursor, err := r.seatsColl.Find(ctx, bson.M{"is_available": false}, opts)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var seats []EventSeat
+    if err = cursor.All(ctx, &seats); err != nil {
+        return nil, err
+    }
+
+    unavailable := make(map[primitive.ObjectID][]primitive.ObjectID)
+    for _, seat := range seats {
+        unavailable[seat.EventID] = append(unavailable[seat.EventID], seat.ID)
+    }
+
+    return unavailable, nil
+}
+
+// SetSeatsAvailability sets seat flags without touching the event's counters
+// and returns how many seats changed
+func (r *Repository) SetSeatsAvailability(ctx context.Context, eventID primitive.ObjectID, seatIDs []primitive.ObjectID, available bool) (int64, error) {
+    filter := bson.M{
+        "event_id":     eventID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": !available,
+    }
+
+    update := bson.M{
+        "$set": bson.M{
+            "is_available": available,
+            "updated_at":   time.Now(),
+        },
+    }
+
+    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
+    if err != nil {
+        return 0, err
+    }
+
+    return result.ModifiedCount, nil
+}
+
+// RecountEventSeats resets every event's seat counters from its seat flags
+// and returns the events whose counters were wrong
+func (r *Repository) RecountEventSeats(ctx context.Context) ([]primitive.ObjectID, error) {
+    pipeline := []bson.M{
+        {"$group": bson.M{
+            "_id":       "$event_id",
+            "available": bson.M{"$sum": bson.M{"$cond": bson.A{"$is_available", 1, 0}}},
+        }},
+    }
+
+    cursor, err := r.seatsColl.Aggregate(ctx, pipeline)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var counts []struct {
+        ID        primitive.ObjectID `bson:"_id"`
+        Available int                `bson:"available"`
+    }
+    if err = cursor.All(ctx, &counts); err != nil {
+        return nil, err
+    }
+
+    var fixed []primitive.ObjectID
+    for _, c := range counts {
+        result, err := r.eventsColl.UpdateOne(
+            ctx,
+            bson.M{"_id": c.ID, "available_seats": bson.M{"$ne": c.Available}},
+            bson.M{"$set": bson.M{
+                "available_seats": c.Available,
+                "updated_at":      time.Now(),
+            }},
+        )
+        if err != nil {
+            return fixed, err
+        }
+        if result.ModifiedCount > 0 {
+            fixed = append(fixed, c.ID)
+        }
+    }
+
+    return fixed, nil
+}
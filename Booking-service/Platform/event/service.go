@@ -3,26 +3,38 @@ package event
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/metrics"
+	"github.com/ansh0014/booking/stream"
+	"github.com/ansh0014/booking/tracing"
 	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("github.com/ansh0014/booking/Platform/event")
+
 // Service handles event business logic
 type Service struct {
 	repo         *Repository
 	redisClient  *redis.Client
+	holds        *hold.Store
 	seatLockTime time.Duration
+	maxHoldTime  time.Duration
 }
 
 // NewService creates a new event service
-func NewService(repo *Repository, redisClient *redis.Client) *Service {
+func NewService(repo *Repository, redisClient *redis.Client, holds *hold.Store) *Service {
 	return &Service{
 		repo:         repo,
 		redisClient:  redisClient,
-		seatLockTime: 5 * time.Minute, // Default 5 minutes lock time
+		holds:        holds,
+		seatLockTime: 5 * time.Minute,  // Default 5 minutes lock time
+		maxHoldTime:  15 * time.Minute, // Extensions stop 15 minutes after the lock
 	}
 }
 
@@ -113,77 +125,69 @@ func (s *Service) GetTicketTypes(ctx context.Context, eventID string) ([]TicketT
 }
 
 // LockEventSeats temporarily locks seats for a booking
-func (s *Service) LockEventSeats(ctx context.Context, eventID string, ticketTypeID string, seatIDs []string, userID string) error {
+func (s *Service) LockEventSeats(ctx context.Context, eventID string, ticketTypeID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	ctx, span := tracer.Start(ctx, "event.LockEventSeats", trace.WithAttributes(
+		attribute.Int("booking.seat_count", len(seatIDs)),
+	))
+	defer span.End()
+
 	if eventID == "" {
-		return errors.New("event ID is required")
+		return nil, errors.New("event ID is required")
 	}
 
 	if len(seatIDs) == 0 {
-		return errors.New("at least one seat must be selected")
+		return nil, errors.New("at least one seat must be selected")
 	}
 
 	if userID == "" {
-		return errors.New("user ID is required")
+		return nil, errors.New("user ID is required")
 	}
 
 	// Validate event ID
-	_, err := primitive.ObjectIDFromHex(eventID)
+	eventObjID, err := primitive.ObjectIDFromHex(eventID)
 	if err != nil {
-		return errors.New("invalid event ID")
+		return nil, errors.New("invalid event ID")
 	}
 
 	seatObjIDs := make([]primitive.ObjectID, 0, len(seatIDs))
 	for _, id := range seatIDs {
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
-			return errors.New("invalid seat ID: " + id)
+			return nil, errors.New("invalid seat ID: " + id)
 		}
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Check if seats are already locked in Redis
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("event_seat_lock:%s:%s", eventID, seatID)
-
-		// Check if the lock exists
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val != "" && val != userID {
-			// Seat is locked by someone else
-			return errors.New("one or more selected seats are no longer available")
-		}
+	// Sold seats can't be held
+	available, err := s.repo.SeatsAvailable(ctx, eventObjID, seatObjIDs)
+	if err != nil {
+		return nil, errors.New("failed to lock seats: " + err.Error())
 	}
-
-	// Set Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("event_seat_lock:%s:%s", eventID, seatID)
-
-		// Set the lock with expiration
-		err := s.redisClient.Set(ctx, lockKey, userID, s.seatLockTime).Err()
-		if err != nil {
-			// If any lock fails, clean up and return error
-			s.unlockSeats(ctx, eventID, seatIDs, userID)
-			return errors.New("failed to lock seats: " + err.Error())
-		}
+	if !available {
+		return nil, errors.New("one or more selected seats are no longer available")
 	}
 
-	return nil
-}
-
-// unlockSeats helper to remove Redis locks
-func (s *Service) unlockSeats(ctx context.Context, eventID string, seatIDs []string, userID string) {
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("event_seat_lock:%s:%s", eventID, seatID)
+	metrics.SeatLockAttempts.WithLabelValues("event").Inc()
 
-		// Only remove if the lock belongs to this user
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val == userID {
-			s.redisClient.Del(ctx, lockKey)
+	// Hold every seat or none
+	h, err := s.holds.Hold(ctx, hold.Request{Owner: userID, Platform: "event", InventoryID: eventID, Seats: seatIDs, TTL: s.seatLockTime})
+	if err != nil {
+		if errors.Is(err, hold.ErrConflict) {
+			// Seat is held by someone else
+			metrics.SeatLockConflicts.WithLabelValues("event").Inc()
+			span.SetStatus(codes.Error, "seat conflict")
+			return nil, errors.New("one or more selected seats are no longer available")
 		}
+		return nil, errors.New("failed to lock seats: " + err.Error())
 	}
+
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsLocked, Platform: "event", InventoryID: eventID, SeatIDs: seatIDs})
+	return h, nil
 }
 
-// ConfirmSeats permanently reserves seats after payment
-func (s *Service) ConfirmSeats(ctx context.Context, eventID string, ticketTypeID string, seatIDs []string) error {
+// ConfirmSeats permanently reserves seats after payment. The seats must
+// still be held by userID's hold holdID.
+func (s *Service) ConfirmSeats(ctx context.Context, eventID string, ticketTypeID string, seatIDs []string, userID, holdID string) error {
 	// Convert string IDs to ObjectIDs
 	eventObjID, err := primitive.ObjectIDFromHex(eventID)
 	if err != nil {
@@ -207,18 +211,39 @@ func (s *Service) ConfirmSeats(ctx context.Context, eventID string, ticketTypeID
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Remove Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("event_seat_lock:%s:%s", eventID, seatID)
-		s.redisClient.Del(ctx, lockKey)
+	// Sell the seats first, so a seat someone else already bought keeps
+	// this hold alive for the caller to release
+	if err := s.repo.LockEventSeats(ctx, eventObjID, ticketTypeObjID, seatObjIDs); err != nil {
+		return err
+	}
+
+	// End the hold on these seats. Without it they may be someone else's
+	// by now, so they go back on sale.
+	if err := s.holds.Confirm(ctx, "event", eventID, seatIDs, userID, holdID); err != nil {
+		s.repo.UnlockEventSeats(ctx, eventObjID, seatObjIDs)
+		return err
 	}
 
-	// Update the database
-	return s.repo.LockEventSeats(ctx, eventObjID, ticketTypeObjID, seatObjIDs)
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsConfirmed, Platform: "event", InventoryID: eventID, SeatIDs: seatIDs})
+	return nil
 }
 
-// ReleaseSeats releases previously locked seats
+// ReleaseSeats frees the seats userID holds. Seats held by someone else or
+// already sold are left alone.
 func (s *Service) ReleaseSeats(ctx context.Context, eventID string, seatIDs []string, userID string) error {
+	freed, err := s.holds.Release(ctx, userID, "event", eventID, seatIDs)
+	if err != nil {
+		return err
+	}
+	if len(freed) > 0 {
+		stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsReleased, Platform: "event", InventoryID: eventID, SeatIDs: freed})
+	}
+	return nil
+}
+
+// UnsellSeats puts sold seats back on sale. Only cancelled and refunded
+// bookings give their seats back this way.
+func (s *Service) UnsellSeats(ctx context.Context, eventID string, seatIDs []string) error {
 	// Convert string IDs to ObjectIDs
 	eventObjID, err := primitive.ObjectIDFromHex(eventID)
 	if err != nil {
@@ -234,17 +259,10 @@ func (s *Service) ReleaseSeats(ctx context.Context, eventID string, seatIDs []st
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Remove Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("event_seat_lock:%s:%s", eventID, seatID)
-
-		// Only remove if the lock belongs to this user
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val == userID {
-			s.redisClient.Del(ctx, lockKey)
-		}
+	if err := s.repo.UnlockEventSeats(ctx, eventObjID, seatObjIDs); err != nil {
+		return err
 	}
 
-	// Update the database if these were permanent locks
-	return s.repo.UnlockEventSeats(ctx, eventObjID, seatObjIDs)
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsReleased, Platform: "event", InventoryID: eventID, SeatIDs: seatIDs})
+	return nil
 }
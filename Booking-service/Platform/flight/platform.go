@@ -0,0 +1,142 @@
+package flight
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+)
+
+var (
+	_ platform.Platform   = (*Service)(nil)
+	_ platform.Reconciler = (*Service)(nil)
+)
+
+// Name implements platform.Platform
+func (s *Service) Name() string {
+	return "flight"
+}
+
+// HoldPolicy implements platform.Platform
+func (s *Service) HoldPolicy() hold.Policy {
+	return hold.Policy{TTL: s.seatLockTime, Max: s.maxHoldTime}
+}
+
+// Search implements platform.Platform; the body is a SearchFlightsRequest
+func (s *Service) Search(ctx context.Context, q platform.SearchQuery) (*platform.SearchResult, error) {
+	var req SearchFlightsRequest
+	if len(q.Body) > 0 {
+		if err := json.Unmarshal(q.Body, &req); err != nil {
+			return nil, err
+		}
+	}
+	items, err := s.SearchFlights(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &platform.SearchResult{Items: items, Total: int64(len(items))}, nil
+}
+
+// Inventory implements platform.Platform
+func (s *Service) Inventory(ctx context.Context, flightID string) (*platform.Inventory, error) {
+	details, err := s.GetFlightByID(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+	seats, err := s.GetFlightSeats(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.holds.Locked(ctx, s.Name(), flightID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &platform.Inventory{ID: flightID, Platform: s.Name(), Details: details, Seats: make([]platform.Seat, 0, len(seats))}
+	f := details.Flight
+	inv.Display = platform.Display{
+		Title:    strings.TrimSpace(f.Airline.Name + " " + f.FlightNumber),
+		Venue:    f.Origin.Code + " - " + f.Destination.Code,
+		StartsAt: f.DepartureTime,
+	}
+	for _, seat := range seats {
+		id := seat.ID.Hex()
+		// Seats without their own price sell at the flight's fare for the class
+		price := seat.Price
+		if price == 0 {
+			price = classFare(f, seat.Class)
+		}
+		inv.Seats = append(inv.Seats, platform.Seat{
+			ID:     id,
+			Label:  seat.SeatNumber,
+			Class:  seat.Class,
+			Price:  price,
+			Status: platform.SeatStatus(seat.IsAvailable, locked[id] != ""),
+		})
+	}
+	return inv, nil
+}
+
+// classFare is the flight's fare for a seat class
+func classFare(f *Flight, class string) float64 {
+	switch class {
+	case "business":
+		return f.PriceBusiness
+	case "first":
+		return f.PriceFirstClass
+	default:
+		return f.PriceEconomy
+	}
+}
+
+// Lock implements platform.Platform
+func (s *Service) Lock(ctx context.Context, flightID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	return s.LockFlightSeats(ctx, flightID, seatIDs, userID)
+}
+
+// Confirm implements platform.Platform
+func (s *Service) Confirm(ctx context.Context, flightID string, seatIDs []string, userID, holdID string) error {
+	return s.ConfirmSeats(ctx, flightID, seatIDs, userID, holdID)
+}
+
+// Release implements platform.Platform
+func (s *Service) Release(ctx context.Context, flightID string, seatIDs []string, userID string) error {
+	return s.ReleaseSeats(ctx, flightID, seatIDs, userID)
+}
+
+// Unsell implements platform.Platform
+func (s *Service) Unsell(ctx context.Context, flightID string, seatIDs []string) error {
+	return s.UnsellSeats(ctx, flightID, seatIDs)
+}
+
+// Quote implements platform.Platform at the listed seat prices
+func (s *Service) Quote(ctx context.Context, flightID string, seatIDs []string) (*platform.Quote, error) {
+	inv, err := s.Inventory(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+	return platform.QuoteSeats(inv, seatIDs)
+}
+
+// UnavailableSeats implements platform.Reconciler
+func (s *Service) UnavailableSeats(ctx context.Context) (map[string][]string, error) {
+	return platform.HexSeats(s.repo.GetUnavailableSeats(ctx))
+}
+
+// MarkSeats implements platform.Reconciler
+func (s *Service) MarkSeats(ctx context.Context, flightID string, seatIDs []string, available bool) (int, error) {
+	flightObjID, seatObjIDs, err := platform.ParseObjectIDs("flight", flightID, seatIDs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.repo.SetSeatsAvailability(ctx, flightObjID, seatObjIDs, available)
+	return int(n), err
+}
+
+// RecountSeats implements platform.Reconciler
+func (s *Service) RecountSeats(ctx context.Context) ([]string, error) {
+	fixed, err := s.repo.RecountFlightSeats(ctx)
+	return platform.HexIDs(fixed), err
+}
@@ -143,7 +143,6 @@ func (r *Repository) GetFlightSeats(ctx context.Context, flightID primitive.Obje
 func (r *Repository) LockFlightSeats(ctx context.Context, flightID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
 	filter := bson.M{
 		"flight_id":    flightID,
-		"_id":          bson.M{"$in": seatIDs},
 		"is_available": true,
 	}
 
@@ -154,33 +153,24 @@ func (r *Repository) LockFlightSeats(ctx context.Context, flightID primitive.Obj
 		},
 	}
 
-	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
-	if err != nil {
-		return err
-	}
-
-	// Check if all seats were updated
-	if result.ModifiedCount != int64(len(seatIDs)) {
-		// Revert the changes
-		revertFilter := bson.M{
-			"flight_id": flightID,
-			"_id":       bson.M{"$in": seatIDs},
+	// Take the seats one at a time, so a seat that is already gone undoes
+	// only the seats this call took and never one another booking owns
+	taken := make([]primitive.ObjectID, 0, len(seatIDs))
+	for _, seatID := range seatIDs {
+		filter["_id"] = seatID
+		result, err := r.seatsColl.UpdateOne(ctx, filter, update)
+		if err == nil && result.ModifiedCount == 0 {
+			err = errors.New("some seats are no longer available")
 		}
-
-		revertUpdate := bson.M{
-			"$set": bson.M{
-				"is_available": true,
-				"updated_at":   time.Now(),
-			},
+		if err != nil {
+			r.revertSeats(ctx, flightID, taken)
+			return err
 		}
-
-		_, _ = r.seatsColl.UpdateMany(ctx, revertFilter, revertUpdate)
-
-		return errors.New("some seats are no longer available")
+		taken = append(taken, seatID)
 	}
 
 	// Update available seats count on the flight
-	_, err = r.flightsColl.UpdateOne(
+	_, err := r.flightsColl.UpdateOne(
 		ctx,
 		bson.M{"_id": flightID},
 		bson.M{
@@ -192,6 +182,36 @@ func (r *Repository) LockFlightSeats(ctx context.Context, flightID primitive.Obj
 	return err
 }
 
+// SeatsAvailable reports whether every seat in seatIDs is still on sale
+func (r *Repository) SeatsAvailable(ctx context.Context, flightID primitive.ObjectID, seatIDs []primitive.ObjectID) (bool, error) {
+	n, err := r.seatsColl.CountDocuments(ctx, bson.M{
+		"flight_id":    flightID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": true,
+	})
+	if err != nil {
+		return false, err
+	}
+	return n == int64(len(seatIDs)), nil
+}
+
+// revertSeats puts back on sale the seats a failed lock already took
+func (r *Repository) revertSeats(ctx context.Context, flightID primitive.ObjectID, seatIDs []primitive.ObjectID) {
+	if len(seatIDs) == 0 {
+		return
+	}
+	_, _ = r.seatsColl.UpdateMany(ctx, bson.M{
+		"flight_id":    flightID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": false,
+	}, bson.M{
+		"$set": bson.M{
+			"is_available": true,
+			"updated_at":   time.Now(),
+		},
+	})
+}
+
 // GetFlightByID retrieves a flight by ID
 func (r *Repository) GetFlightByID(ctx context.Context, id primitive.ObjectID) (*Flight, error) {
 	var flight Flight
@@ -243,8 +263,9 @@ func (r *Repository) getAirportByID(ctx context.Context, id primitive.ObjectID)
 // UnlockFlightSeats unlocks previously locked seats
 func (r *Repository) UnlockFlightSeats(ctx context.Context, flightID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
 	filter := bson.M{
-		"flight_id": flightID,
-		"_id":       bson.M{"$in": seatIDs},
+		"flight_id":    flightID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": false,
 	}
 
 	update := bson.M{
@@ -254,17 +275,21 @@ func (r *Repository) UnlockFlightSeats(ctx context.Context, flightID primitive.O
 		},
 	}
 
-	_, err := r.seatsColl.UpdateMany(ctx, filter, update)
+	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	// Seats already on sale must not move the counters again
+	if result.ModifiedCount == 0 {
+		return nil
+	}
 
 	// Update available seats count on the flight
 	_, err = r.flightsColl.UpdateOne(
 		ctx,
 		bson.M{"_id": flightID},
 		bson.M{
-			"$inc": bson.M{"available_seats": len(seatIDs)},
+			"$inc": bson.M{"available_seats": result.ModifiedCount},
 			"$set": bson.M{"updated_at": time.Now()},
 		},
 	)
@@ -319,3 +344,95 @@ func (r *Repository) UpdateFlight(ctx context.Context, flight *Flight) error {
 
 	return err
 }
+
+// GetUnavailableSeats lists the seats marked unavailable, by flight
+func (r *Repository) GetUnavailableSeats(ctx context.Context) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "flight_id": 1})
+
+	cursor, err := r.seatsColl.Find(ctx, bson.M{"is_available": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var seats []FlightSeat
+	if err = cursor.All(ctx, &seats); err != nil {
+		return nil, err
+	}
+
+	unavailable := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, seat := range seats {
+		unavailable[seat.FlightID] = append(unavailable[seat.FlightID], seat.ID)
+	}
+
+	return unavailable, nil
+}
+
+// SetSeatsAvailability sets seat flags without touching the flight's counters
+// and returns how many seats changed
+func (r *Repository) SetSeatsAvailability(ctx context.Context, flightID primitive.ObjectID, seatIDs []primitive.ObjectID, available bool) (int64, error) {
+	filter := bson.M{
+		"flight_id":    flightID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": !available,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_available": available,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// RecountFlightSeats resets every flight's seat counters from its seat flags
+// and returns the flights whose counters were wrong
+func (r *Repository) RecountFlightSeats(ctx context.Context) ([]primitive.ObjectID, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{
+			"_id":       "$flight_id",
+			"available": bson.M{"$sum": bson.M{"$cond": bson.A{"$is_available", 1, 0}}},
+		}},
+	}
+
+	cursor, err := r.seatsColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		Available int                `bson:"available"`
+	}
+	if err = cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	var fixed []primitive.ObjectID
+	for _, c := range counts {
+		result, err := r.flightsColl.UpdateOne(
+			ctx,
+			bson.M{"_id": c.ID, "available_seats": bson.M{"$ne": c.Available}},
+			bson.M{"$set": bson.M{
+				"available_seats": c.Available,
+				"updated_at":      time.Now(),
+			}},
+		)
+		if err != nil {
+			return fixed, err
+		}
+		if result.ModifiedCount > 0 {
+			fixed = append(fixed, c.ID)
+		}
+	}
+
+	return fixed, nil
+}
@@ -0,0 +1,128 @@
+package movie
+
+import (
+	"context"
+	"encoding/json"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+)
+
+var (
+	_ platform.Platform   = (*Service)(nil)
+	_ platform.Reconciler = (*Service)(nil)
+)
+
+// Name implements platform.Platform
+func (s *Service) Name() string {
+	return "movie"
+}
+
+// HoldPolicy implements platform.Platform
+func (s *Service) HoldPolicy() hold.Policy {
+	return hold.Policy{TTL: s.seatLockTime, Max: s.maxHoldTime}
+}
+
+// Search implements platform.Platform; the body is a SearchMoviesRequest
+func (s *Service) Search(ctx context.Context, q platform.SearchQuery) (*platform.SearchResult, error) {
+	var req SearchMoviesRequest
+	if len(q.Body) > 0 {
+		if err := json.Unmarshal(q.Body, &req); err != nil {
+			return nil, err
+		}
+	}
+	items, total, err := s.SearchMovies(ctx, req, q.Page, q.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &platform.SearchResult{Items: items, Total: total}, nil
+}
+
+// Inventory implements platform.Platform
+func (s *Service) Inventory(ctx context.Context, showID string) (*platform.Inventory, error) {
+	details, err := s.GetShowByID(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+	seats, err := s.GetShowSeats(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.holds.Locked(ctx, s.Name(), showID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &platform.Inventory{ID: showID, Platform: s.Name(), Details: details, Seats: make([]platform.Seat, 0, len(seats))}
+	venue := details.Theater.Name
+	if details.Screen.Name != "" {
+		venue += " - " + details.Screen.Name
+	}
+	inv.Display = platform.Display{Title: details.Movie.Title, Venue: venue, StartsAt: details.StartTime}
+	for _, seat := range seats {
+		id := seat.ID.Hex()
+		// Seats without their own price sell at the show's base price
+		price := seat.Price
+		if price == 0 {
+			price = details.BasePrice
+		}
+		inv.Seats = append(inv.Seats, platform.Seat{
+			ID:     id,
+			Label:  seat.SeatNumber,
+			Class:  seat.Category,
+			Price:  price,
+			Status: platform.SeatStatus(seat.IsAvailable, locked[id] != ""),
+		})
+	}
+	return inv, nil
+}
+
+// Lock implements platform.Platform
+func (s *Service) Lock(ctx context.Context, showID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	return s.LockShowSeats(ctx, showID, seatIDs, userID)
+}
+
+// Confirm implements platform.Platform
+func (s *Service) Confirm(ctx context.Context, showID string, seatIDs []string, userID, holdID string) error {
+	return s.ConfirmSeats(ctx, showID, seatIDs, userID, holdID)
+}
+
+// Release implements platform.Platform
+func (s *Service) Release(ctx context.Context, showID string, seatIDs []string, userID string) error {
+	return s.ReleaseSeats(ctx, showID, seatIDs, userID)
+}
+
+// Unsell implements platform.Platform
+func (s *Service) Unsell(ctx context.Context, showID string, seatIDs []string) error {
+	return s.UnsellSeats(ctx, showID, seatIDs)
+}
+
+// Quote implements platform.Platform at the listed seat prices
+func (s *Service) Quote(ctx context.Context, showID string, seatIDs []string) (*platform.Quote, error) {
+	inv, err := s.Inventory(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+	return platform.QuoteSeats(inv, seatIDs)
+}
+
+// UnavailableSeats implements platform.Reconciler
+func (s *Service) UnavailableSeats(ctx context.Context) (map[string][]string, error) {
+	return platform.HexSeats(s.repo.GetUnavailableSeats(ctx))
+}
+
+// MarkSeats implements platform.Reconciler
+func (s *Service) MarkSeats(ctx context.Context, showID string, seatIDs []string, available bool) (int, error) {
+	showObjID, seatObjIDs, err := platform.ParseObjectIDs("show", showID, seatIDs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.repo.SetSeatsAvailability(ctx, showObjID, seatObjIDs, available)
+	return int(n), err
+}
+
+// RecountSeats implements platform.Reconciler
+func (s *Service) RecountSeats(ctx context.Context) ([]string, error) {
+	fixed, err := s.repo.RecountShowSeats(ctx)
+	return platform.HexIDs(fixed), err
+}
@@ -257,7 +257,6 @@ func (r *Repository) GetShowSeats(ctx context.Context, showID primitive.ObjectID
 func (r *Repository) LockShowSeats(ctx context.Context, showID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
     filter := bson.M{
         "show_id":      showID,
-        "_id":          bson.M{"$in": seatIDs},
         "is_available": true,
     }
 
@@ -268,33 +267,24 @@ func (r *Repository) LockShowSeats(ctx context.Context, showID primitive.ObjectI
         },
     }
 
-    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
-    if err != nil {
-        return err
-    }
-
-    // Check if all seats were updated
-    if result.ModifiedCount != int64(len(seatIDs)) {
-        // Revert the changes
-        revertFilter := bson.M{
-            "show_id": showID,
-            "_id":     bson.M{"$in": seatIDs},
+    // Take the seats one at a time, so a seat that is already gone undoes
+    // only the seats this call took and never one another booking owns
+    taken := make([]primitive.ObjectID, 0, len(seatIDs))
+    for _, seatID := range seatIDs {
+        filter["_id"] = seatID
+        result, err := r.seatsColl.UpdateOne(ctx, filter, update)
+        if err == nil && result.ModifiedCount == 0 {
+            err = errors.New("some seats are no longer available")
         }
-
-        revertUpdate := bson.M{
-            "$set": bson.M{
-                "is_available": true,
-                "updated_at":   time.Now(),
-            },
+        if err != nil {
+            r.revertSeats(ctx, showID, taken)
+            return err
         }
-
-        _, _ = r.seatsColl.UpdateMany(ctx, revertFilter, revertUpdate)
-
-        return errors.New("some seats are no longer available")
+        taken = append(taken, seatID)
     }
 
     // Update available seats count on the show
-    _, err = r.showsColl.UpdateOne(
+    _, err := r.showsColl.UpdateOne(
         ctx,
         bson.M{"_id": showID},
         bson.M{
@@ -309,11 +299,42 @@ func (r *Repository) LockShowSeats(ctx context.Context, showID primitive.ObjectI
     return err
 }
 
+// SeatsAvailable reports whether every seat in seatIDs is still on sale
+func (r *Repository) SeatsAvailable(ctx context.Context, showID primitive.ObjectID, seatIDs []primitive.ObjectID) (bool, error) {
+    n, err := r.seatsColl.CountDocuments(ctx, bson.M{
+        "show_id":      showID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": true,
+    })
+    if err != nil {
+        return false, err
+    }
+    return n == int64(len(seatIDs)), nil
+}
+
+// revertSeats puts back on sale the seats a failed lock already took
+func (r *Repository) revertSeats(ctx context.Context, showID primitive.ObjectID, seatIDs []primitive.ObjectID) {
+    if len(seatIDs) == 0 {
+        return
+    }
+    _, _ = r.seatsColl.UpdateMany(ctx, bson.M{
+        "show_id":      showID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": false,
+    }, bson.M{
+        "$set": bson.M{
+            "is_available": true,
+            "updated_at":   time.Now(),
+        },
+    })
+}
+
 // UnlockShowSeats unlocks previously locked seats
 func (r *Repository) UnlockShowSeats(ctx context.Context, showID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
     filter := bson.M{
-        "show_id": showID,
-        "_id":     bson.M{"$in": seatIDs},
+        "show_id":      showID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": false,
     }
 
     update := bson.M{
@@ -323,10 +344,14 @@ func (r *Repository) UnlockShowSeats(ctx context.Context, showID primitive.Objec
         },
     }
 
-    _, err := r.seatsColl.UpdateMany(ctx, filter, update)
+    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
     if err != nil {
         return err
     }
+    // Seats already on sale must not move the counters again
+    if result.ModifiedCount == 0 {
+        return nil
+    }
 
     // Update available seats count on the show
     _, err = r.showsColl.UpdateOne(
@@ -334,8 +359,8 @@ func (r *Repository) UnlockShowSeats(ctx context.Context, showID primitive.Objec
         bson.M{"_id": showID},
         bson.M{
             "$inc": bson.M{
-                "avail_seats":  len(seatIDs),
-                "booked_seats": -len(seatIDs),
+                "avail_seats":  result.ModifiedCount,
+                "booked_seats": -result.ModifiedCount,
             },
             "$set": bson.M{"updated_at": time.Now()},
         },
@@ -468,4 +493,106 @@ func (r *Repository) GetTheaterShows(ctx context.Context, theaterID primitive.Ob
     }
 
     return shows, nil
-}
\ No newline at end of file
+}
+
+// GetUnavailableSeats lists the seats marked unavailable, by show
+func (r *Repository) GetUnavailableSeats(ctx context.Context) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+    opts := options.Find().SetProjection(bson.M{"_id": 1, "show_id": 1})
+
+    cursor, err := r.seatsColl.Find(ctx, bson.M{"is_available": false}, opts)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var seats []ShowSeat
+    if err = cursor.All(ctx, &seats); err != nil {
+        return nil, err
+    }
+
+    unavailable := make(map[primitive.ObjectID][]primitive.ObjectID)
+    for _, seat := range seats {
+        unavailable[seat.ShowID] = append(unavailable[seat.ShowID], seat.ID)
+    }
+
+    return unavailable, nil
+}
+
+// SetSeatsAvailability sets seat flags without touching the show's counters
+// and returns how many seats changed
+func (r *Repository) SetSeatsAvailability(ctx context.Context, showID primitive.ObjectID, seatIDs []primitive.ObjectID, available bool) (int64, error) {
+    filter := bson.M{
+        "show_id":      showID,
+        "_id":          bson.M{"$in": seatIDs},
+        "is_available": !available,
+    }
+
+    update := bson.M{
+        "$set": bson.M{
+            "is_available": available,
+            "updated_at":   time.Now(),
+        },
+    }
+
+    result, err := r.seatsColl.UpdateMany(ctx, filter, update)
+    if err != nil {
+        return 0, err
+    }
+
+    return result.ModifiedCount, nil
+}
+
+// RecountShowSeats resets every show's seat counters from its seat flags
+// and returns the shows whose counters were wrong
+func (r *Repository) RecountShowSeats(ctx context.Context) ([]primitive.ObjectID, error) {
+    pipeline := []bson.M{
+        {"$group": bson.M{
+            "_id":       "$show_id",
+            "total":     bson.M{"$sum": 1},
+            "available": bson.M{"$sum": bson.M{"$cond": bson.A{"$is_available", 1, 0}}},
+        }},
+    }
+
+    cursor, err := r.seatsColl.Aggregate(ctx, pipeline)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var counts []struct {
+        ID        primitive.ObjectID `bson:"_id"`
+        Total     int                `bson:"total"`
+        Available int                `bson:"available"`
+    }
+    if err = cursor.All(ctx, &counts); err != nil {
+        return nil, err
+    }
+
+    var fixed []primitive.ObjectID
+    for _, c := range counts {
+        booked := c.Total - c.Available
+        result, err := r.showsColl.UpdateOne(
+            ctx,
+            bson.M{
+                "_id": c.ID,
+                "$or": bson.A{
+                    bson.M{"avail_seats": bson.M{"$ne": c.Available}},
+                    bson.M{"booked_seats": bson.M{"$ne": booked}},
+                },
+            },
+            bson.M{"$set": bson.M{
+                "avail_seats":  c.Available,
+                "booked_seats": booked,
+                "updated_at":   time.Now(),
+            }},
+        )
+        if err != nil {
+            return fixed, err
+        }
+        if result.ModifiedCount > 0 {
+            fixed = append(fixed, c.ID)
+        }
+    }
+
+    return fixed, nil
+}
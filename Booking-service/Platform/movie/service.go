@@ -4,26 +4,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/metrics"
+	"github.com/ansh0014/booking/stream"
+	"github.com/ansh0014/booking/tracing"
 	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("github.com/ansh0014/booking/Platform/movie")
+
 // Service handles movie business logic
 type Service struct {
 	repo         *Repository
 	redisClient  *redis.Client
+	holds        *hold.Store
 	seatLockTime time.Duration
+	maxHoldTime  time.Duration
 }
 
 // NewService creates a new movie service
-func NewService(repo *Repository, redisClient *redis.Client) *Service {
+func NewService(repo *Repository, redisClient *redis.Client, holds *hold.Store) *Service {
 	return &Service{
 		repo:         repo,
 		redisClient:  redisClient,
-		seatLockTime: 5 * time.Minute, // Default 5 minutes lock time
+		holds:        holds,
+		seatLockTime: 5 * time.Minute,  // Default 5 minutes lock time
+		maxHoldTime:  15 * time.Minute, // Extensions stop 15 minutes after the lock
 	}
 }
 
@@ -125,6 +139,24 @@ func (s *Service) GetShowByID(ctx context.Context, showID string) (*Show, error)
 	return s.repo.GetShowByID(ctx, id)
 }
 
+// GetLockedSeats returns the IDs of seats currently held for a show
+func (s *Service) GetLockedSeats(ctx context.Context, showID string) ([]string, error) {
+	if _, err := primitive.ObjectIDFromHex(showID); err != nil {
+		return nil, errors.New("invalid show ID")
+	}
+
+	held, err := s.holds.Locked(ctx, "movie", showID)
+	if err != nil {
+		return nil, err
+	}
+	locked := make([]string, 0, len(held))
+	for seatID := range held {
+		locked = append(locked, seatID)
+	}
+	sort.Strings(locked)
+	return locked, nil
+}
+
 // GetShowSeats retrieves seats for a show
 func (s *Service) GetShowSeats(ctx context.Context, showID string) ([]ShowSeat, error) {
 	id, err := primitive.ObjectIDFromHex(showID)
@@ -136,77 +168,69 @@ func (s *Service) GetShowSeats(ctx context.Context, showID string) ([]ShowSeat,
 }
 
 // LockShowSeats temporarily locks seats for a booking
-func (s *Service) LockShowSeats(ctx context.Context, showID string, seatIDs []string, userID string) error {
+func (s *Service) LockShowSeats(ctx context.Context, showID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	ctx, span := tracer.Start(ctx, "movie.LockShowSeats", trace.WithAttributes(
+		attribute.Int("booking.seat_count", len(seatIDs)),
+	))
+	defer span.End()
+
 	if showID == "" {
-		return errors.New("show ID is required")
+		return nil, errors.New("show ID is required")
 	}
 
 	if len(seatIDs) == 0 {
-		return errors.New("at least one seat must be selected")
+		return nil, errors.New("at least one seat must be selected")
 	}
 
 	if userID == "" {
-		return errors.New("user ID is required")
+		return nil, errors.New("user ID is required")
 	}
 
 	// Convert string IDs to ObjectIDs
-	_, err := primitive.ObjectIDFromHex(showID)
+	showObjID, err := primitive.ObjectIDFromHex(showID)
 	if err != nil {
-		return errors.New("invalid show ID")
+		return nil, errors.New("invalid show ID")
 	}
 
 	seatObjIDs := make([]primitive.ObjectID, 0, len(seatIDs))
 	for _, id := range seatIDs {
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
-			return errors.New("invalid seat ID: " + id)
+			return nil, errors.New("invalid seat ID: " + id)
 		}
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Check if seats are already locked in Redis
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("show_seat_lock:%s:%s", showID, seatID)
-
-		// Check if the lock exists
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val != "" && val != userID {
-			// Seat is locked by someone else
-			return errors.New("one or more selected seats are no longer available")
-		}
+	// Sold seats can't be held
+	available, err := s.repo.SeatsAvailable(ctx, showObjID, seatObjIDs)
+	if err != nil {
+		return nil, errors.New("failed to lock seats: " + err.Error())
 	}
-
-	// Set Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("show_seat_lock:%s:%s", showID, seatID)
-
-		// Set the lock with expiration
-		err := s.redisClient.Set(ctx, lockKey, userID, s.seatLockTime).Err()
-		if err != nil {
-			// If any lock fails, clean up and return error
-			s.unlockSeats(ctx, showID, seatIDs, userID)
-			return errors.New("failed to lock seats: " + err.Error())
-		}
+	if !available {
+		return nil, errors.New("one or more selected seats are no longer available")
 	}
 
-	return nil
-}
-
-// unlockSeats helper to remove Redis locks
-func (s *Service) unlockSeats(ctx context.Context, showID string, seatIDs []string, userID string) {
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("show_seat_lock:%s:%s", showID, seatID)
+	metrics.SeatLockAttempts.WithLabelValues("movie").Inc()
 
-		// Only remove if the lock belongs to this user
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val == userID {
-			s.redisClient.Del(ctx, lockKey)
+	// Hold every seat or none
+	h, err := s.holds.Hold(ctx, hold.Request{Owner: userID, Platform: "movie", InventoryID: showID, Seats: seatIDs, TTL: s.seatLockTime})
+	if err != nil {
+		if errors.Is(err, hold.ErrConflict) {
+			// Seat is held by someone else
+			metrics.SeatLockConflicts.WithLabelValues("movie").Inc()
+			span.SetStatus(codes.Error, "seat conflict")
+			return nil, errors.New("one or more selected seats are no longer available")
 		}
+		return nil, errors.New("failed to lock seats: " + err.Error())
 	}
+
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsLocked, Platform: "movie", InventoryID: showID, SeatIDs: seatIDs})
+	return h, nil
 }
 
-// ConfirmSeats permanently reserves seats after payment
-func (s *Service) ConfirmSeats(ctx context.Context, showID string, seatIDs []string) error {
+// ConfirmSeats permanently reserves seats after payment. The seats must
+// still be held by userID's hold holdID.
+func (s *Service) ConfirmSeats(ctx context.Context, showID string, seatIDs []string, userID, holdID string) error {
 	// Convert string IDs to ObjectIDs
 	showObjID, err := primitive.ObjectIDFromHex(showID)
 	if err != nil {
@@ -222,18 +246,39 @@ func (s *Service) ConfirmSeats(ctx context.Context, showID string, seatIDs []str
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Remove Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("show_seat_lock:%s:%s", showID, seatID)
-		s.redisClient.Del(ctx, lockKey)
+	// Sell the seats first, so a seat someone else already bought keeps
+	// this hold alive for the caller to release
+	if err := s.repo.LockShowSeats(ctx, showObjID, seatObjIDs); err != nil {
+		return err
 	}
 
-	// Update the database
-	return s.repo.LockShowSeats(ctx, showObjID, seatObjIDs)
+	// End the hold on these seats. Without it they may be someone else's
+	// by now, so they go back on sale.
+	if err := s.holds.Confirm(ctx, "movie", showID, seatIDs, userID, holdID); err != nil {
+		s.repo.UnlockShowSeats(ctx, showObjID, seatObjIDs)
+		return err
+	}
+
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsConfirmed, Platform: "movie", InventoryID: showID, SeatIDs: seatIDs})
+	return nil
 }
 
-// ReleaseSeats releases previously locked seats
+// ReleaseSeats frees the seats userID holds. Seats held by someone else or
+// already sold are left alone.
 func (s *Service) ReleaseSeats(ctx context.Context, showID string, seatIDs []string, userID string) error {
+	freed, err := s.holds.Release(ctx, userID, "movie", showID, seatIDs)
+	if err != nil {
+		return err
+	}
+	if len(freed) > 0 {
+		stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsReleased, Platform: "movie", InventoryID: showID, SeatIDs: freed})
+	}
+	return nil
+}
+
+// UnsellSeats puts sold seats back on sale. Only cancelled and refunded
+// bookings give their seats back this way.
+func (s *Service) UnsellSeats(ctx context.Context, showID string, seatIDs []string) error {
 	// Convert string IDs to ObjectIDs
 	showObjID, err := primitive.ObjectIDFromHex(showID)
 	if err != nil {
@@ -249,19 +294,12 @@ func (s *Service) ReleaseSeats(ctx context.Context, showID string, seatIDs []str
 		seatObjIDs = append(seatObjIDs, objID)
 	}
 
-	// Remove Redis locks
-	for _, seatID := range seatIDs {
-		lockKey := fmt.Sprintf("show_seat_lock:%s:%s", showID, seatID)
-
-		// Only remove if the lock belongs to this user
-		val, err := s.redisClient.Get(ctx, lockKey).Result()
-		if err == nil && val == userID {
-			s.redisClient.Del(ctx, lockKey)
-		}
+	if err := s.repo.UnlockShowSeats(ctx, showObjID, seatObjIDs); err != nil {
+		return err
 	}
 
-	// Update the database if these were permanent locks
-	return s.repo.UnlockShowSeats(ctx, showObjID, seatObjIDs)
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsReleased, Platform: "movie", InventoryID: showID, SeatIDs: seatIDs})
+	return nil
 }
 
 // GetTheaters retrieves theaters with optional city filter
@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The helpers below serve the platforms that key inventory and seats by
+// Mongo ObjectID and pass them across the Platform interfaces as hex strings.
+
+// ParseObjectIDs parses an inventory ID and its seat IDs. kind names the
+// inventory in the error, e.g. "show".
+func ParseObjectIDs(kind, inventoryID string, seatIDs []string) (primitive.ObjectID, []primitive.ObjectID, error) {
+	invObjID, err := primitive.ObjectIDFromHex(inventoryID)
+	if err != nil {
+		return primitive.NilObjectID, nil, errors.New("invalid " + kind + " ID")
+	}
+	seatObjIDs := make([]primitive.ObjectID, 0, len(seatIDs))
+	for _, id := range seatIDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return primitive.NilObjectID, nil, errors.New("invalid seat ID: " + id)
+		}
+		seatObjIDs = append(seatObjIDs, objID)
+	}
+	return invObjID, seatObjIDs, nil
+}
+
+// HexIDs formats ids as hex strings
+func HexIDs(ids []primitive.ObjectID) []string {
+	hex := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hex = append(hex, id.Hex())
+	}
+	return hex
+}
+
+// HexSeats formats seats listed by inventory as hex strings. It takes the
+// repository's results directly and passes err through.
+func HexSeats(seats map[primitive.ObjectID][]primitive.ObjectID, err error) (map[string][]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	hex := make(map[string][]string, len(seats))
+	for invID, seatIDs := range seats {
+		if len(seatIDs) > 0 {
+			hex[invID.Hex()] = HexIDs(seatIDs)
+		}
+	}
+	return hex, nil
+}
@@ -0,0 +1,222 @@
+// Package platform defines the contract every booking vertical implements
+// and the registry the generic seat and booking endpoints dispatch through.
+//
+// A vertical (movie, flight, railway, event, ...) lives in its own package
+// under Platform/ and is plugged in by registering it in main; nothing else
+// in the service needs to know it exists.
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ansh0014/booking/hold"
+)
+
+// Seat status values reported in an Inventory
+const (
+	SeatAvailable = "available"
+	SeatLocked    = "locked"
+	SeatBooked    = "booked"
+)
+
+// DefaultCurrency is used for quotes until platforms price in other currencies
+const DefaultCurrency = "INR"
+
+var (
+	// ErrUnknownPlatform is returned for a platform name nobody registered
+	ErrUnknownPlatform = errors.New("unknown platform")
+	// ErrUnknownSeat is returned when a quote names a seat the inventory lacks
+	ErrUnknownSeat = errors.New("unknown seat")
+	// ErrSeatSold is returned when a quote names a seat that is already booked
+	ErrSeatSold = errors.New("seat is already booked")
+)
+
+// Platform is one bookable vertical. Inventory IDs and seat IDs are opaque
+// strings owned by the platform.
+type Platform interface {
+	// Name is the registry key, e.g. "movie"
+	Name() string
+	// HoldPolicy bounds how long seats may be held
+	HoldPolicy() hold.Policy
+	// Search runs the platform's own search; the query body is decoded by the platform
+	Search(ctx context.Context, q SearchQuery) (*SearchResult, error)
+	// Inventory lists every seat of a show, flight, train or event with its status
+	Inventory(ctx context.Context, inventoryID string) (*Inventory, error)
+	// Lock holds seats for userID for the platform's lock duration
+	Lock(ctx context.Context, inventoryID string, seatIDs []string, userID string) (*hold.SeatHold, error)
+	// Confirm permanently reserves seats still held by userID's hold holdID
+	Confirm(ctx context.Context, inventoryID string, seatIDs []string, userID, holdID string) error
+	// Release frees seats held by userID; sold seats stay sold
+	Release(ctx context.Context, inventoryID string, seatIDs []string, userID string) error
+	// Unsell puts confirmed seats back on sale when their booking is
+	// cancelled or refunded
+	Unsell(ctx context.Context, inventoryID string, seatIDs []string) error
+	// Quote prices the given seats
+	Quote(ctx context.Context, inventoryID string, seatIDs []string) (*Quote, error)
+}
+
+// Reconciler is implemented by platforms that keep seat flags and counters
+// in Mongo, so they can be checked against bookings and holds
+type Reconciler interface {
+	// UnavailableSeats lists the seats marked unavailable, by inventory ID
+	UnavailableSeats(ctx context.Context) (map[string][]string, error)
+	// MarkSeats sets seat flags without touching counters and returns how many changed
+	MarkSeats(ctx context.Context, inventoryID string, seatIDs []string, available bool) (int, error)
+	// RecountSeats resets seat counters from the seat flags and returns the
+	// inventory IDs whose counters were wrong
+	RecountSeats(ctx context.Context) ([]string, error)
+}
+
+// SearchQuery carries a platform-specific search body plus paging
+type SearchQuery struct {
+	Body     json.RawMessage `json:"body"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+}
+
+// SearchResult is a page of platform-specific items
+type SearchResult struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+}
+
+// Seat is one seat of an inventory item in a platform-neutral shape
+type Seat struct {
+	ID     string  `json:"id"`
+	Label  string  `json:"label"`
+	Class  string  `json:"class,omitempty"`
+	Price  float64 `json:"price"`
+	Status string  `json:"status"`
+}
+
+// Display is what a ticket shows about a show, flight, train or event
+type Display struct {
+	Title string `json:"title"`
+	// Venue is the theater, venue or route
+	Venue    string    `json:"venue,omitempty"`
+	StartsAt time.Time `json:"starts_at"`
+}
+
+// Inventory is the seat map of one show, flight, train or event
+type Inventory struct {
+	ID       string      `json:"id"`
+	Platform string      `json:"platform"`
+	Display  Display     `json:"display"`
+	Currency string      `json:"currency,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+	Seats    []Seat      `json:"seats"`
+}
+
+// QuoteLine is the price of one seat
+type QuoteLine struct {
+	SeatID string  `json:"seat_id"`
+	Label  string  `json:"label"`
+	Class  string  `json:"class,omitempty"`
+	Price  float64 `json:"price"`
+}
+
+// Quote is the price of a set of seats
+type Quote struct {
+	Platform    string      `json:"platform"`
+	InventoryID string      `json:"inventory_id"`
+	Display     Display     `json:"display"`
+	Currency    string      `json:"currency"`
+	Lines       []QuoteLine `json:"lines"`
+	Total       float64     `json:"total"`
+}
+
+// QuoteSeats prices seatIDs from inv at their listed prices and refuses
+// booked seats. Platforms without special pricing implement Quote with it.
+func QuoteSeats(inv *Inventory, seatIDs []string) (*Quote, error) {
+	byID := make(map[string]Seat, len(inv.Seats))
+	for _, seat := range inv.Seats {
+		byID[seat.ID] = seat
+	}
+
+	currency := inv.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	q := &Quote{Platform: inv.Platform, InventoryID: inv.ID, Display: inv.Display, Currency: currency}
+	for _, id := range seatIDs {
+		seat, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSeat, id)
+		}
+		if seat.Status == SeatBooked {
+			return nil, fmt.Errorf("%w: %s", ErrSeatSold, id)
+		}
+		q.Lines = append(q.Lines, QuoteLine{SeatID: seat.ID, Label: seat.Label, Class: seat.Class, Price: seat.Price})
+		q.Total += seat.Price
+	}
+	return q, nil
+}
+
+// SeatStatus maps a seat's stored availability and lock state to a status
+func SeatStatus(available, locked bool) string {
+	switch {
+	case !available:
+		return SeatBooked
+	case locked:
+		return SeatLocked
+	default:
+		return SeatAvailable
+	}
+}
+
+// Registry maps platform names to implementations
+type Registry struct {
+	mu        sync.RWMutex
+	platforms map[string]Platform
+}
+
+// NewRegistry creates a registry holding ps
+func NewRegistry(ps ...Platform) *Registry {
+	r := &Registry{platforms: map[string]Platform{}}
+	for _, p := range ps {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds p, replacing any platform with the same name
+func (r *Registry) Register(p Platform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.platforms[p.Name()] = p
+}
+
+// Get returns the platform registered under name
+func (r *Registry) Get(name string) (Platform, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.platforms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPlatform, name)
+	}
+	return p, nil
+}
+
+// Has reports whether name is registered
+func (r *Registry) Has(name string) bool {
+	_, err := r.Get(name)
+	return err == nil
+}
+
+// Names lists the registered platforms in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.platforms))
+	for name := range r.platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+package railway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+)
+
+var (
+	_ platform.Platform   = (*Service)(nil)
+	_ platform.Reconciler = (*Service)(nil)
+)
+
+// Name implements platform.Platform
+func (s *Service) Name() string {
+	return "railway"
+}
+
+// HoldPolicy implements platform.Platform
+func (s *Service) HoldPolicy() hold.Policy {
+	return hold.Policy{TTL: s.seatLockTime, Max: s.maxHoldTime}
+}
+
+// Search implements platform.Platform; the body is a SearchTrainsRequest
+func (s *Service) Search(ctx context.Context, q platform.SearchQuery) (*platform.SearchResult, error) {
+	var req SearchTrainsRequest
+	if len(q.Body) > 0 {
+		if err := json.Unmarshal(q.Body, &req); err != nil {
+			return nil, err
+		}
+	}
+	items, err := s.SearchTrains(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &platform.SearchResult{Items: items, Total: int64(len(items))}, nil
+}
+
+// Inventory implements platform.Platform
+func (s *Service) Inventory(ctx context.Context, trainID string) (*platform.Inventory, error) {
+	details, err := s.GetTrainByID(ctx, trainID)
+	if err != nil {
+		return nil, err
+	}
+	seats, err := s.GetTrainSeats(ctx, trainID, "")
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.holds.Locked(ctx, s.Name(), trainID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &platform.Inventory{ID: trainID, Platform: s.Name(), Details: details, Seats: make([]platform.Seat, 0, len(seats))}
+	t := details.Train
+	inv.Display = platform.Display{
+		Title:    strings.TrimSpace(t.Number + " " + t.Name),
+		Venue:    t.Origin.Name + " - " + t.Destination.Name,
+		StartsAt: t.DepartureTime,
+	}
+	for _, seat := range seats {
+		id := seat.ID.Hex()
+		inv.Seats = append(inv.Seats, platform.Seat{
+			ID:     id,
+			Label:  seat.Coach + "-" + seat.SeatNumber,
+			Class:  seat.Class,
+			Price:  seat.Price,
+			Status: platform.SeatStatus(seat.IsAvailable, locked[id] != ""),
+		})
+	}
+	return inv, nil
+}
+
+// Lock implements platform.Platform
+func (s *Service) Lock(ctx context.Context, trainID string, seatIDs []string, userID string) (*hold.SeatHold, error) {
+	return s.LockTrainSeats(ctx, trainID, seatIDs, userID)
+}
+
+// Confirm implements platform.Platform
+func (s *Service) Confirm(ctx context.Context, trainID string, seatIDs []string, userID, holdID string) error {
+	return s.ConfirmSeats(ctx, trainID, seatIDs, userID, holdID)
+}
+
+// Release implements platform.Platform
+func (s *Service) Release(ctx context.Context, trainID string, seatIDs []string, userID string) error {
+	return s.ReleaseSeats(ctx, trainID, seatIDs, userID)
+}
+
+// Unsell implements platform.Platform
+func (s *Service) Unsell(ctx context.Context, trainID string, seatIDs []string) error {
+	return s.UnsellSeats(ctx, trainID, seatIDs)
+}
+
+// Quote implements platform.Platform at the listed seat prices
+func (s *Service) Quote(ctx context.Context, trainID string, seatIDs []string) (*platform.Quote, error) {
+	inv, err := s.Inventory(ctx, trainID)
+	if err != nil {
+		return nil, err
+	}
+	return platform.QuoteSeats(inv, seatIDs)
+}
+
+// UnavailableSeats implements platform.Reconciler
+func (s *Service) UnavailableSeats(ctx context.Context) (map[string][]string, error) {
+	return platform.HexSeats(s.repo.GetUnavailableSeats(ctx))
+}
+
+// MarkSeats implements platform.Reconciler
+func (s *Service) MarkSeats(ctx context.Context, trainID string, seatIDs []string, available bool) (int, error) {
+	trainObjID, seatObjIDs, err := platform.ParseObjectIDs("train", trainID, seatIDs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.repo.SetSeatsAvailability(ctx, trainObjID, seatObjIDs, available)
+	return int(n), err
+}
+
+// RecountSeats implements platform.Reconciler
+func (s *Service) RecountSeats(ctx context.Context) ([]string, error) {
+	fixed, err := s.repo.RecountTrainSeats(ctx)
+	return platform.HexIDs(fixed), err
+}
@@ -231,7 +231,6 @@ func (r *Repository) GetTrainStops(ctx context.Context, trainID primitive.Object
 func (r *Repository) LockTrainSeats(ctx context.Context, trainID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
 	filter := bson.M{
 		"train_id":     trainID,
-		"_id":          bson.M{"$in": seatIDs},
 		"is_available": true,
 	}
 
@@ -242,33 +241,24 @@ func (r *Repository) LockTrainSeats(ctx context.Context, trainID primitive.Objec
 		},
 	}
 
-	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
-	if err != nil {
-		return err
-	}
-
-	// Check if all seats were updated
-	if result.ModifiedCount != int64(len(seatIDs)) {
-		// Revert the changes
-		revertFilter := bson.M{
-			"train_id": trainID,
-			"_id":      bson.M{"$in": seatIDs},
+	// Take the seats one at a time, so a seat that is already gone undoes
+	// only the seats this call took and never one another booking owns
+	taken := make([]primitive.ObjectID, 0, len(seatIDs))
+	for _, seatID := range seatIDs {
+		filter["_id"] = seatID
+		result, err := r.seatsColl.UpdateOne(ctx, filter, update)
+		if err == nil && result.ModifiedCount == 0 {
+			err = errors.New("some seats are no longer available")
 		}
-
-		revertUpdate := bson.M{
-			"$set": bson.M{
-				"is_available": true,
-				"updated_at":   time.Now(),
-			},
+		if err != nil {
+			r.revertSeats(ctx, trainID, taken)
+			return err
 		}
-
-		_, _ = r.seatsColl.UpdateMany(ctx, revertFilter, revertUpdate)
-
-		return errors.New("some seats are no longer available")
+		taken = append(taken, seatID)
 	}
 
 	// Update available seats count on the train
-	_, err = r.trainsColl.UpdateOne(
+	_, err := r.trainsColl.UpdateOne(
 		ctx,
 		bson.M{"_id": trainID},
 		bson.M{
@@ -280,11 +270,42 @@ func (r *Repository) LockTrainSeats(ctx context.Context, trainID primitive.Objec
 	return err
 }
 
+// SeatsAvailable reports whether every seat in seatIDs is still on sale
+func (r *Repository) SeatsAvailable(ctx context.Context, trainID primitive.ObjectID, seatIDs []primitive.ObjectID) (bool, error) {
+	n, err := r.seatsColl.CountDocuments(ctx, bson.M{
+		"train_id":     trainID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": true,
+	})
+	if err != nil {
+		return false, err
+	}
+	return n == int64(len(seatIDs)), nil
+}
+
+// revertSeats puts back on sale the seats a failed lock already took
+func (r *Repository) revertSeats(ctx context.Context, trainID primitive.ObjectID, seatIDs []primitive.ObjectID) {
+	if len(seatIDs) == 0 {
+		return
+	}
+	_, _ = r.seatsColl.UpdateMany(ctx, bson.M{
+		"train_id":     trainID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": false,
+	}, bson.M{
+		"$set": bson.M{
+			"is_available": true,
+			"updated_at":   time.Now(),
+		},
+	})
+}
+
 // UnlockTrainSeats unlocks previously locked seats
 func (r *Repository) UnlockTrainSeats(ctx context.Context, trainID primitive.ObjectID, seatIDs []primitive.ObjectID) error {
 	filter := bson.M{
-		"train_id": trainID,
-		"_id":      bson.M{"$in": seatIDs},
+		"train_id":     trainID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": false,
 	}
 
 	update := bson.M{
@@ -294,17 +315,21 @@ func (r *Repository) UnlockTrainSeats(ctx context.Context, trainID primitive.Obj
 		},
 	}
 
-	_, err := r.seatsColl.UpdateMany(ctx, filter, update)
+	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	// Seats already on sale must not move the counters again
+	if result.ModifiedCount == 0 {
+		return nil
+	}
 
 	// Update available seats count on the train
 	_, err = r.trainsColl.UpdateOne(
 		ctx,
 		bson.M{"_id": trainID},
 		bson.M{
-			"$inc": bson.M{"available_seats": len(seatIDs)},
+			"$inc": bson.M{"available_seats": result.ModifiedCount},
 			"$set": bson.M{"updated_at": time.Now()},
 		},
 	)
@@ -433,3 +458,95 @@ func (r *Repository) SearchTrainsByStations(ctx context.Context, originCode, des
 
 	return validTrains, nil
 }
+
+// GetUnavailableSeats lists the seats marked unavailable, by train
+func (r *Repository) GetUnavailableSeats(ctx context.Context) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "train_id": 1})
+
+	cursor, err := r.seatsColl.Find(ctx, bson.M{"is_available": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var seats []TrainSeat
+	if err = cursor.All(ctx, &seats); err != nil {
+		return nil, err
+	}
+
+	unavailable := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, seat := range seats {
+		unavailable[seat.TrainID] = append(unavailable[seat.TrainID], seat.ID)
+	}
+
+	return unavailable, nil
+}
+
+// SetSeatsAvailability sets seat flags without touching the train's counters
+// and returns how many seats changed
+func (r *Repository) SetSeatsAvailability(ctx context.Context, trainID primitive.ObjectID, seatIDs []primitive.ObjectID, available bool) (int64, error) {
+	filter := bson.M{
+		"train_id":     trainID,
+		"_id":          bson.M{"$in": seatIDs},
+		"is_available": !available,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_available": available,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	result, err := r.seatsColl.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// RecountTrainSeats resets every train's seat counters from its seat flags
+// and returns the trains whose counters were wrong
+func (r *Repository) RecountTrainSeats(ctx context.Context) ([]primitive.ObjectID, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{
+			"_id":       "$train_id",
+			"available": bson.M{"$sum": bson.M{"$cond": bson.A{"$is_available", 1, 0}}},
+		}},
+	}
+
+	cursor, err := r.seatsColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		Available int                `bson:"available"`
+	}
+	if err = cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	var fixed []primitive.ObjectID
+	for _, c := range counts {
+		result, err := r.trainsColl.UpdateOne(
+			ctx,
+			bson.M{"_id": c.ID, "available_seats": bson.M{"$ne": c.Available}},
+			bson.M{"$set": bson.M{
+				"available_seats": c.Available,
+				"updated_at":      time.Now(),
+			}},
+		)
+		if err != nil {
+			return fixed, err
+		}
+		if result.ModifiedCount > 0 {
+			fixed = append(fixed, c.ID)
+		}
+	}
+
+	return fixed, nil
+}
@@ -2,12 +2,17 @@ package config
 
 import (
     "context"
+    "errors"
     "os"
+    "strconv"
     "time"
 
+    "github.com/ansh0014/booking/metrics"
+    "github.com/ansh0014/booking/tracing"
     "github.com/go-redis/redis/v8"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
+    "go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var (
@@ -25,6 +30,8 @@ func InitRedis() error {
     }
     
     RedisClient = redis.NewClient(opt)
+    RedisClient.AddHook(tracing.RedisHook{})
+    RedisClient.AddHook(metrics.RedisHook{})
     
     // Ping Redis to verify connection
     _, err = RedisClient.Ping(Ctx).Result()
@@ -35,7 +42,9 @@ func InitMongo() error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGODB_URI")))
+    client, err := mongo.Connect(ctx, options.Client().
+        ApplyURI(os.Getenv("MONGODB_URI")).
+        SetMonitor(metrics.MongoMonitor(otelmongo.NewMonitor())))
     if err != nil {
         return err
     }
@@ -48,4 +57,29 @@ func InitMongo() error {
     MongoClient = client
     MongoDB = client.Database(os.Getenv("MONGODB_DATABASE"))
     return nil
+}
+
+// Close releases the Mongo and Redis connections.
+func Close(ctx context.Context) error {
+    var errs []error
+    if RedisClient != nil {
+        errs = append(errs, RedisClient.Close())
+    }
+    if MongoClient != nil {
+        errs = append(errs, MongoClient.Disconnect(ctx))
+    }
+    return errors.Join(errs...)
+}
+
+// GetInternalToken returns the token other services present in the
+// X-Internal-Token header when calling the internal endpoints
+func GetInternalToken() string {
+    return os.Getenv("BOOKING_INTERNAL_TOKEN")
+}
+
+// OutboxAllowNonAtomic reports whether booking events may be written after,
+// instead of together with, booking changes when MongoDB has no transactions
+func OutboxAllowNonAtomic() bool {
+    allow, _ := strconv.ParseBool(os.Getenv("OUTBOX_ALLOW_NON_ATOMIC"))
+    return allow
 }
\ No newline at end of file
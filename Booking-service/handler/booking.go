@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -15,26 +15,7 @@ import (
 func CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.BookingRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate platform
-	if req.Platform == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform is required")
-		return
-	}
-
-	// Validate platform ID
-	if req.PlatformID == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform ID is required")
-		return
-	}
-
-	// Validate seats
-	if len(req.SeatIDs) == 0 {
-		utils.RespondWithError(w, http.StatusBadRequest, "At least one seat must be selected")
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
@@ -46,7 +27,7 @@ func CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get booking service
-	bookingService := r.Context().Value("bookingService").(*service.BookingService)
+	bookingService := services.Booking
 
 	// Create booking
 	booking, err := bookingService.CreateBooking(r.Context(), req, userID)
@@ -78,12 +59,12 @@ func GetBookingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get booking service
-	bookingService := r.Context().Value("bookingService").(*service.BookingService)
+	bookingService := services.Booking
 
 	// Get booking
 	booking, err := bookingService.GetBooking(r.Context(), bookingID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Booking not found")
+		respondBookingError(w, err)
 		return
 	}
 
@@ -115,7 +96,7 @@ func GetUserBookingsHandler(w http.ResponseWriter, r *http.Request) {
 	page, pageSize := utils.GetPageParams(r)
 
 	// Get booking service
-	bookingService := r.Context().Value("bookingService").(*service.BookingService)
+	bookingService := services.Booking
 
 	// Get user's bookings
 	bookings, total, err := bookingService.GetUserBookings(r.Context(), userID, page, pageSize)
@@ -136,11 +117,17 @@ func GetUserBookingsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// CancelBookingHandler cancels a booking
+// CancelBookingHandler cancels a booking. The optional body gives a reason
+// and the version the client last saw, which must still be current.
 func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	bookingID := vars["id"]
 
+	var req model.CancelBookingRequest
+	if r.ContentLength != 0 && !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
 	// Get user ID from context
 	userID, err := utils.GetUserFromContext(r.Context())
 	if err != nil {
@@ -149,12 +136,12 @@ func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get booking service
-	bookingService := r.Context().Value("bookingService").(*service.BookingService)
+	bookingService := services.Booking
 
 	// Get booking first to check ownership
 	booking, err := bookingService.GetBooking(r.Context(), bookingID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Booking not found")
+		respondBookingError(w, err)
 		return
 	}
 
@@ -164,10 +151,19 @@ func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Version != nil && *req.Version != booking.Version {
+		respondBookingError(w, service.ErrVersionConflict)
+		return
+	}
+
 	// Cancel the booking
-	err = bookingService.CancelBooking(r.Context(), bookingID)
+	reason := req.Reason
+	if reason == "" {
+		reason = "cancelled by user"
+	}
+	booking, err = bookingService.CancelBooking(r.Context(), booking, model.UserActor(userID), reason)
 	if err != nil {
-		utils.ServerErrorResponse(w, "Failed to cancel booking: "+err.Error())
+		respondBookingError(w, err)
 		return
 	}
 
@@ -175,5 +171,20 @@ func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "Booking cancelled successfully",
+		"data": map[string]interface{}{
+			"booking": booking,
+		},
 	})
 }
+
+// respondBookingError maps booking service errors to responses
+func respondBookingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrBookingNotFound):
+		utils.NotFoundResponse(w, "Booking not found")
+	case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, service.ErrVersionConflict):
+		utils.ConflictResponse(w, err.Error())
+	default:
+		utils.ServerErrorResponse(w, "Failed to process booking: "+err.Error())
+	}
+}
@@ -19,8 +19,8 @@ func SearchEventsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get event service from context
-	eventService := r.Context().Value("eventService").(*event.Service)
+	// Get event service
+	eventService := services.Event
 
 	// Parse pagination parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -54,7 +54,7 @@ func GetEventDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	eventID := vars["id"]
 
-	eventService := r.Context().Value("eventService").(*event.Service)
+	eventService := services.Event
 
 	eventDetails, err := eventService.GetEventByID(r.Context(), eventID)
 	if err != nil {
@@ -76,7 +76,7 @@ func GetEventSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Optional ticket type filter
 	ticketTypeID := r.URL.Query().Get("ticket_type_id")
 
-	eventService := r.Context().Value("eventService").(*event.Service)
+	eventService := services.Event
 
 	seats, err := eventService.GetEventSeats(r.Context(), eventID, ticketTypeID)
 	if err != nil {
@@ -98,7 +98,7 @@ func GetEventTicketTypesHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	eventID := vars["id"]
 
-	eventService := r.Context().Value("eventService").(*event.Service)
+	eventService := services.Event
 
 	ticketTypes, err := eventService.GetTicketTypes(r.Context(), eventID)
 	if err != nil {
@@ -130,9 +130,9 @@ func LockEventSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the authenticated request
 	userID := r.Context().Value("userID").(string)
 
-	eventService := r.Context().Value("eventService").(*event.Service)
+	eventService := services.Event
 
-	err := eventService.LockEventSeats(r.Context(), req.EventID, req.TicketTypeID, req.SeatIDs, userID)
+	seatHold, err := eventService.LockEventSeats(r.Context(), req.EventID, req.TicketTypeID, req.SeatIDs, userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Failed to lock seats: "+err.Error())
 		return
@@ -145,6 +145,7 @@ func LockEventSeatsHandler(w http.ResponseWriter, r *http.Request) {
 			"event_id":       req.EventID,
 			"ticket_type_id": req.TicketTypeID,
 			"seat_ids":       req.SeatIDs,
+			"hold":           seatHold,
 		},
 	})
 }
@@ -18,8 +18,8 @@ func SearchFlightsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get flight service from context
-	flightService := r.Context().Value("flightService").(*flight.Service)
+	// Get flight service
+	flightService := services.Flight
 
 	flights, err := flightService.SearchFlights(r.Context(), req)
 	if err != nil {
@@ -38,7 +38,7 @@ func GetFlightDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	flightID := vars["id"]
 
-	flightService := r.Context().Value("flightService").(*flight.Service)
+	flightService := services.Flight
 
 	flightDetails, err := flightService.GetFlightByID(r.Context(), flightID)
 	if err != nil {
@@ -54,7 +54,7 @@ func GetFlightSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	flightID := vars["id"]
 
-	flightService := r.Context().Value("flightService").(*flight.Service)
+	flightService := services.Flight
 
 	seats, err := flightService.GetFlightSeats(r.Context(), flightID)
 	if err != nil {
@@ -86,9 +86,9 @@ func LockFlightSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the authenticated request
 	userID := r.Context().Value("userID").(string)
 
-	flightService := r.Context().Value("flightService").(*flight.Service)
+	flightService := services.Flight
 
-	err := flightService.LockFlightSeats(r.Context(), req.FlightID, req.SeatIDs, userID)
+	seatHold, err := flightService.LockFlightSeats(r.Context(), req.FlightID, req.SeatIDs, userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Failed to lock seats: "+err.Error())
 		return
@@ -100,6 +100,7 @@ func LockFlightSeatsHandler(w http.ResponseWriter, r *http.Request) {
 		"data": map[string]interface{}{
 			"flight_id": req.FlightID,
 			"seat_ids":  req.SeatIDs,
+			"hold":      seatHold,
 		},
 	})
 }
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/model"
+	"github.com/ansh0014/booking/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetHoldHandler returns one of the user's seat holds
+func GetHoldHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.UnauthorizedResponse(w, "User not authenticated")
+		return
+	}
+
+	seatHold, err := services.Hold.GetHold(r.Context(), mux.Vars(r)["id"], userID)
+	if err != nil {
+		respondHoldError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    seatHold,
+	})
+}
+
+// ExtendHoldHandler pushes back a hold's expiry. The body is optional;
+// without it the hold is extended by the platform's default.
+func ExtendHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.ExtendHoldRequest
+	if r.ContentLength != 0 && !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID, err := utils.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.UnauthorizedResponse(w, "User not authenticated")
+		return
+	}
+
+	by := time.Duration(req.Seconds) * time.Second
+	seatHold, err := services.Hold.ExtendHold(r.Context(), mux.Vars(r)["id"], userID, by)
+	if err != nil {
+		respondHoldError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Hold extended",
+		"data":    seatHold,
+	})
+}
+
+// ReleaseHoldHandler frees a hold's seats before it expires
+func ReleaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.UnauthorizedResponse(w, "User not authenticated")
+		return
+	}
+
+	seatHold, err := services.Hold.ReleaseHold(r.Context(), mux.Vars(r)["id"], userID)
+	if err != nil {
+		respondHoldError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Hold released",
+		"data":    seatHold,
+	})
+}
+
+// respondHoldError maps hold store errors to responses
+func respondHoldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, hold.ErrNotFound):
+		utils.NotFoundResponse(w, "Hold not found")
+	case errors.Is(err, hold.ErrNotOwner):
+		utils.ForbiddenResponse(w, "You don't have permission to access this hold")
+	case errors.Is(err, hold.ErrNotActive), errors.Is(err, hold.ErrExtendLimit):
+		utils.ConflictResponse(w, err.Error())
+	case errors.Is(err, hold.ErrExtendTooShort):
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.ServerErrorResponse(w, "Failed to update hold: "+err.Error())
+	}
+}
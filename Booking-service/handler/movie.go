@@ -13,7 +13,7 @@ import (
 
 // GetMoviesHandler retrieves a list of movies
 func GetMoviesHandler(w http.ResponseWriter, r *http.Request) {
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	movieService := services.Movie
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -54,8 +54,8 @@ func SearchMoviesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get movie service from context
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	// Get movie service
+	movieService := services.Movie
 
 	// Handle pagination
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -92,7 +92,7 @@ func GetMovieDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	movieID := vars["id"]
 
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	movieService := services.Movie
 
 	movieDetails, err := movieService.GetMovieByID(r.Context(), movieID)
 	if err != nil {
@@ -129,7 +129,7 @@ func GetMovieShowsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get theater filter
 	theaterID := r.URL.Query().Get("theater_id")
 
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	movieService := services.Movie
 
 	shows, err := movieService.GetMovieShows(r.Context(), movieID, date, theaterID)
 	if err != nil {
@@ -158,7 +158,7 @@ func GetMovieSeatsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	movieService := services.Movie
 
 	seats, err := movieService.GetShowSeats(r.Context(), showID)
 	if err != nil {
@@ -176,6 +176,63 @@ func GetMovieSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetShowDetailsHandler retrieves a single show
+func GetShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	showID := mux.Vars(r)["id"]
+
+	movieService := services.Movie
+
+	show, err := movieService.GetShowByID(r.Context(), showID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Show not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    show,
+	})
+}
+
+// GetShowLocksHandler lists seats currently held by a checkout for a show
+func GetShowLocksHandler(w http.ResponseWriter, r *http.Request) {
+	showID := mux.Vars(r)["id"]
+
+	movieService := services.Movie
+
+	locked, err := movieService.GetLockedSeats(r.Context(), showID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get seat locks: "+err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"show_id": showID,
+			"locked":  locked,
+		},
+	})
+}
+
+// GetTheaterDetailsHandler retrieves a single theater
+func GetTheaterDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	theaterID := mux.Vars(r)["id"]
+
+	movieService := services.Movie
+
+	theater, err := movieService.GetTheaterByID(r.Context(), theaterID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Theater not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    theater,
+	})
+}
+
 // LockMovieSeatsHandler temporarily reserves seats for a movie show
 func LockMovieSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -191,9 +248,9 @@ func LockMovieSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the authenticated request
 	userID := r.Context().Value("userID").(string)
 
-	movieService := r.Context().Value("movieService").(*movie.Service)
+	movieService := services.Movie
 
-	err := movieService.LockShowSeats(r.Context(), req.ShowID, req.SeatIDs, userID)
+	seatHold, err := movieService.LockShowSeats(r.Context(), req.ShowID, req.SeatIDs, userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Failed to lock seats: "+err.Error())
 		return
@@ -205,6 +262,7 @@ func LockMovieSeatsHandler(w http.ResponseWriter, r *http.Request) {
 		"data": map[string]interface{}{
 			"show_id":  req.ShowID,
 			"seat_ids": req.SeatIDs,
+			"hold":     seatHold,
 		},
 	})
 }
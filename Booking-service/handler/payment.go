@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ansh0014/booking/model"
+	"github.com/ansh0014/booking/service"
+	"github.com/ansh0014/booking/utils"
+)
+
+// PaymentEventHandler applies a payment status change reported by the
+// payment service and answers with what it did to the booking, including
+// whether the payment has to be refunded
+func PaymentEventHandler(w http.ResponseWriter, r *http.Request) {
+	var ev model.PaymentEvent
+	if !utils.DecodeAndValidate(w, r, &ev) {
+		return
+	}
+
+	outcome, err := services.Booking.ApplyPayment(r.Context(), ev)
+	if err != nil {
+		if errors.Is(err, service.ErrPaymentInProgress) {
+			utils.ConflictResponse(w, err.Error())
+			return
+		}
+		respondBookingError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    outcome,
+	})
+}
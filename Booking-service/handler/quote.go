@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ansh0014/booking/model"
+	"github.com/ansh0014/booking/pricing"
+	"github.com/ansh0014/booking/utils"
+)
+
+// QuoteHandler prices seats with fees, taxes and discounts without holding them
+func QuoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.QuoteRequest
+
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	price, err := services.Pricing.Quote(r.Context(), pricing.Request{
+		Platform:    req.Platform,
+		InventoryID: req.PlatformID,
+		SeatIDs:     req.SeatIDs,
+		PromoCode:   req.PromoCode,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    price,
+	})
+}
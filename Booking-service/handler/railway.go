@@ -18,8 +18,8 @@ func SearchTrainsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get railway service from context
-	railwayService := r.Context().Value("railwayService").(*railway.Service)
+	// Get railway service
+	railwayService := services.Railway
 
 	trains, err := railwayService.SearchTrains(r.Context(), req)
 	if err != nil {
@@ -41,7 +41,7 @@ func GetTrainDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	trainID := vars["id"]
 
-	railwayService := r.Context().Value("railwayService").(*railway.Service)
+	railwayService := services.Railway
 
 	trainDetails, err := railwayService.GetTrainByID(r.Context(), trainID)
 	if err != nil {
@@ -63,7 +63,7 @@ func GetTrainSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Optional class filter
 	class := r.URL.Query().Get("class")
 
-	railwayService := r.Context().Value("railwayService").(*railway.Service)
+	railwayService := services.Railway
 
 	seats, err := railwayService.GetTrainSeats(r.Context(), trainID, class)
 	if err != nil {
@@ -85,7 +85,7 @@ func GetTrainStopsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	trainID := vars["id"]
 
-	railwayService := r.Context().Value("railwayService").(*railway.Service)
+	railwayService := services.Railway
 
 	stops, err := railwayService.GetTrainStops(r.Context(), trainID)
 	if err != nil {
@@ -118,9 +118,9 @@ func LockTrainSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the authenticated request
 	userID := r.Context().Value("userID").(string)
 
-	railwayService := r.Context().Value("railwayService").(*railway.Service)
+	railwayService := services.Railway
 
-	err := railwayService.LockTrainSeats(r.Context(), req.TrainID, req.SeatIDs, userID)
+	seatHold, err := railwayService.LockTrainSeats(r.Context(), req.TrainID, req.SeatIDs, userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Failed to lock seats: "+err.Error())
 		return
@@ -133,6 +133,7 @@ func LockTrainSeatsHandler(w http.ResponseWriter, r *http.Request) {
 			"train_id": req.TrainID,
 			"seat_ids": req.SeatIDs,
 			"class":    req.Class,
+			"hold":     seatHold,
 		},
 	})
 }
@@ -1,12 +1,12 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	platform "github.com/ansh0014/booking/Platform"
 	"github.com/ansh0014/booking/model"
-	"github.com/ansh0014/booking/service"
 	"github.com/ansh0014/booking/utils"
 )
 
@@ -14,26 +14,7 @@ import (
 func LockSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.SeatLockRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate platform
-	if req.Platform == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform is required")
-		return
-	}
-
-	// Validate platform ID
-	if req.PlatformID == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform ID is required")
-		return
-	}
-
-	// Validate seats
-	if len(req.SeatIDs) == 0 {
-		utils.RespondWithError(w, http.StatusBadRequest, "At least one seat must be selected")
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
@@ -45,10 +26,10 @@ func LockSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get seat service
-	seatService := r.Context().Value("seatService").(*service.SeatService)
+	seatService := services.Seat
 
 	// Lock seats
-	err = seatService.LockSeats(r.Context(), req, userID)
+	seatHold, err := seatService.LockSeats(r.Context(), req, userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
@@ -62,7 +43,8 @@ func LockSeatsHandler(w http.ResponseWriter, r *http.Request) {
 			"platform":    req.Platform,
 			"platform_id": req.PlatformID,
 			"seat_ids":    req.SeatIDs,
-			"expires_at":  time.Now().Add(5 * time.Minute),
+			"hold_id":     seatHold.ID,
+			"expires_at":  seatHold.ExpiresAt,
 		},
 	})
 }
@@ -71,15 +53,18 @@ func LockSeatsHandler(w http.ResponseWriter, r *http.Request) {
 func GetAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.AvailabilityRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
 	// Get seat service
-	seatService := r.Context().Value("seatService").(*service.SeatService)
+	seatService := services.Seat
 
-	seatStatus, err := seatService.GetAvailability(r.Context(), req.ShowID)
+	inventory, err := seatService.GetAvailability(r.Context(), req.Platform, req.ShowID)
+	if errors.Is(err, platform.ErrUnknownPlatform) {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get availability: "+err.Error())
 		return
@@ -90,13 +75,14 @@ func GetAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
 	locked := []string{}
 	booked := []string{}
 
-	// TODO: Get full seat list from Venue service
-	// For now, we'll just categorize the ones in Redis
-	for seatID, userID := range seatStatus {
-		if userID != "" {
-			locked = append(locked, seatID)
-		} else {
-			available = append(available, seatID)
+	for _, seat := range inventory.Seats {
+		switch seat.Status {
+		case platform.SeatLocked:
+			locked = append(locked, seat.ID)
+		case platform.SeatBooked:
+			booked = append(booked, seat.ID)
+		default:
+			available = append(available, seat.ID)
 		}
 	}
 
@@ -118,26 +104,7 @@ func GetAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
 func ReleaseSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.SeatLockRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate platform
-	if req.Platform == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform is required")
-		return
-	}
-
-	// Validate platform ID
-	if req.PlatformID == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Platform ID is required")
-		return
-	}
-
-	// Validate seats
-	if len(req.SeatIDs) == 0 {
-		utils.RespondWithError(w, http.StatusBadRequest, "At least one seat must be selected")
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
@@ -149,7 +116,7 @@ func ReleaseSeatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get seat service
-	seatService := r.Context().Value("seatService").(*service.SeatService)
+	seatService := services.Seat
 
 	// Release seats
 	err = seatService.ReleaseSeats(r.Context(), req, userID)
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/Platform/event"
+	"github.com/ansh0014/booking/Platform/flight"
+	"github.com/ansh0014/booking/Platform/movie"
+	"github.com/ansh0014/booking/Platform/railway"
+	"github.com/ansh0014/booking/pricing"
+	"github.com/ansh0014/booking/service"
+)
+
+// Services are the typed dependencies the handlers call into
+type Services struct {
+	Platforms *platform.Registry
+	Flight    *flight.Service
+	Railway   *railway.Service
+	Event     *event.Service
+	Movie     *movie.Service
+	Booking   *service.BookingService
+	Seat      *service.SeatService
+	Hold      *service.HoldService
+	Pricing   *pricing.Engine
+}
+
+var services Services
+
+// Init wires the handlers to their services; call it once before serving
+func Init(s Services) {
+	services = s
+}
@@ -0,0 +1,140 @@
+// Package health serves liveness and readiness probes.
+//
+// Liveness only says the process is up. Readiness probes every registered
+// dependency concurrently, each under its own timeout, and reports
+// per-dependency status so an orchestrator or the gateway can tell which one
+// is failing.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Status values
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// DefaultTimeout bounds a single dependency probe.
+const DefaultTimeout = 2 * time.Second
+
+// Check probes one dependency and returns nil when it is usable.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the readiness response body.
+type Report struct {
+	Service string            `json:"service"`
+	Status  string            `json:"status"`
+	Checks  map[string]Result `json:"checks"`
+}
+
+// Checker holds the dependency checks of a service.
+type Checker struct {
+	service string
+	timeout time.Duration
+	checks  map[string]Check
+}
+
+// New returns a checker for the named service.
+func New(service string) *Checker {
+	return &Checker{service: service, timeout: DefaultTimeout, checks: map[string]Check{}}
+}
+
+// Add registers a dependency check.
+func (c *Checker) Add(name string, check Check) *Checker {
+	c.checks[name] = check
+	return c
+}
+
+// Run executes every check concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	rep := Report{Service: c.service, Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			res := Result{Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Status = StatusUnavailable
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			rep.Checks[name] = res
+			if err != nil {
+				rep.Status = StatusUnavailable
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return rep
+}
+
+// Live answers liveness probes.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"service": c.service, "status": StatusOK})
+}
+
+// Ready answers readiness probes: 200 when every dependency is up, else 503.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	rep := c.Run(r.Context())
+	code := http.StatusOK
+	if rep.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, rep)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+var errNotConnected = errors.New("not connected")
+
+// Mongo pings a Mongo client.
+func Mongo(client *mongo.Client) Check {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return errNotConnected
+		}
+		return client.Ping(ctx, nil)
+	}
+}
+
+// Redis pings a Redis client.
+func Redis(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return errNotConnected
+		}
+		return client.Ping(ctx).Err()
+	}
+}
@@ -0,0 +1,492 @@
+// Package hold is the single store for seat holds.
+//
+// A SeatHold is one user's temporary claim on a set of seats of one
+// inventory item (show, flight, train or event). Every platform locks,
+// confirms and releases seats through a Store, so a seat held through the
+// generic endpoints is held for the platform endpoints too.
+//
+// Redis layout:
+//
+//	hold_seat:<platform>:<inventory>:<seat>  owner|hold ID, expires with the hold
+//	hold:<id>                                JSON SeatHold, kept for a while after it ends
+//	hold_expiry                              sorted set of active hold IDs by expiry
+//
+// Seat keys expire on their own; Sweep finds holds that ran out so their
+// expiry can be announced.
+package hold
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ansh0014/booking/seatlock"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hold status values
+const (
+	StatusHeld      = "held"
+	StatusConfirmed = "confirmed"
+	StatusReleased  = "released"
+	StatusExpired   = "expired"
+)
+
+// retention is how long a hold record stays readable after it expires
+const retention = time.Hour
+
+// expiryKey indexes active holds by expiry time
+const expiryKey = "hold_expiry"
+
+// ExpiredChannel is the Redis channel carrying a JSON SeatHold for every
+// hold that expires
+const ExpiredChannel = "holds:expired"
+
+var (
+	// ErrConflict is returned when another user holds one of the seats
+	ErrConflict = seatlock.ErrConflict
+	// ErrNotFound is returned for an unknown or long-finished hold
+	ErrNotFound = errors.New("hold not found")
+	// ErrNotOwner is returned when a user acts on someone else's hold
+	ErrNotOwner = errors.New("hold belongs to another user")
+	// ErrNotActive is returned for holds that were confirmed, released or expired
+	ErrNotActive = errors.New("hold is no longer active")
+	// ErrExtendLimit is returned when a hold already lasts its maximum duration
+	ErrExtendLimit = errors.New("hold has reached its maximum duration")
+	// ErrExtendTooShort is returned when an extension would not move the
+	// expiry past its current time
+	ErrExtendTooShort = errors.New("extension ends before the hold's current expiry")
+)
+
+// Policy bounds the holds of one platform
+type Policy struct {
+	// TTL is the initial duration and the default extension
+	TTL time.Duration
+	// Max is the longest a hold may last from creation, extensions included
+	Max time.Duration
+}
+
+// SeatHold is a user's temporary claim on seats of one inventory item
+type SeatHold struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Platform    string    `json:"platform"`
+	InventoryID string    `json:"inventory_id"`
+	Seats       []string  `json:"seats"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Request describes the seats to hold
+type Request struct {
+	Owner       string
+	Platform    string
+	InventoryID string
+	Seats       []string
+	TTL         time.Duration
+}
+
+// Store keeps seat holds in Redis
+type Store struct {
+	rdb *redis.Client
+}
+
+// NewStore creates a hold store
+func NewStore(rdb *redis.Client) *Store {
+	return &Store{rdb: rdb}
+}
+
+// Hold claims every seat in req or none. Seats the owner already holds are
+// moved into the new hold; seats held by anyone else fail with ErrConflict.
+func (s *Store) Hold(ctx context.Context, req Request) (*SeatHold, error) {
+	if req.Owner == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if req.Platform == "" || req.InventoryID == "" {
+		return nil, errors.New("platform and inventory ID are required")
+	}
+	if len(req.Seats) == 0 {
+		return nil, errors.New("at least one seat must be selected")
+	}
+	if req.TTL <= 0 {
+		return nil, errors.New("hold duration must be positive")
+	}
+
+	now := time.Now().UTC()
+	h := &SeatHold{
+		ID:          primitive.NewObjectID().Hex(),
+		Owner:       req.Owner,
+		Platform:    req.Platform,
+		InventoryID: req.InventoryID,
+		Seats:       req.Seats,
+		ExpiresAt:   now.Add(req.TTL),
+		Status:      StatusHeld,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	keys := seatKeys(h.Platform, h.InventoryID, h.Seats)
+	replaced, err := seatlock.Acquire(ctx, s.rdb, keys, h.Owner, h.ID, req.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, h); err != nil {
+		seatlock.Release(ctx, s.rdb, keys, h.Owner)
+		return nil, err
+	}
+	// seats moved out of the owner's older holds no longer belong to them
+	s.forget(ctx, h.Seats, replaced, StatusReleased)
+	return h, nil
+}
+
+// Extend pushes a hold's expiry to now+by, capped at its creation plus max.
+// It fails with ErrExtendLimit once the cap is reached and with
+// ErrExtendTooShort when now+by is not past the current expiry.
+func (s *Store) Extend(ctx context.Context, id, owner string, by, max time.Duration) (*SeatHold, error) {
+	h, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h.Owner != owner {
+		return nil, ErrNotOwner
+	}
+	if h.Status != StatusHeld {
+		return nil, ErrNotActive
+	}
+
+	now := time.Now().UTC()
+	limit := h.CreatedAt.Add(max)
+	if !limit.After(h.ExpiresAt) {
+		return nil, ErrExtendLimit
+	}
+	until := now.Add(by)
+	if until.After(limit) {
+		until = limit
+	}
+	if !until.After(h.ExpiresAt) {
+		return nil, ErrExtendTooShort
+	}
+
+	n, err := seatlock.Extend(ctx, s.rdb, seatKeys(h.Platform, h.InventoryID, h.Seats), seatlock.Value(h.Owner, h.ID), until.Sub(now))
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		// every seat expired or moved on before we got here
+		return nil, ErrNotActive
+	}
+	if err := s.update(ctx, id, func(h *SeatHold) { h.ExpiresAt = until }); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// ReleaseHold ends an active hold early and returns it with the seats that
+// were freed.
+func (s *Store) ReleaseHold(ctx context.Context, id, owner string) (*SeatHold, []string, error) {
+	h, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.Owner != owner {
+		return nil, nil, ErrNotOwner
+	}
+	if h.Status != StatusHeld {
+		return nil, nil, ErrNotActive
+	}
+
+	released, err := seatlock.ReleaseValue(ctx, s.rdb, seatKeys(h.Platform, h.InventoryID, h.Seats), seatlock.Value(h.Owner, h.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+	freed := make([]string, 0, len(released))
+	for i := range released {
+		freed = append(freed, h.Seats[i])
+	}
+	if err := s.update(ctx, id, func(h *SeatHold) { h.Status = StatusReleased }); err != nil {
+		return nil, nil, err
+	}
+	h.Status = StatusReleased
+	return h, freed, nil
+}
+
+// Sweep marks up to limit holds that expired before now as expired and
+// returns them. Each hold is claimed by exactly one caller, so replicas can
+// sweep concurrently without announcing an expiry twice.
+func (s *Store) Sweep(ctx context.Context, now time.Time, limit int64) ([]*SeatHold, error) {
+	ids, err := s.rdb.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprint(now.UnixMilli()),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []*SeatHold
+	for _, id := range ids {
+		claimed, err := s.rdb.ZRem(ctx, expiryKey, id).Result()
+		if err != nil {
+			return expired, err
+		}
+		if claimed == 0 {
+			continue
+		}
+		h, err := s.Get(ctx, id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		// a hold extended after it was indexed is simply not due yet
+		if h.Status == StatusHeld {
+			s.index(ctx, h)
+			continue
+		}
+		if h.Status != StatusExpired {
+			continue
+		}
+		// seat keys normally expired already; clear any left behind
+		seatlock.ReleaseValue(ctx, s.rdb, seatKeys(h.Platform, h.InventoryID, h.Seats), seatlock.Value(h.Owner, h.ID))
+		if err := s.update(ctx, id, func(h *SeatHold) { h.Status = StatusExpired }); err != nil {
+			return expired, err
+		}
+		expired = append(expired, h)
+	}
+	return expired, nil
+}
+
+// Get returns a hold by ID. A held hold past its expiry is reported as
+// expired.
+func (s *Store) Get(ctx context.Context, id string) (*SeatHold, error) {
+	data, err := s.rdb.Get(ctx, recordKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h SeatHold
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	if h.Status == StatusHeld && time.Now().After(h.ExpiresAt) {
+		h.Status = StatusExpired
+	}
+	return &h, nil
+}
+
+// Release frees the given seats held by owner and returns the seats it
+// released. Seats held by someone else are left alone.
+func (s *Store) Release(ctx context.Context, owner, platform, inventoryID string, seats []string) ([]string, error) {
+	released, err := seatlock.Release(ctx, s.rdb, seatKeys(platform, inventoryID, seats), owner)
+	if err != nil {
+		return nil, err
+	}
+
+	freed := make([]string, 0, len(released))
+	for i := range released {
+		freed = append(freed, seats[i])
+	}
+	s.forget(ctx, seats, released, StatusReleased)
+	return freed, nil
+}
+
+// Confirm ends owner's hold id because its seats were sold. Every seat
+// must still be locked by that hold; when any is not, e.g. because the
+// hold expired and another user locked it, nothing changes and the error
+// wraps seatlock.ErrNotHeld.
+func (s *Store) Confirm(ctx context.Context, platform, inventoryID string, seats []string, owner, id string) error {
+	if err := seatlock.TakeValue(ctx, s.rdb, seatKeys(platform, inventoryID, seats), seatlock.Value(owner, id)); err != nil {
+		return err
+	}
+	// The seats are sold; like forget, the record only follows the locks
+	s.update(ctx, id, func(h *SeatHold) { h.Status = StatusConfirmed })
+	return nil
+}
+
+// forget removes seats from the holds that held them, given the lock
+// values returned by seatlock by index into seats. A hold left without
+// seats ends with status.
+func (s *Store) forget(ctx context.Context, seats []string, values map[int]string, status string) {
+	byHold := map[string][]string{}
+	for i, val := range values {
+		if _, id := seatlock.Split(val); id != "" {
+			byHold[id] = append(byHold[id], seats[i])
+		}
+	}
+	for id, gone := range byHold {
+		s.update(ctx, id, func(h *SeatHold) {
+			rest := without(h.Seats, gone)
+			if len(rest) == 0 {
+				h.Status = status
+				return
+			}
+			h.Seats = rest
+		})
+	}
+}
+
+// Free returns the seats of an inventory item nobody holds right now
+func (s *Store) Free(ctx context.Context, platform, inventoryID string, seats []string) ([]string, error) {
+	if len(seats) == 0 {
+		return nil, nil
+	}
+	vals, err := s.rdb.MGet(ctx, seatKeys(platform, inventoryID, seats)...).Result()
+	if err != nil {
+		return nil, err
+	}
+	free := make([]string, 0, len(seats))
+	for i, v := range vals {
+		if v == nil {
+			free = append(free, seats[i])
+		}
+	}
+	return free, nil
+}
+
+// Locked returns the owner of every currently held seat of an inventory
+// item, by seat ID.
+func (s *Store) Locked(ctx context.Context, platform, inventoryID string) (map[string]string, error) {
+	prefix := seatKey(platform, inventoryID, "")
+	var keys []string
+	iter := s.rdb.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	locked := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return locked, nil
+	}
+	vals, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		// expired between the scan and the read
+		val, ok := v.(string)
+		if !ok {
+			continue
+		}
+		owner, _ := seatlock.Split(val)
+		locked[strings.TrimPrefix(keys[i], prefix)] = owner
+	}
+	return locked, nil
+}
+
+// save writes h and indexes it by expiry
+func (s *Store) save(ctx context.Context, h *SeatHold) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, recordKey(h.ID), data, recordTTL(h))
+		pipe.ZAdd(ctx, expiryKey, &redis.Z{Score: float64(h.ExpiresAt.UnixMilli()), Member: h.ID})
+		return nil
+	})
+	return err
+}
+
+// index (re)adds an active hold to the expiry index
+func (s *Store) index(ctx context.Context, h *SeatHold) error {
+	return s.rdb.ZAdd(ctx, expiryKey, &redis.Z{Score: float64(h.ExpiresAt.UnixMilli()), Member: h.ID}).Err()
+}
+
+// update applies fn to a stored active hold and keeps the expiry index in
+// step. Records that are gone or already finished are skipped; the seat
+// locks remain the source of truth.
+func (s *Store) update(ctx context.Context, id string, fn func(h *SeatHold)) error {
+	key := recordKey(id)
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			return err
+		}
+		var h SeatHold
+		if err := json.Unmarshal(data, &h); err != nil {
+			return err
+		}
+		if h.Status != StatusHeld {
+			return nil
+		}
+		fn(&h)
+		h.UpdatedAt = time.Now().UTC()
+		data, err = json.Marshal(&h)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, recordTTL(&h))
+			if h.Status == StatusHeld {
+				pipe.ZAdd(ctx, expiryKey, &redis.Z{Score: float64(h.ExpiresAt.UnixMilli()), Member: h.ID})
+			} else {
+				pipe.ZRem(ctx, expiryKey, h.ID)
+			}
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		err = s.rdb.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// recordTTL keeps a record until retention after its hold expires
+func recordTTL(h *SeatHold) time.Duration {
+	if ttl := time.Until(h.ExpiresAt); ttl > 0 {
+		return ttl + retention
+	}
+	return retention
+}
+
+func recordKey(id string) string {
+	return "hold:" + id
+}
+
+func seatKey(platform, inventoryID, seat string) string {
+	return fmt.Sprintf("hold_seat:%s:%s:%s", platform, inventoryID, seat)
+}
+
+func seatKeys(platform, inventoryID string, seats []string) []string {
+	keys := make([]string, len(seats))
+	for i, seat := range seats {
+		keys[i] = seatKey(platform, inventoryID, seat)
+	}
+	return keys
+}
+
+// without returns list minus every entry in drop
+func without(list, drop []string) []string {
+	skip := make(map[string]bool, len(drop))
+	for _, d := range drop {
+		skip[d] = true
+	}
+	var out []string
+	for _, v := range list {
+		if !skip[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
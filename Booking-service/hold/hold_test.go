@@ -0,0 +1,73 @@
+package hold
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// testStore returns a store on an in-memory miniredis and a function that
+// lets seat locks run out
+func testStore(t *testing.T) (*Store, func(time.Duration)) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewStore(rdb), mr.FastForward
+}
+
+func TestExtend(t *testing.T) {
+	ctx := context.Background()
+	s, _ := testStore(t)
+	h, err := s.Hold(ctx, Request{Owner: "u1", Platform: "movie", InventoryID: "show-1", Seats: []string{"A1"}, TTL: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+
+	if _, err := s.Extend(ctx, h.ID, "u1", time.Minute, 15*time.Minute); !errors.Is(err, ErrExtendTooShort) {
+		t.Fatalf("extending by less than the time left: got %v, want ErrExtendTooShort", err)
+	}
+	if _, err := s.Extend(ctx, h.ID, "u2", 20*time.Minute, 15*time.Minute); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("extending someone else's hold: got %v, want ErrNotOwner", err)
+	}
+
+	extended, err := s.Extend(ctx, h.ID, "u1", 20*time.Minute, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if want := h.CreatedAt.Add(15 * time.Minute); !extended.ExpiresAt.Equal(want) {
+		t.Fatalf("extended to %v, want the cap %v", extended.ExpiresAt, want)
+	}
+
+	if _, err := s.Extend(ctx, h.ID, "u1", 20*time.Minute, 15*time.Minute); !errors.Is(err, ErrExtendLimit) {
+		t.Fatalf("extending past the cap: got %v, want ErrExtendLimit", err)
+	}
+}
+
+func TestFree(t *testing.T) {
+	ctx := context.Background()
+	s, fastForward := testStore(t)
+	seats := []string{"A1", "A2", "A3"}
+	if _, err := s.Hold(ctx, Request{Owner: "u1", Platform: "movie", InventoryID: "show-1", Seats: seats, TTL: time.Second}); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	fastForward(2 * time.Second)
+
+	// another user takes one of the seats once the first hold ran out
+	if _, err := s.Hold(ctx, Request{Owner: "u2", Platform: "movie", InventoryID: "show-1", Seats: []string{"A2"}, TTL: time.Minute}); err != nil {
+		t.Fatalf("Hold after expiry: %v", err)
+	}
+
+	free, err := s.Free(ctx, "movie", "show-1", seats)
+	if err != nil {
+		t.Fatalf("Free: %v", err)
+	}
+	if want := []string{"A1", "A3"}; !reflect.DeepEqual(free, want) {
+		t.Fatalf("free seats %v, want %v", free, want)
+	}
+}
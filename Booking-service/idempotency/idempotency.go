@@ -0,0 +1,188 @@
+// Package idempotency makes retried POSTs safe.
+//
+// The first response for an (user, Idempotency-Key) pair is stored in Redis
+// and replayed for later requests with the same key. Reusing a key with a
+// different body is rejected with 422; a retry that arrives while the first
+// request is still running gets 409.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ansh0014/booking/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// Header names
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// DefaultTTL is how long a stored response can be replayed.
+const DefaultTTL = 24 * time.Hour
+
+// lease is how long an in-progress claim blocks retries. A request that
+// dies without answering (a crash, a lost replica) frees its key after it.
+const lease = time.Minute
+
+// maxKeyLen bounds client-chosen keys.
+const maxKeyLen = 255
+
+// maxBody bounds the request body read for fingerprinting.
+const maxBody = 1 << 20
+
+const (
+	stateInProgress = "in_progress"
+	stateDone       = "done"
+)
+
+type record struct {
+	Fingerprint string `json:"fingerprint"`
+	State       string `json:"state"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Guard stores and replays responses.
+type Guard struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewGuard returns a guard keeping responses for ttl.
+func NewGuard(rdb *redis.Client, ttl time.Duration) *Guard {
+	return &Guard{rdb: rdb, ttl: ttl}
+}
+
+func redisKey(userID, key string) string {
+	return "idempotency:" + userID + ":" + key
+}
+
+// Middleware applies idempotency to requests carrying an Idempotency-Key.
+// Requests without the header pass straight through.
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLen {
+			utils.RespondWithError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+		userID, _ := utils.GetUserFromContext(r.Context())
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fp := fingerprint(r, body)
+
+		ctx := r.Context()
+		rk := redisKey(userID, key)
+		claim, _ := json.Marshal(record{Fingerprint: fp, State: stateInProgress})
+		ok, err := g.rdb.SetNX(ctx, rk, claim, lease).Result()
+		if err != nil {
+			// fail open: better a possible duplicate than a failed checkout
+			slog.WarnContext(ctx, "idempotency store unavailable", "error", err.Error())
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			g.replay(ctx, w, rk, fp)
+			return
+		}
+
+		// A request that ends in a server error or a panic has nothing to
+		// replay; dropping its claim lets the client retry with the same key
+		replayable := false
+		defer func() {
+			if !replayable {
+				g.rdb.Del(context.WithoutCancel(ctx), rk)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= 500 {
+			return
+		}
+		replayable = true
+		done, _ := json.Marshal(record{
+			Fingerprint: fp,
+			State:       stateDone,
+			Status:      rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+		if err := g.rdb.Set(context.WithoutCancel(ctx), rk, done, g.ttl).Err(); err != nil {
+			slog.WarnContext(ctx, "idempotency store failed", "error", err.Error())
+		}
+	})
+}
+
+func (g *Guard) replay(ctx context.Context, w http.ResponseWriter, rk, fp string) {
+	data, err := g.rdb.Get(ctx, rk).Bytes()
+	if err != nil {
+		utils.ConflictResponse(w, "A request with this Idempotency-Key is already in progress")
+		return
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		utils.ServerErrorResponse(w, "Corrupt idempotency record")
+		return
+	}
+	if rec.Fingerprint != fp {
+		utils.RespondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+		return
+	}
+	if rec.State != stateDone {
+		utils.ConflictResponse(w, "A request with this Idempotency-Key is already in progress")
+		return
+	}
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Body)
+}
+
+// fingerprint identifies the request a key was first used with.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response through while keeping a copy.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
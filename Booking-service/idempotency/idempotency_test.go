@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func testGuard(t *testing.T) (*Guard, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewGuard(rdb, DefaultTTL), rdb
+}
+
+// post sends a keyed POST through h, recovering from panics the way the
+// recovery middleware does
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/bookings", strings.NewReader(body))
+	r.Header.Set(Header, key)
+	func() {
+		defer func() {
+			if recover() != nil {
+				rec.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(rec, r)
+	}()
+	return rec
+}
+
+func TestClaimIsLeasedUntilStored(t *testing.T) {
+	g, rdb := testGuard(t)
+	ctx := context.Background()
+	rk := redisKey("", "k1")
+
+	var claimTTL time.Duration
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claimTTL = rdb.TTL(ctx, rk).Val()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	if rec := post(h, "k1", `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if claimTTL <= 0 || claimTTL > lease {
+		t.Fatalf("in-progress claim lives %v, want at most %v", claimTTL, lease)
+	}
+	if ttl := rdb.TTL(ctx, rk).Val(); ttl <= lease {
+		t.Fatalf("stored response lives %v, want the full TTL", ttl)
+	}
+	if rec := post(h, "k1", `{}`); rec.Header().Get(ReplayedHeader) != "true" || rec.Code != http.StatusCreated {
+		t.Fatalf("retry was not replayed: %d", rec.Code)
+	}
+}
+
+func TestFailedRequestsFreeTheKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) }},
+		{"panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := testGuard(t)
+			calls := 0
+			h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				tt.handler(w, r)
+			}))
+			post(h, "k1", `{}`)
+			if rec := post(h, "k1", `{}`); rec.Code == http.StatusConflict {
+				t.Fatal("retry after a failed request got 409")
+			}
+			if calls != 2 {
+				t.Fatalf("handler ran %d times, want 2", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package leader elects one replica to run a background job.
+//
+// Replicas compete for a lease on a Redis key. The holder renews it every
+// time it runs the job; if it stops renewing, the lease expires and another
+// replica takes over on its next attempt.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// acquireScript takes the lease when it is free and renews it when this
+// replica already holds it. Returns 1 when the caller holds the lease.
+var acquireScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if cur == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// resignScript drops the lease only if this replica holds it
+var resignScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Lease is one replica's claim on leadership for a named job
+type Lease struct {
+	rdb *redis.Client
+	key string
+	id  string
+	ttl time.Duration
+}
+
+// NewLease creates a lease on leader:<name> that lasts ttl unless renewed
+func NewLease(rdb *redis.Client, name string, ttl time.Duration) *Lease {
+	return &Lease{
+		rdb: rdb,
+		key: "leader:" + name,
+		id:  instanceID(),
+		ttl: ttl,
+	}
+}
+
+// ID identifies this replica in the lease key
+func (l *Lease) ID() string {
+	return l.id
+}
+
+// Acquire takes or renews the lease and reports whether this replica leads
+func (l *Lease) Acquire(ctx context.Context) (bool, error) {
+	n, err := acquireScript.Run(ctx, l.rdb, []string{l.key}, l.id, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Resign gives the lease up so another replica can take over at once
+func (l *Lease) Resign(ctx context.Context) error {
+	return resignScript.Run(ctx, l.rdb, []string{l.key}, l.id).Err()
+}
+
+// instanceID names this process uniquely across replicas
+func instanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
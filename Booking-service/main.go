@@ -3,27 +3,69 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	platform "github.com/ansh0014/booking/Platform"
 	"github.com/ansh0014/booking/Platform/event"
 	"github.com/ansh0014/booking/Platform/flight"
 	"github.com/ansh0014/booking/Platform/movie"
 	"github.com/ansh0014/booking/Platform/railway"
 	"github.com/ansh0014/booking/config"
+	"github.com/ansh0014/booking/handler"
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/leader"
+	"github.com/ansh0014/booking/outbox"
+	"github.com/ansh0014/booking/pricing"
 	"github.com/ansh0014/booking/router"
 	"github.com/ansh0014/booking/service"
+	"github.com/ansh0014/booking/stream"
+	"github.com/ansh0014/booking/tracing"
 	"github.com/joho/godotenv"
 )
 
+// Background worker intervals
+const (
+	// holdSweepInterval is how often expired seat holds are looked for
+	holdSweepInterval = 5 * time.Second
+	// staleBookingInterval is how often pending bookings past expiry are expired
+	staleBookingInterval = 30 * time.Second
+	// reconcileInterval is how often seat flags and counters are reconciled
+	reconcileInterval = 5 * time.Minute
+	// outboxRelayInterval is how often unpublished booking events are relayed
+	outboxRelayInterval = time.Second
+	// outboxLeaseTTL is how long a silent relay keeps its leadership
+	outboxLeaseTTL = 10 * time.Second
+)
+
 func main() {
+	// Structured JSON logs; log.Printf output goes through the same handler
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	// Load environment variables
 	err := godotenv.Load()
 	if err != nil {
 		log.Printf("Warning: Error loading .env file: %v", err)
 	}
 
+	// The payment service authenticates its callbacks with this token
+	internalToken := config.GetInternalToken()
+	if internalToken == "" {
+		log.Fatal("BOOKING_INTERNAL_TOKEN is required")
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatalf("Tracing setup failed: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database connection
 	if err := config.InitMongo(); err != nil {
 		log.Fatalf("MongoDB connection failed: %v", err)
@@ -37,11 +79,66 @@ func main() {
 	log.Println("Redis connected successfully")
 
 	// Initialize platform services
-	platformServices := initPlatformServices()
+	platformServices, events := initPlatformServices()
 	log.Println("Platform services initialized")
 
+	// Background workers stop when a shutdown signal arrives
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var workers sync.WaitGroup
+
+	// Fan seat events out to live stream subscribers
+	hub := stream.NewHub(config.RedisClient, platformServices.Platforms)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		hub.Run(ctx)
+	}()
+
+	// Announce expired seat holds and expire their pending bookings
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		platformServices.Hold.RunExpiry(ctx, holdSweepInterval)
+	}()
+
+	// Backfill references on bookings saved before they were stored
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		result, err := platformServices.Booking.MigrateReferences(ctx, platformServices.Platforms)
+		if err != nil {
+			log.Printf("Booking reference migration failed: %v", err)
+			return
+		}
+		if result.Migrated > 0 || result.Unresolved > 0 {
+			log.Printf("Booking reference migration: %d migrated, %d unresolved", result.Migrated, result.Unresolved)
+		}
+	}()
+
+	// Expire stale bookings and reconcile seats on one replica at a time
+	lease := leader.NewLease(config.RedisClient, "booking-reconcile", 3*staleBookingInterval)
+	reconciler := service.NewReconcileService(platformServices.Booking, platformServices.Hold, platformServices.Platforms, lease)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reconciler.Run(ctx, staleBookingInterval, reconcileInterval)
+	}()
+
+	// Publish booking events from the outbox on one replica at a time
+	relay := outbox.NewRelay(
+		events,
+		outbox.NewRedisStreams(config.RedisClient, outbox.Stream),
+		leader.NewLease(config.RedisClient, "booking-outbox", outboxLeaseTTL),
+	)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		relay.Run(ctx, outboxRelayInterval)
+	}()
+
 	// Setup router with platform services
-	r := router.SetupRoutes(platformServices)
+	r := router.SetupRoutes(platformServices, hub, internalToken)
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -58,13 +155,19 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	log.Printf("Booking service starting on port %s...", port)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
-	}
+	go func() {
+		log.Printf("Booking service starting on port %s...", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	gracefulShutdown(server, &workers)
 }
 
-func initPlatformServices() map[string]interface{} {
+func initPlatformServices() (handler.Services, *outbox.Store) {
 	db := config.MongoDB
 	redisClient := config.RedisClient
 
@@ -74,53 +177,97 @@ func initPlatformServices() map[string]interface{} {
 	eventRepo := event.NewRepository(db)
 	movieRepo := movie.NewRepository(db)
 
+	// Every platform holds seats through the same store
+	holds := hold.NewStore(redisClient)
+
 	// Create services
-	flightService := flight.NewService(flightRepo, redisClient)
-	railwayService := railway.NewService(railwayRepo, redisClient)
-	eventService := event.NewService(eventRepo, redisClient)
-	movieService := movie.NewService(movieRepo, redisClient)
+	flightService := flight.NewService(flightRepo, redisClient, holds)
+	railwayService := railway.NewService(railwayRepo, redisClient, holds)
+	eventService := event.NewService(eventRepo, redisClient, holds)
+	movieService := movie.NewService(movieRepo, redisClient, holds)
+
+	// Every vertical registered here is reachable through the generic
+	// seat and booking endpoints
+	platforms := platform.NewRegistry(flightService, railwayService, eventService, movieService)
 
 	// Create booking and seat services
 	bookingService := service.NewBookingService(db, redisClient)
-	seatService := service.NewSeatService(redisClient, map[string]interface{}{
-		"flight":  flightService,
-		"railway": railwayService,
-		"event":   eventService,
-		"movie":   movieService,
-	})
-
-	// Set up circular reference
-	bookingService.SetSeatService(seatService)
+	seatService := service.NewSeatService(platforms)
 
-	// Return all services in a map
-	return map[string]interface{}{
-		"flight":  flightService,
-		"railway": railwayService,
-		"event":   eventService,
-		"movie":   movieService,
-		"booking": bookingService,
-		"seat":    seatService,
+	holdService := service.NewHoldService(holds, platforms, redisClient)
+
+	// Price seats with the rules file when one is configured
+	rules := pricing.DefaultRules()
+	if path := os.Getenv("PRICING_RULES"); path != "" {
+		loaded, err := pricing.LoadRules(path)
+		if err != nil {
+			log.Fatalf("Pricing rules failed to load: %v", err)
+		}
+		rules = loaded
 	}
-}
+	pricingEngine := pricing.NewEngine(platforms, rules)
 
-// Graceful shutdown handler
-func setupGracefulShutdown(server *http.Server) {
-	// Create a channel to listen for interrupt signals
-	c := make(chan os.Signal, 1)
-	// signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// Booking changes write their events to the outbox
+	events := outbox.NewStore(db)
+	initCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := events.Init(initCtx); err != nil {
+		log.Fatalf("Outbox setup failed: %v", err)
+	}
+	if !events.Transactional() {
+		// Without transactions a crash between a booking change and its
+		// event loses the event, so running that way must be asked for
+		if !config.OutboxAllowNonAtomic() {
+			log.Fatal("Outbox setup failed: MongoDB does not support transactions; run a replica set or set OUTBOX_ALLOW_NON_ATOMIC=true")
+		}
+		events.AllowNonAtomic()
+		log.Println("WARNING: OUTBOX_ALLOW_NON_ATOMIC is set and MongoDB does not support transactions; booking events are written after, not with, booking changes and can be lost")
+	}
+
+	// Set up circular references
+	bookingService.SetSeatService(seatService)
+	bookingService.SetPricingEngine(pricingEngine)
+	bookingService.SetOutbox(events)
+	bookingService.SetHoldService(holdService)
+	holdService.SetBookingService(bookingService)
 
-	// Block until a signal is received
-	<-c
+	return handler.Services{
+		Platforms: platforms,
+		Flight:    flightService,
+		Railway:   railwayService,
+		Event:     eventService,
+		Movie:     movieService,
+		Booking:   bookingService,
+		Seat:      seatService,
+		Hold:      holdService,
+		Pricing:   pricingEngine,
+	}, events
+}
 
-	// Create a deadline for graceful shutdown
+// gracefulShutdown stops accepting connections, drains in-flight requests,
+// waits for background workers and then closes the data stores.
+func gracefulShutdown(server *http.Server, workers *sync.WaitGroup) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	// Attempt graceful shutdown
 	log.Println("Shutting down server...")
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("Background workers did not stop in time")
+	}
+
+	if err := config.Close(ctx); err != nil {
+		log.Printf("Closing data stores: %v", err)
+	}
 	log.Println("Server gracefully stopped")
 }
@@ -0,0 +1,204 @@
+// Package metrics defines the Prometheus metrics exported by the booking
+// service on /metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const namespace = "booking"
+
+var (
+	// HTTPRequests counts handled requests by method, route template and status
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by method, route template and status
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MongoDuration observes MongoDB command latency
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "MongoDB command latency, by command and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command", "outcome"})
+
+	// RedisDuration observes Redis command latency
+	RedisDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Redis command latency, by command and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command", "outcome"})
+
+	// SeatLockAttempts counts seat lock requests per platform
+	SeatLockAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_lock_attempts_total",
+		Help:      "Seat lock attempts, by platform.",
+	}, []string{"platform"})
+
+	// SeatLockConflicts counts lock attempts rejected because a seat was held by someone else
+	SeatLockConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_lock_conflicts_total",
+		Help:      "Seat lock attempts rejected because a seat was already held, by platform.",
+	}, []string{"platform"})
+
+	// BookingsCreated counts bookings created
+	BookingsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created.",
+	})
+
+	// BookingsConfirmed counts bookings confirmed after payment
+	BookingsConfirmed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_confirmed_total",
+		Help:      "Bookings confirmed.",
+	})
+
+	// BookingsCancelled counts bookings cancelled
+	BookingsCancelled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_cancelled_total",
+		Help:      "Bookings cancelled.",
+	})
+
+	// BookingsExpired counts open bookings whose seat hold ran out
+	BookingsExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_expired_total",
+		Help:      "Open bookings expired because their seat hold ran out.",
+	})
+
+	// HoldsExpired counts seat holds that ran out before being confirmed or released
+	HoldsExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_holds_expired_total",
+		Help:      "Seat holds that expired, by platform.",
+	}, []string{"platform"})
+
+	// ReconcileFixes counts seat flags and counters the reconciler corrected
+	ReconcileFixes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_reconcile_fixes_total",
+		Help:      "Seat flags and counters corrected by the reconciler, by platform and kind.",
+	}, []string{"platform", "kind"})
+
+	// OutboxPublished counts outbox events handed to the broker
+	OutboxPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_published_total",
+		Help:      "Outbox events published, by event type.",
+	}, []string{"type"})
+
+	// OutboxPublishErrors counts failed attempts to publish an outbox event
+	OutboxPublishErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_publish_errors_total",
+		Help:      "Failed attempts to publish an outbox event.",
+	})
+)
+
+// Handler serves the registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTP records one handled request. An empty route means no route matched.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(method, route, code).Inc()
+	HTTPDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// MongoMonitor returns a command monitor that records MongoDB command latency
+// and then forwards each event to next, if any
+func MongoMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if next != nil && next.Started != nil {
+				next.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			MongoDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			MongoDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, e)
+			}
+		},
+	}
+}
+
+type redisStartKey struct{}
+
+// RedisHook records Redis command latency
+type RedisHook struct{}
+
+var _ redis.Hook = RedisHook{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if start, ok := ctx.Value(redisStartKey{}).(time.Time); ok {
+		RedisDuration.WithLabelValues(cmd.Name(), redisOutcome(cmd.Err())).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if start, ok := ctx.Value(redisStartKey{}).(time.Time); ok {
+		var err error
+		for _, cmd := range cmds {
+			if cmd.Err() != nil && cmd.Err() != redis.Nil {
+				err = cmd.Err()
+				break
+			}
+		}
+		RedisDuration.WithLabelValues("pipeline", redisOutcome(err)).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+// redisOutcome treats redis.Nil (key not found) as a successful call
+func redisOutcome(err error) string {
+	if err != nil && err != redis.Nil {
+		return "error"
+	}
+	return "ok"
+}
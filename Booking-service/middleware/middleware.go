@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/ansh0014/booking/metrics"
 	"github.com/ansh0014/booking/utils"
+	"github.com/gorilla/mux"
 )
 
 // AuthMiddleware extracts the user ID from the Authorization header
@@ -41,16 +49,21 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// ServiceInjector injects services into the request context
-func ServiceInjector(services map[string]interface{}) func(http.Handler) http.Handler {
+// InternalTokenHeader carries the token shared by the services for calls
+// that bypass user authentication
+const InternalTokenHeader = "X-Internal-Token"
+
+// InternalAuth only lets through requests carrying token in the
+// X-Internal-Token header. Without a token every request is refused.
+func InternalAuth(token string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Add services to context
-			ctx := r.Context()
-			for name, service := range services {
-				ctx = context.WithValue(ctx, name+"Service", service)
+			got := r.Header.Get(InternalTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				utils.UnauthorizedResponse(w, "Invalid internal token")
+				return
 			}
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -60,6 +73,10 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				slog.ErrorContext(r.Context(), "panic recovered",
+					"request_id", utils.GetRequestIDFromContext(r.Context()),
+					"path", r.URL.Path,
+					"error", err)
 				utils.ServerErrorResponse(w, "Internal server error")
 			}
 		}()
@@ -67,40 +84,107 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// LoggingMiddleware logs requests
-func LoggingMiddleware(next http.Handler) http.Handler {
+// RequestIDMiddleware reuses the X-Request-ID set by the gateway or generates
+// one, stores it in the context and echoes it on the response
+func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Log request
-		// In a real system, you would use a proper logger
-		next.ServeHTTP(w, r)
+		requestID := r.Header.Get(utils.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = utils.NewRequestID()
+			r.Header.Set(utils.RequestIDHeader, requestID)
+		}
+		w.Header().Set(utils.RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(utils.WithRequestID(r.Context(), requestID)))
 	})
 }
 
-// CORSMiddleware handles CORS
-func CORSMiddleware(next http.Handler) http.Handler {
+// LoggingMiddleware writes one JSON access log line per request and records
+// the request in the HTTP metrics
+func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+		next.ServeHTTP(rec, r)
 
-		next.ServeHTTP(w, r)
+		elapsed := time.Since(start)
+		route := ""
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("request_id", utils.GetRequestIDFromContext(r.Context())),
+			slog.String("user_id", r.Header.Get("X-User-ID")),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+		)
+		metrics.ObserveHTTP(r.Method, route, rec.status, elapsed)
 	})
 }
 
+// statusRecorder captures the status code and response size for access logs
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming responses
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		s.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack supports upgraded connections
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := s.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijack not supported")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
 // isPublicEndpoint checks if the endpoint is public
 func isPublicEndpoint(path string) bool {
 	publicEndpoints := []string{
 		"/health",
+		"/metrics",
 		"/api/platforms/flight/search",
 		"/api/platforms/railway/search",
 		"/api/platforms/event/search",
 		"/api/platforms/movie/search",
 		"/api/platforms/movie",
+		"/api/quotes",
+		// internal routes check the service token themselves
+		"/internal/",
 	}
 
 	for _, endpoint := range publicEndpoints {
@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// Booking lifecycle states
+const (
+	// BookingHeld bookings hold their seats and wait for payment to start
+	BookingHeld = "held"
+	// BookingPendingPayment bookings have a payment in progress
+	BookingPendingPayment = "pending_payment"
+	// BookingConfirmed bookings are paid and their seats are reserved
+	BookingConfirmed = "confirmed"
+	// BookingCancelled bookings were cancelled before or after payment
+	BookingCancelled = "cancelled"
+	// BookingRefunded bookings had their whole payment returned
+	BookingRefunded = "refunded"
+	// BookingPartiallyRefunded bookings had part of their payment returned
+	BookingPartiallyRefunded = "partially_refunded"
+	// BookingExpired bookings lost their seat hold before being paid
+	BookingExpired = "expired"
+	// BookingCheckedIn bookings were used at the venue
+	BookingCheckedIn = "checked_in"
+
+	// BookingPending is the single open state of bookings created before the
+	// lifecycle was modelled. It behaves like BookingHeld.
+	BookingPending = "pending"
+)
+
+// Actors recorded in booking history for changes no user made
+const (
+	ActorSystem  = "system"
+	ActorPayment = "payment"
+)
+
+// bookingTransitions lists the states each state may move to
+var bookingTransitions = map[string][]string{
+	BookingHeld:              {BookingPendingPayment, BookingConfirmed, BookingCancelled, BookingExpired},
+	BookingPending:           {BookingPendingPayment, BookingConfirmed, BookingCancelled, BookingExpired},
+	BookingPendingPayment:    {BookingConfirmed, BookingCancelled, BookingExpired},
+	BookingConfirmed:         {BookingCancelled, BookingRefunded, BookingPartiallyRefunded, BookingCheckedIn},
+	BookingPartiallyRefunded: {BookingPartiallyRefunded, BookingRefunded, BookingCancelled, BookingCheckedIn},
+	BookingCancelled:         {BookingRefunded, BookingPartiallyRefunded},
+	BookingRefunded:          {},
+	BookingExpired:           {BookingRefunded},
+	BookingCheckedIn:         {},
+}
+
+// OpenBookingStatuses are the states in which a booking still waits on its
+// seat hold
+var OpenBookingStatuses = []string{BookingHeld, BookingPending, BookingPendingPayment}
+
+// ReservedBookingStatuses are the states in which a booking's seats are
+// permanently taken
+var ReservedBookingStatuses = []string{BookingConfirmed, BookingPartiallyRefunded, BookingCheckedIn}
+
+// CanTransition reports whether a booking may move from one state to another
+func CanTransition(from, to string) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBookingStatus reports whether status is a known lifecycle state
+func IsBookingStatus(status string) bool {
+	_, ok := bookingTransitions[status]
+	return ok
+}
+
+// BookingEvent is one entry of a booking's status history
+type BookingEvent struct {
+	From   string    `json:"from,omitempty" bson:"from,omitempty"`
+	To     string    `json:"to" bson:"to"`
+	Actor  string    `json:"actor" bson:"actor"`
+	Reason string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	At     time.Time `json:"at" bson:"at"`
+}
+
+// UserActor is the history actor for changes made by a user
+func UserActor(userID string) string {
+	return "user:" + userID
+}
@@ -0,0 +1,90 @@
+package model
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		// open bookings
+		{BookingHeld, BookingPendingPayment, true},
+		{BookingHeld, BookingConfirmed, true},
+		{BookingHeld, BookingCancelled, true},
+		{BookingHeld, BookingExpired, true},
+		{BookingHeld, BookingRefunded, false},
+		{BookingHeld, BookingCheckedIn, false},
+		{BookingPending, BookingPendingPayment, true},
+		{BookingPending, BookingExpired, true},
+		{BookingPending, BookingHeld, false},
+		{BookingPendingPayment, BookingConfirmed, true},
+		{BookingPendingPayment, BookingExpired, true},
+		{BookingPendingPayment, BookingHeld, false},
+		{BookingPendingPayment, BookingPendingPayment, false},
+
+		// paid bookings
+		{BookingConfirmed, BookingCheckedIn, true},
+		{BookingConfirmed, BookingRefunded, true},
+		{BookingConfirmed, BookingPartiallyRefunded, true},
+		{BookingConfirmed, BookingCancelled, true},
+		{BookingConfirmed, BookingExpired, false},
+		{BookingConfirmed, BookingConfirmed, false},
+		{BookingPartiallyRefunded, BookingPartiallyRefunded, true},
+		{BookingPartiallyRefunded, BookingRefunded, true},
+		{BookingPartiallyRefunded, BookingPendingPayment, false},
+		{BookingCancelled, BookingRefunded, true},
+		{BookingCancelled, BookingConfirmed, false},
+
+		// a payment that completes after the hold expired is refunded
+		{BookingExpired, BookingRefunded, true},
+		{BookingExpired, BookingConfirmed, false},
+		{BookingExpired, BookingCancelled, false},
+
+		// final states
+		{BookingRefunded, BookingConfirmed, false},
+		{BookingRefunded, BookingRefunded, false},
+		{BookingCheckedIn, BookingCancelled, false},
+
+		// unknown states
+		{"shipped", BookingConfirmed, false},
+		{BookingHeld, "shipped", false},
+		{"", BookingHeld, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// Every state a booking may move to must itself be a known state, and the
+// final states must not lead anywhere.
+func TestBookingTransitionsClosed(t *testing.T) {
+	for from, next := range bookingTransitions {
+		for _, to := range next {
+			if !IsBookingStatus(to) {
+				t.Errorf("%s may move to unknown state %q", from, to)
+			}
+		}
+	}
+	for _, final := range []string{BookingRefunded, BookingCheckedIn} {
+		if n := len(bookingTransitions[final]); n != 0 {
+			t.Errorf("final state %s has %d transitions", final, n)
+		}
+	}
+}
+
+// Open bookings may always be cancelled or expired, and no reserved
+// booking may expire.
+func TestBookingStatusGroups(t *testing.T) {
+	for _, open := range OpenBookingStatuses {
+		if !CanTransition(open, BookingCancelled) || !CanTransition(open, BookingExpired) {
+			t.Errorf("open state %s cannot be cancelled and expired", open)
+		}
+	}
+	for _, reserved := range ReservedBookingStatuses {
+		if CanTransition(reserved, BookingExpired) {
+			t.Errorf("reserved state %s may expire", reserved)
+		}
+	}
+}
@@ -5,17 +5,50 @@ import (
 )
 
 type Booking struct {
-	ID          string    `json:"id" bson:"_id,omitempty"`
-	UserID      string    `json:"user_id" bson:"user_id"`
-	ShowID      string    `json:"show_id" bson:"show_id"`
-	Seats       []string  `json:"seats" bson:"seats"`
-	TotalPrice  float64   `json:"total_price" bson:"total_price"`
-	Status      string    `json:"status" bson:"status"`
-	PaymentID   string    `json:"payment_id,omitempty" bson:"payment_id,omitempty"`
-	BookingTime time.Time `json:"booking_time" bson:"booking_time"`
-	ExpiryTime  time.Time `json:"expiry_time,omitempty" bson:"expiry_time,omitempty"`
-	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+	ID          string          `json:"id" bson:"_id,omitempty"`
+	UserID      string          `json:"user_id" bson:"user_id"`
+	Platform    string          `json:"platform" bson:"platform,omitempty"`
+	HoldID      string          `json:"hold_id,omitempty" bson:"hold_id,omitempty"`
+	InventoryID string          `json:"inventory_id" bson:"inventory_id,omitempty"`
+	ShowID      string          `json:"show_id" bson:"show_id"` // same as InventoryID, kept for older clients
+	Seats       []string        `json:"seats" bson:"seats"`
+	SeatDetails []BookedSeat    `json:"seat_details,omitempty" bson:"seat_details,omitempty"`
+	Display     *BookingDisplay `json:"display,omitempty" bson:"display,omitempty"`
+	TotalPrice  float64         `json:"total_price" bson:"total_price"`
+	Currency    string          `json:"currency,omitempty" bson:"currency,omitempty"`
+	Price       *Price          `json:"price,omitempty" bson:"price,omitempty"`
+	Status      string          `json:"status" bson:"status"`
+	Version     int64           `json:"version" bson:"version"`
+	History     []BookingEvent  `json:"history,omitempty" bson:"history,omitempty"`
+	PaymentID   string          `json:"payment_id,omitempty" bson:"payment_id,omitempty"`
+	BookingTime time.Time       `json:"booking_time" bson:"booking_time"`
+	ExpiryTime  time.Time       `json:"expiry_time,omitempty" bson:"expiry_time,omitempty"`
+	CreatedAt   time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// InventoryKey is the booked show, flight, train or event, also for
+// bookings saved before InventoryID existed
+func (b *Booking) InventoryKey() string {
+	if b.InventoryID != "" {
+		return b.InventoryID
+	}
+	return b.ShowID
+}
+
+// BookedSeat is a seat as it was sold
+type BookedSeat struct {
+	ID    string  `json:"id" bson:"id"`
+	Label string  `json:"label" bson:"label"`
+	Class string  `json:"class,omitempty" bson:"class,omitempty"`
+	Price float64 `json:"price" bson:"price"`
+}
+
+// BookingDisplay is what a ticket shows about the booked show, trip or event
+type BookingDisplay struct {
+	Title    string    `json:"title" bson:"title"`
+	Venue    string    `json:"venue,omitempty" bson:"venue,omitempty"`
+	StartsAt time.Time `json:"starts_at" bson:"starts_at"`
 }
 
 type Seat struct {
@@ -37,9 +70,10 @@ type CreateBookingRequest struct {
 type BookingRequest struct {
 	Platform    string   `json:"platform" validate:"required"`
 	PlatformID  string   `json:"platform_id" validate:"required"`
-	SeatIDs     []string `json:"seat_ids" validate:"required,min=1"`
+	SeatIDs     []string `json:"seat_ids" validate:"required,min=1,max=10,unique,dive,required"`
 	UserID      string   `json:"user_id,omitempty"`
 	PaymentType string   `json:"payment_type,omitempty"`
+	PromoCode   string   `json:"promo_code,omitempty"`
 }
 
 type BookingResponse struct {
@@ -58,11 +92,28 @@ type LockSeatsRequest struct {
 type SeatLockRequest struct {
 	Platform   string   `json:"platform" validate:"required"`
 	PlatformID string   `json:"platform_id" validate:"required"`
-	SeatIDs    []string `json:"seat_ids" validate:"required,min=1"`
+	SeatIDs    []string `json:"seat_ids" validate:"required,min=1,max=10,unique,dive,required"`
+}
+
+type QuoteRequest struct {
+	Platform   string   `json:"platform" validate:"required"`
+	PlatformID string   `json:"platform_id" validate:"required"`
+	SeatIDs    []string `json:"seat_ids" validate:"required,min=1,max=10,unique,dive,required"`
+	PromoCode  string   `json:"promo_code,omitempty"`
+}
+
+type CancelBookingRequest struct {
+	Reason  string `json:"reason" validate:"max=500"`
+	Version *int64 `json:"version,omitempty"`
+}
+
+type ExtendHoldRequest struct {
+	Seconds int `json:"seconds" validate:"omitempty,min=1,max=3600"`
 }
 
 type AvailabilityRequest struct {
-	ShowID string `json:"show_id" validate:"required"`
+	Platform string `json:"platform" validate:"required"`
+	ShowID   string `json:"show_id" validate:"required"`
 }
 
 type AvailabilityResponse struct {
@@ -72,3 +123,30 @@ type AvailabilityResponse struct {
 	Booked    []string  `json:"booked"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Payment statuses reported by the payment service
+const (
+	PaymentPending   = "pending"
+	PaymentCompleted = "completed"
+	PaymentFailed    = "failed"
+	PaymentRefunded  = "refunded"
+)
+
+// PaymentEvent is a payment status change reported by the payment service
+type PaymentEvent struct {
+	PaymentID string  `json:"payment_id" validate:"required"`
+	BookingID string  `json:"booking_id" validate:"required"`
+	Status    string  `json:"status" validate:"required,oneof=pending completed failed refunded"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency,omitempty"`
+}
+
+// PaymentOutcome tells the payment service what a payment event did to its
+// booking. Refund is set when the payment cannot be kept, e.g. because the
+// seat hold ran out before the payment completed.
+type PaymentOutcome struct {
+	BookingID     string `json:"booking_id"`
+	BookingStatus string `json:"booking_status"`
+	Refund        bool   `json:"refund"`
+	Reason        string `json:"reason,omitempty"`
+}
@@ -0,0 +1,30 @@
+package model
+
+// Price line kinds
+const (
+	PriceSeat     = "seat"
+	PriceFee      = "fee"
+	PriceTax      = "tax"
+	PriceDiscount = "discount"
+)
+
+// PriceLine is one line item of a price. Discount amounts are negative.
+type PriceLine struct {
+	Kind   string  `json:"kind" bson:"kind"`
+	Code   string  `json:"code" bson:"code"`
+	Label  string  `json:"label" bson:"label"`
+	Amount float64 `json:"amount" bson:"amount"`
+}
+
+// Price is the itemised price of a set of seats
+type Price struct {
+	Platform    string      `json:"platform" bson:"platform"`
+	InventoryID string      `json:"inventory_id" bson:"inventory_id"`
+	Currency    string      `json:"currency" bson:"currency"`
+	Lines       []PriceLine `json:"lines" bson:"lines"`
+	Subtotal    float64     `json:"subtotal" bson:"subtotal"`
+	Discounts   float64     `json:"discounts" bson:"discounts"`
+	Fees        float64     `json:"fees" bson:"fees"`
+	Taxes       float64     `json:"taxes" bson:"taxes"`
+	Total       float64     `json:"total" bson:"total"`
+}
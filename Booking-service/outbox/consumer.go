@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Consumer read settings
+const (
+	consumeBatch = 50
+	consumeBlock = 5 * time.Second
+	retryBackoff = time.Second
+)
+
+// Handler handles one event. Returning an error leaves the event pending so
+// it is delivered again.
+type Handler func(ctx context.Context, ev Event) error
+
+// Consumer reads a stream as one member of a consumer group. Every group
+// sees every event and the members of a group share them. An event stays
+// pending until its handler succeeds; events left pending longer than
+// MinIdle, e.g. by a member that died, are taken over by another member.
+// Delivery is therefore at least once and handlers must tolerate repeats,
+// for instance by remembering the IDs of events they handled. Taking over
+// events needs Redis 6.2 or later.
+type Consumer struct {
+	rdb    *redis.Client
+	stream string
+	group  string
+	name   string
+	// MinIdle is how long an event may stay unacknowledged before any
+	// member of the group may take it over
+	MinIdle time.Duration
+}
+
+// NewConsumer creates the member name of group reading stream
+func NewConsumer(rdb *redis.Client, stream, group, name string) *Consumer {
+	return &Consumer{
+		rdb:     rdb,
+		stream:  stream,
+		group:   group,
+		name:    name,
+		MinIdle: time.Minute,
+	}
+}
+
+// Run hands events to handle until ctx is cancelled. A new group starts at
+// the beginning of the stream.
+func (c *Consumer) Run(ctx context.Context, handle Handler) error {
+	err := c.rdb.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if err := c.poll(ctx, handle); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "outbox consumer read failed", "stream", c.stream, "group", c.group, "error", err.Error())
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryBackoff):
+			}
+		}
+	}
+	return nil
+}
+
+// poll takes over stale events, then waits for new ones
+func (c *Consumer) poll(ctx context.Context, handle Handler) error {
+	stale, err := c.autoClaim(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range stale {
+		c.deliver(ctx, msg, handle)
+	}
+
+	streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  []string{c.stream, ">"},
+		Count:    consumeBatch,
+		Block:    consumeBlock,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range streams {
+		for _, msg := range s.Messages {
+			c.deliver(ctx, msg, handle)
+		}
+	}
+	return nil
+}
+
+// autoClaim takes over up to consumeBatch events left pending longer than
+// MinIdle. go-redis only reads the two element XAUTOCLAIM reply of Redis
+// 6.2, while Redis 7 adds a third listing deleted entries, so the reply is
+// parsed here.
+func (c *Consumer) autoClaim(ctx context.Context) ([]redis.XMessage, error) {
+	reply, err := c.rdb.Do(ctx, "XAUTOCLAIM", c.stream, c.group, c.name,
+		c.MinIdle.Milliseconds(), "0-0", "COUNT", consumeBatch).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) < 2 {
+		return nil, fmt.Errorf("unexpected XAUTOCLAIM reply of %d elements", len(reply))
+	}
+	entries, ok := reply[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected XAUTOCLAIM entries %T", reply[1])
+	}
+
+	msgs := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+		id, _ := entry[0].(string)
+		// Redis 6.2 returns entries deleted from the stream without fields;
+		// they fail to decode and are dropped
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if k, ok := fields[i].(string); ok {
+				values[k] = fields[i+1]
+			}
+		}
+		msgs = append(msgs, redis.XMessage{ID: id, Values: values})
+	}
+	return msgs, nil
+}
+
+// deliver hands one message to handle and acknowledges it on success
+func (c *Consumer) deliver(ctx context.Context, msg redis.XMessage, handle Handler) {
+	ev, err := decodeMessage(msg)
+	if err != nil {
+		// It will never decode; drop it rather than retry forever
+		slog.ErrorContext(ctx, "dropping malformed outbox event", "stream", c.stream, "message_id", msg.ID, "error", err.Error())
+	} else if err := handle(ctx, ev); err != nil {
+		slog.WarnContext(ctx, "outbox event handler failed", "group", c.group, "event_id", ev.ID, "type", ev.Type, "error", err.Error())
+		return
+	}
+
+	if err := c.rdb.XAck(ctx, c.stream, c.group, msg.ID).Err(); err != nil {
+		slog.WarnContext(ctx, "acknowledging outbox event failed", "group", c.group, "message_id", msg.ID, "error", err.Error())
+	}
+}
+
+// decodeMessage turns a stream entry written by RedisStreams back into an event
+func decodeMessage(msg redis.XMessage) (Event, error) {
+	field := func(name string) string {
+		v, _ := msg.Values[name].(string)
+		return v
+	}
+
+	ev := Event{
+		ID:          field("id"),
+		Type:        field("type"),
+		AggregateID: field("aggregate_id"),
+		Payload:     field("payload"),
+	}
+	if ev.ID == "" || ev.Type == "" {
+		return Event{}, fmt.Errorf("entry %s is not an outbox event", msg.ID)
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, field("occurred_at"))
+	if err != nil {
+		return Event{}, err
+	}
+	ev.OccurredAt = occurredAt
+	return ev, nil
+}
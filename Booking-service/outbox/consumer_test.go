@@ -0,0 +1,192 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const testGroup = "notifications"
+
+// recorder is a handler that remembers what it saw and fails for some events
+type recorder struct {
+	seen []string
+	fail map[string]bool
+}
+
+func (r *recorder) handle(ctx context.Context, ev Event) error {
+	r.seen = append(r.seen, ev.ID)
+	if r.fail[ev.ID] {
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func publish(t *testing.T, rdb *redis.Client, ids ...string) {
+	t.Helper()
+	b := NewRedisStreams(rdb, testStream)
+	for _, id := range ids {
+		ev := Event{ID: id, Type: BookingConfirmed, AggregateID: "b-" + id, Payload: `{}`, OccurredAt: time.Now().UTC()}
+		if err := b.Publish(context.Background(), ev); err != nil {
+			t.Fatalf("publish %s: %v", id, err)
+		}
+	}
+}
+
+// pendingIDs lists the stream entries not yet acknowledged, by consumer
+func pendingIDs(t *testing.T, rdb *redis.Client) map[string]int {
+	t.Helper()
+	pending, err := rdb.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: testStream,
+		Group:  testGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err != nil {
+		t.Fatalf("XPENDING: %v", err)
+	}
+	byConsumer := map[string]int{}
+	for _, p := range pending {
+		byConsumer[p.Consumer]++
+	}
+	return byConsumer
+}
+
+func testConsumerGroup(t *testing.T, rdb *redis.Client) {
+	t.Helper()
+	if err := rdb.XGroupCreateMkStream(context.Background(), testStream, testGroup, "0").Err(); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+}
+
+func TestConsumerAcknowledgesHandledEvents(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := testRedis(t)
+	testConsumerGroup(t, rdb)
+	publish(t, rdb, "e1", "e2", "e3")
+
+	c := NewConsumer(rdb, testStream, testGroup, "a")
+	r := &recorder{fail: map[string]bool{"e2": true}}
+	if err := c.poll(ctx, r.handle); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	if want := []string{"e1", "e2", "e3"}; !reflect.DeepEqual(r.seen, want) {
+		t.Fatalf("handled %v, want %v", r.seen, want)
+	}
+	if got := pendingIDs(t, rdb); !reflect.DeepEqual(got, map[string]int{"a": 1}) {
+		t.Fatalf("pending %v, want only the failed event left with a", got)
+	}
+}
+
+func TestConsumerTakesOverStaleEvents(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := testRedis(t)
+	testConsumerGroup(t, rdb)
+	publish(t, rdb, "e1")
+
+	// a reads the event and dies before acknowledging it
+	crashed := NewConsumer(rdb, testStream, testGroup, "a")
+	if err := crashed.poll(ctx, (&recorder{fail: map[string]bool{"e1": true}}).handle); err != nil {
+		t.Fatalf("poll a: %v", err)
+	}
+
+	// New events keep b's read from blocking
+	b := NewConsumer(rdb, testStream, testGroup, "b")
+	r := &recorder{}
+	publish(t, rdb, "e2")
+	if err := b.poll(ctx, r.handle); err != nil {
+		t.Fatalf("poll b: %v", err)
+	}
+	if want := []string{"e2"}; !reflect.DeepEqual(r.seen, want) {
+		t.Fatalf("before MinIdle b handled %v, want %v", r.seen, want)
+	}
+
+	mr.SetTime(time.Now().Add(b.MinIdle + time.Second))
+	publish(t, rdb, "e3")
+	r.seen = nil
+	if err := b.poll(ctx, r.handle); err != nil {
+		t.Fatalf("poll b: %v", err)
+	}
+	if want := []string{"e1", "e3"}; !reflect.DeepEqual(r.seen, want) {
+		t.Fatalf("after MinIdle b handled %v, want %v", r.seen, want)
+	}
+	if got := pendingIDs(t, rdb); len(got) != 0 {
+		t.Fatalf("pending %v, want nothing", got)
+	}
+}
+
+func TestConsumerDropsMalformedEvents(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := testRedis(t)
+	testConsumerGroup(t, rdb)
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: testStream, Values: map[string]interface{}{"hello": "world"}}).Err(); err != nil {
+		t.Fatalf("XADD: %v", err)
+	}
+
+	c := NewConsumer(rdb, testStream, testGroup, "a")
+	r := &recorder{}
+	if err := c.poll(ctx, r.handle); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(r.seen) != 0 {
+		t.Fatalf("handler got %v", r.seen)
+	}
+	if got := pendingIDs(t, rdb); len(got) != 0 {
+		t.Fatalf("malformed entry still pending: %v", got)
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	occurred := time.Date(2026, 10, 1, 12, 0, 0, 123456789, time.UTC)
+	valid := map[string]interface{}{
+		"id":           "e1",
+		"type":         BookingCreated,
+		"aggregate_id": "b1",
+		"occurred_at":  occurred.Format(time.RFC3339Nano),
+		"payload":      `{"seats":["A1"]}`,
+	}
+	// edit copies valid with field set to value, or removed when value is nil
+	edit := func(field string, value interface{}) map[string]interface{} {
+		values := map[string]interface{}{}
+		for k, v := range valid {
+			values[k] = v
+		}
+		if value == nil {
+			delete(values, field)
+		} else {
+			values[field] = value
+		}
+		return values
+	}
+
+	ev, err := decodeMessage(redis.XMessage{ID: "1-0", Values: valid})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := Event{ID: "e1", Type: BookingCreated, AggregateID: "b1", Payload: `{"seats":["A1"]}`, OccurredAt: occurred}
+	if !reflect.DeepEqual(ev, want) {
+		t.Fatalf("decoded %+v, want %+v", ev, want)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{"no id", edit("id", nil)},
+		{"empty id", edit("id", "")},
+		{"no type", edit("type", nil)},
+		{"no occurred_at", edit("occurred_at", nil)},
+		{"bad occurred_at", edit("occurred_at", "yesterday")},
+	}
+	for _, tt := range tests {
+		if _, err := decodeMessage(redis.XMessage{ID: "1-0", Values: tt.values}); err == nil {
+			t.Errorf("%s: decoded without error", tt.name)
+		}
+	}
+}
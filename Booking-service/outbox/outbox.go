@@ -0,0 +1,209 @@
+// Package outbox publishes booking domain events reliably.
+//
+// A booking change and the event announcing it are written to Mongo in one
+// transaction, the event into the outbox collection. A Relay on one replica
+// at a time publishes unpublished events through a Broker, oldest first, and
+// marks them published, so every committed change is published at least
+// once even when a replica dies between the two steps.
+//
+// The default broker appends events to the Redis stream Stream. Consumers
+// such as notifications or analytics each read it with their own consumer
+// group through a Consumer and acknowledge what they handled.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Booking event types
+const (
+	BookingCreated   = "booking.created"
+	BookingConfirmed = "booking.confirmed"
+	BookingCancelled = "booking.cancelled"
+	BookingExpired   = "booking.expired"
+)
+
+// retention is how long published events stay in the outbox collection
+const retention = 7 * 24 * time.Hour
+
+// ErrNotTransactional is returned by Write on a deployment without
+// transactions unless non-atomic writes were allowed
+var ErrNotTransactional = errors.New("outbox writes need MongoDB transactions (a replica set or sharded cluster)")
+
+// Event is a domain event waiting in, or published from, the outbox
+type Event struct {
+	ID          string `json:"id" bson:"_id"`
+	Type        string `json:"type" bson:"type"`
+	AggregateID string `json:"aggregate_id" bson:"aggregate_id"`
+	// Payload is the JSON encoded state the event describes
+	Payload     string     `json:"payload" bson:"payload"`
+	OccurredAt  time.Time  `json:"occurred_at" bson:"occurred_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty" bson:"published_at,omitempty"`
+	Attempts    int        `json:"attempts,omitempty" bson:"attempts,omitempty"`
+	LastError   string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// NewEvent creates an event about the aggregate with the given ID, e.g. a
+// booking, carrying payload as JSON
+func NewEvent(eventType, aggregateID string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     string(data),
+		OccurredAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Decode unmarshals the event's payload into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(e.Payload), v)
+}
+
+// Store writes events to the outbox collection together with the changes
+// they announce
+type Store struct {
+	client       *mongo.Client
+	coll         *mongo.Collection
+	transactions bool
+	nonAtomic    bool
+}
+
+// NewStore creates an outbox in db's outbox collection. Call Init before
+// writing to it.
+func NewStore(db *mongo.Database) *Store {
+	return &Store{
+		client: db.Client(),
+		coll:   db.Collection("outbox"),
+	}
+}
+
+// Init creates the outbox indexes and checks whether the deployment
+// supports transactions. Standalone servers do not; there Write fails
+// unless AllowNonAtomic was called.
+func (s *Store) Init(ctx context.Context) error {
+	_, err := s.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "occurred_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "published_at", Value: 1}},
+			Options: options.Index().SetName("published_at_ttl").SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var hello bson.M
+	if err := s.client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello); err != nil {
+		return err
+	}
+	_, replicaSet := hello["setName"]
+	s.transactions = replicaSet || hello["msg"] == "isdbgrid"
+	return nil
+}
+
+// Transactional reports whether Write is atomic on this deployment
+func (s *Store) Transactional() bool {
+	return s.transactions
+}
+
+// AllowNonAtomic lets Write run without transactions, e.g. on a standalone
+// development server. Events are then stored right after the change, and a
+// crash or error in between loses them.
+func (s *Store) AllowNonAtomic() {
+	s.nonAtomic = true
+}
+
+// Write runs fn and stores events in the same transaction. fn must do its
+// writes with the context it is given and may run more than once when the
+// transaction is retried. Nothing is stored when fn fails.
+func (s *Store) Write(ctx context.Context, fn func(ctx context.Context) error, events ...Event) error {
+	if !s.transactions {
+		if !s.nonAtomic {
+			return ErrNotTransactional
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		// The change is committed either way, so its caller must not undo it
+		if err := s.insert(ctx, events); err != nil {
+			slog.ErrorContext(ctx, "writing outbox events failed", "events", len(events), "error", err.Error())
+		}
+		return nil
+	}
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := fn(sc); err != nil {
+			return nil, err
+		}
+		return nil, s.insert(sc, events)
+	})
+	return err
+}
+
+func (s *Store) insert(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+	_, err := s.coll.InsertMany(ctx, docs)
+	return err
+}
+
+// pending returns up to limit unpublished events, oldest first
+func (s *Store) pending(ctx context.Context, limit int64) ([]Event, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	cursor, err := s.coll.Find(ctx, bson.M{"published_at": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []Event
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// markPublished records that an event reached the broker
+func (s *Store) markPublished(ctx context.Context, id string) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"published_at": time.Now().UTC()},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+// markFailed records a failed publish attempt
+func (s *Store) markFailed(ctx context.Context, id string, cause error) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"last_error": cause.Error()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ansh0014/booking/leader"
+	"github.com/ansh0014/booking/metrics"
+	"github.com/go-redis/redis/v8"
+)
+
+// Stream is the Redis stream booking events are published to
+const Stream = "booking:events"
+
+// streamMaxLen roughly bounds the stream; consumer groups that fall further
+// behind than this miss events
+const streamMaxLen = 100000
+
+// relayBatch bounds how many events one query loads
+const relayBatch = 100
+
+// Broker delivers published events to their consumers
+type Broker interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+// RedisStreams publishes events to a Redis stream, one entry per event
+type RedisStreams struct {
+	rdb    *redis.Client
+	stream string
+}
+
+// NewRedisStreams creates a broker appending to stream
+func NewRedisStreams(rdb *redis.Client, stream string) *RedisStreams {
+	return &RedisStreams{rdb: rdb, stream: stream}
+}
+
+// Publish implements Broker
+func (b *RedisStreams) Publish(ctx context.Context, ev Event) error {
+	return b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":           ev.ID,
+			"type":         ev.Type,
+			"aggregate_id": ev.AggregateID,
+			"occurred_at":  ev.OccurredAt.Format(time.RFC3339Nano),
+			"payload":      ev.Payload,
+		},
+	}).Err()
+}
+
+// Relay moves events from the outbox to a broker. Only the replica holding
+// the lease relays, so events leave in the order they were written.
+type Relay struct {
+	store   *Store
+	broker  Broker
+	lease   *leader.Lease
+	leading bool
+}
+
+// NewRelay creates a relay from store to broker
+func NewRelay(store *Store, broker Broker, lease *leader.Lease) *Relay {
+	return &Relay{
+		store:  store,
+		broker: broker,
+		lease:  lease,
+	}
+}
+
+// Run relays pending events every interval while this replica leads, until
+// ctx is cancelled
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Hand leadership over straight away on shutdown
+	defer func() {
+		if !r.leading {
+			return
+		}
+		resignCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := r.lease.Resign(resignCtx); err != nil {
+			slog.Warn("resigning outbox leadership failed", "error", err.Error())
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.lead(ctx) {
+				continue
+			}
+			if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "outbox relay failed", "error", err.Error())
+			}
+		}
+	}
+}
+
+// lead takes or renews the leader lease and logs leadership changes
+func (r *Relay) lead(ctx context.Context) bool {
+	ok, err := r.lease.Acquire(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "outbox leader election failed", "error", err.Error())
+		ok = false
+	}
+	if ok != r.leading {
+		slog.InfoContext(ctx, "outbox leadership changed", "leader", ok, "instance", r.lease.ID())
+		r.leading = ok
+	}
+	return ok
+}
+
+// Flush publishes pending events oldest first until none are left and
+// returns how many it published. It stops at the first event the broker
+// rejects so later events never overtake it.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		events, err := r.store.pending(ctx, relayBatch)
+		if err != nil {
+			return published, err
+		}
+		for _, ev := range events {
+			if err := r.broker.Publish(ctx, ev); err != nil {
+				metrics.OutboxPublishErrors.Inc()
+				if merr := r.store.markFailed(ctx, ev.ID, err); merr != nil {
+					slog.WarnContext(ctx, "recording outbox failure failed", "event_id", ev.ID, "error", merr.Error())
+				}
+				return published, err
+			}
+			// A crash before this update publishes the event again
+			if err := r.store.markPublished(ctx, ev.ID); err != nil {
+				return published, err
+			}
+			metrics.OutboxPublished.WithLabelValues(ev.Type).Inc()
+			published++
+		}
+		if len(events) < relayBatch {
+			return published, nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+const testStream = "booking-events"
+
+func testRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+// failOn publishes to a Redis stream but rejects the event with the given ID
+type failOn struct {
+	*RedisStreams
+	id string
+}
+
+func (b failOn) Publish(ctx context.Context, ev Event) error {
+	if ev.ID == b.id {
+		return errors.New("broker unavailable")
+	}
+	return b.RedisStreams.Publish(ctx, ev)
+}
+
+// pendingEvents answers the relay's find command with events
+func pendingEvents(mt *mtest.T, events ...Event) bson.D {
+	docs := make([]bson.D, len(events))
+	for i, ev := range events {
+		raw, err := bson.Marshal(ev)
+		if err != nil {
+			mt.Fatalf("marshal event: %v", err)
+		}
+		if err := bson.Unmarshal(raw, &docs[i]); err != nil {
+			mt.Fatalf("unmarshal event: %v", err)
+		}
+	}
+	return mtest.CreateCursorResponse(0, "booking.outbox", mtest.FirstBatch, docs...)
+}
+
+// nextUpdate returns the update document of the next update command sent
+func nextUpdate(mt *mtest.T) (id string, update bson.Raw) {
+	ev := mt.GetStartedEvent()
+	if ev == nil || ev.CommandName != "update" {
+		mt.Fatalf("expected an update command, got %+v", ev)
+	}
+	stmt := ev.Command.Lookup("updates").Array().Index(0).Value().Document()
+	return stmt.Lookup("q").Document().Lookup("_id").StringValue(), stmt.Lookup("u").Document()
+}
+
+func TestFlush(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+	occurred := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{ID: "e1", Type: BookingConfirmed, AggregateID: "b1", Payload: `{}`, OccurredAt: occurred},
+		{ID: "e2", Type: BookingCancelled, AggregateID: "b2", Payload: `{}`, OccurredAt: occurred.Add(time.Second)},
+		{ID: "e3", Type: BookingConfirmed, AggregateID: "b3", Payload: `{}`, OccurredAt: occurred.Add(2 * time.Second)},
+	}
+
+	mt.Run("publishes in order and stops at the first broker error", func(mt *mtest.T) {
+		_, rdb := testRedis(t)
+		relay := NewRelay(NewStore(mt.DB), failOn{NewRedisStreams(rdb, testStream), "e2"}, nil)
+		mt.AddMockResponses(pendingEvents(mt, events...), updated(1), updated(1))
+
+		n, err := relay.Flush(ctx)
+		if err == nil {
+			mt.Fatal("Flush succeeded although the broker rejected e2")
+		}
+		if n != 1 {
+			mt.Fatalf("published %d events, want 1", n)
+		}
+
+		entries, err := rdb.XRange(ctx, testStream, "-", "+").Result()
+		if err != nil {
+			mt.Fatalf("XRange: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Values["id"] != "e1" {
+			mt.Fatalf("stream holds %+v, want only e1", entries)
+		}
+
+		if ev := mt.GetStartedEvent(); ev == nil || ev.CommandName != "find" {
+			mt.Fatalf("expected the pending events to be read first, got %+v", ev)
+		}
+		if id, _ := nextUpdate(mt); id != "e1" {
+			mt.Fatalf("first update is for %s, want e1", id)
+		}
+		if id, _ := nextUpdate(mt); id != "e2" {
+			mt.Fatalf("second update is for %s, want e2", id)
+		}
+		if ev := mt.GetStartedEvent(); ev != nil {
+			mt.Fatalf("e3 was touched after e2 failed: %s", ev.CommandName)
+		}
+	})
+
+	mt.Run("publishes everything", func(mt *mtest.T) {
+		_, rdb := testRedis(t)
+		relay := NewRelay(NewStore(mt.DB), NewRedisStreams(rdb, testStream), nil)
+		mt.AddMockResponses(pendingEvents(mt, events...), updated(1), updated(1), updated(1))
+
+		n, err := relay.Flush(ctx)
+		if err != nil {
+			mt.Fatalf("Flush: %v", err)
+		}
+		if n != len(events) {
+			mt.Fatalf("published %d events, want %d", n, len(events))
+		}
+		entries := rdb.XRange(ctx, testStream, "-", "+").Val()
+		if len(entries) != len(events) {
+			mt.Fatalf("stream holds %d entries, want %d", len(entries), len(events))
+		}
+		for i, entry := range entries {
+			if entry.Values["id"] != events[i].ID {
+				mt.Fatalf("entry %d is %v, want %s", i, entry.Values["id"], events[i].ID)
+			}
+		}
+	})
+}
+
+func TestPublishState(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+
+	mt.Run("markPublished", func(mt *mtest.T) {
+		s := NewStore(mt.DB)
+		mt.AddMockResponses(updated(1))
+		if err := s.markPublished(ctx, "e1"); err != nil {
+			mt.Fatalf("markPublished: %v", err)
+		}
+
+		id, u := nextUpdate(mt)
+		if id != "e1" {
+			mt.Fatalf("updated %s, want e1", id)
+		}
+		if _, err := u.LookupErr("$set", "published_at"); err != nil {
+			mt.Fatalf("published_at not set: %v", u)
+		}
+		if _, err := u.LookupErr("$unset", "last_error"); err != nil {
+			mt.Fatalf("last_error not cleared: %v", u)
+		}
+	})
+
+	mt.Run("markFailed", func(mt *mtest.T) {
+		s := NewStore(mt.DB)
+		mt.AddMockResponses(updated(1))
+		if err := s.markFailed(ctx, "e2", errors.New("broker unavailable")); err != nil {
+			mt.Fatalf("markFailed: %v", err)
+		}
+
+		id, u := nextUpdate(mt)
+		if id != "e2" {
+			mt.Fatalf("updated %s, want e2", id)
+		}
+		if msg := u.Lookup("$set", "last_error").StringValue(); msg != "broker unavailable" {
+			mt.Fatalf("last_error %q", msg)
+		}
+		if n := u.Lookup("$inc", "attempts").AsInt64(); n != 1 {
+			mt.Fatalf("attempts incremented by %d, want 1", n)
+		}
+		if _, err := u.LookupErr("$set", "published_at"); err == nil {
+			mt.Fatalf("failed event marked published: %v", u)
+		}
+	})
+}
+
+// updated answers an update command as matching n documents
+func updated(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
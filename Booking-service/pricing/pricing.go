@@ -0,0 +1,222 @@
+// Package pricing turns the seat prices a platform quotes into the price a
+// customer pays.
+//
+// Seat prices always come from the platform. On top of them each platform
+// has Rules: discounts come off the seat subtotal first, fees are charged
+// on what is left, and taxes are charged on the discounted seats and fees,
+// or on the fees alone. Every step is a separate line item so the UI and
+// the stored booking show the same breakdown.
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/model"
+)
+
+// ErrUnknownPromo is returned for a promo code no discount accepts
+var ErrUnknownPromo = errors.New("unknown promo code")
+
+// Fee is charged per seat, per order and/or as a share of the seat subtotal
+type Fee struct {
+	Code     string  `json:"code"`
+	Label    string  `json:"label"`
+	PerSeat  float64 `json:"per_seat"`
+	PerOrder float64 `json:"per_order"`
+	Percent  float64 `json:"percent"`
+}
+
+// Tax is a percentage of the discounted seats and fees, or of the fees only
+type Tax struct {
+	Code    string  `json:"code"`
+	Label   string  `json:"label"`
+	Percent float64 `json:"percent"`
+	OnFees  bool    `json:"on_fees"`
+}
+
+// Discount takes a percentage and/or a fixed amount off the seat subtotal.
+// Discounts with a promo code only apply when the customer enters it; the
+// others apply to every order with at least MinSeats seats.
+type Discount struct {
+	Code      string  `json:"code"`
+	Label     string  `json:"label"`
+	PromoCode string  `json:"promo_code,omitempty"`
+	MinSeats  int     `json:"min_seats"`
+	Percent   float64 `json:"percent"`
+	Amount    float64 `json:"amount"`
+}
+
+// Rules are the fees, taxes and discounts of one platform
+type Rules struct {
+	Fees      []Fee      `json:"fees"`
+	Taxes     []Tax      `json:"taxes"`
+	Discounts []Discount `json:"discounts"`
+}
+
+// DefaultRules are used for platforms the rules file does not cover
+func DefaultRules() map[string]Rules {
+	return map[string]Rules{
+		"movie": {
+			Fees:  []Fee{{Code: "convenience", Label: "Convenience fee", PerSeat: 30}},
+			Taxes: []Tax{{Code: "gst", Label: "GST", Percent: 18}},
+		},
+		"flight": {
+			Fees:  []Fee{{Code: "convenience", Label: "Convenience fee", PerSeat: 350}},
+			Taxes: []Tax{{Code: "gst", Label: "GST", Percent: 5}},
+		},
+		"railway": {
+			Fees:  []Fee{{Code: "convenience", Label: "Convenience fee", PerOrder: 20}},
+			Taxes: []Tax{{Code: "gst", Label: "GST on fees", Percent: 18, OnFees: true}},
+		},
+		"event": {
+			Fees:      []Fee{{Code: "booking", Label: "Booking fee", Percent: 5}},
+			Taxes:     []Tax{{Code: "gst", Label: "GST on fees", Percent: 18, OnFees: true}},
+			Discounts: []Discount{{Code: "group", Label: "Group discount", MinSeats: 10, Percent: 10}},
+		},
+	}
+}
+
+// LoadRules reads per-platform rules from a JSON file and fills in the
+// defaults for platforms it leaves out
+func LoadRules(path string) (map[string]Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string]Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse pricing rules %s: %w", path, err)
+	}
+	for name, r := range DefaultRules() {
+		if _, ok := rules[name]; !ok {
+			rules[name] = r
+		}
+	}
+	return rules, nil
+}
+
+// Request names the seats to price
+type Request struct {
+	Platform    string
+	InventoryID string
+	SeatIDs     []string
+	PromoCode   string
+}
+
+// Engine prices seats through the platform registry
+type Engine struct {
+	platforms *platform.Registry
+	rules     map[string]Rules
+}
+
+// NewEngine creates a pricing engine with per-platform rules
+func NewEngine(platforms *platform.Registry, rules map[string]Rules) *Engine {
+	return &Engine{
+		platforms: platforms,
+		rules:     rules,
+	}
+}
+
+// Quote prices the requested seats at the platform's current prices
+func (e *Engine) Quote(ctx context.Context, req Request) (*model.Price, error) {
+	p, err := e.platforms.Get(req.Platform)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := p.Quote(ctx, req.InventoryID, req.SeatIDs)
+	if err != nil {
+		return nil, err
+	}
+	return e.Price(quote, req.PromoCode)
+}
+
+// Price applies the quoting platform's rules to a quote already taken
+func (e *Engine) Price(quote *platform.Quote, promoCode string) (*model.Price, error) {
+	return Apply(quote, e.rules[quote.Platform], promoCode)
+}
+
+// Apply prices a platform quote under rules
+func Apply(quote *platform.Quote, rules Rules, promoCode string) (*model.Price, error) {
+	price := &model.Price{
+		Platform:    quote.Platform,
+		InventoryID: quote.InventoryID,
+		Currency:    quote.Currency,
+	}
+
+	for _, line := range quote.Lines {
+		label := line.Label
+		if line.Class != "" {
+			label += " (" + line.Class + ")"
+		}
+		amount := round(line.Price)
+		price.Lines = append(price.Lines, model.PriceLine{Kind: model.PriceSeat, Code: line.SeatID, Label: label, Amount: amount})
+		price.Subtotal += amount
+	}
+	price.Subtotal = round(price.Subtotal)
+	seats := len(quote.Lines)
+
+	// Discounts never take the seats below zero
+	promoUsed := promoCode == ""
+	for _, d := range rules.Discounts {
+		if d.PromoCode != "" {
+			if !strings.EqualFold(d.PromoCode, promoCode) {
+				continue
+			}
+			promoUsed = true
+		}
+		if seats < d.MinSeats {
+			continue
+		}
+		amount := round(price.Subtotal*d.Percent/100 + d.Amount)
+		amount = math.Min(amount, price.Subtotal+price.Discounts)
+		if amount <= 0 {
+			continue
+		}
+		price.Lines = append(price.Lines, model.PriceLine{Kind: model.PriceDiscount, Code: d.Code, Label: d.Label, Amount: -amount})
+		price.Discounts -= amount
+	}
+	if !promoUsed {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPromo, promoCode)
+	}
+	discounted := price.Subtotal + price.Discounts
+
+	for _, f := range rules.Fees {
+		amount := round(f.PerSeat*float64(seats) + f.PerOrder + discounted*f.Percent/100)
+		if amount == 0 {
+			continue
+		}
+		price.Lines = append(price.Lines, model.PriceLine{Kind: model.PriceFee, Code: f.Code, Label: f.Label, Amount: amount})
+		price.Fees += amount
+	}
+
+	for _, t := range rules.Taxes {
+		base := discounted + price.Fees
+		if t.OnFees {
+			base = price.Fees
+		}
+		amount := round(base * t.Percent / 100)
+		if amount == 0 {
+			continue
+		}
+		price.Lines = append(price.Lines, model.PriceLine{Kind: model.PriceTax, Code: t.Code, Label: t.Label, Amount: amount})
+		price.Taxes += amount
+	}
+
+	price.Discounts = round(price.Discounts)
+	price.Fees = round(price.Fees)
+	price.Taxes = round(price.Taxes)
+	price.Total = round(discounted + price.Fees + price.Taxes)
+	return price, nil
+}
+
+// round rounds an amount to the smallest currency unit
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,211 @@
+package pricing
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/model"
+)
+
+// quote builds a quote for seats A1, A2, ... at the given prices
+func quote(name string, prices ...float64) *platform.Quote {
+	q := &platform.Quote{Platform: name, InventoryID: "inv-1", Currency: "INR"}
+	for i, p := range prices {
+		id := strconv.Itoa(i + 1)
+		q.Lines = append(q.Lines, platform.QuoteLine{SeatID: "s" + id, Label: "A" + id, Price: p})
+	}
+	return q
+}
+
+func seat(id string, amount float64) model.PriceLine {
+	return model.PriceLine{Kind: model.PriceSeat, Code: "s" + id, Label: "A" + id, Amount: amount}
+}
+
+func TestApply(t *testing.T) {
+	defaults := DefaultRules()
+	voucher := Rules{
+		Discounts: []Discount{
+			{Code: "voucher", Label: "Voucher", PromoCode: "FREE", Amount: 500},
+			{Code: "group", Label: "Group discount", Percent: 10},
+		},
+		Fees:  []Fee{{Code: "service", Label: "Service fee", PerOrder: 20}},
+		Taxes: []Tax{{Code: "gst", Label: "GST", Percent: 18}},
+	}
+
+	tests := []struct {
+		name  string
+		quote *platform.Quote
+		rules Rules
+		promo string
+		lines []model.PriceLine
+		// subtotal, discounts, fees, taxes, total
+		sums [5]float64
+	}{
+		{
+			name:  "movie: per seat fee, tax on seats and fees",
+			quote: quote("movie", 250, 250),
+			rules: defaults["movie"],
+			lines: []model.PriceLine{
+				seat("1", 250),
+				seat("2", 250),
+				{Kind: model.PriceFee, Code: "convenience", Label: "Convenience fee", Amount: 60},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST", Amount: 100.8},
+			},
+			sums: [5]float64{500, 0, 60, 100.8, 660.8},
+		},
+		{
+			name:  "flight",
+			quote: quote("flight", 4999),
+			rules: defaults["flight"],
+			lines: []model.PriceLine{
+				seat("1", 4999),
+				{Kind: model.PriceFee, Code: "convenience", Label: "Convenience fee", Amount: 350},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST", Amount: 267.45},
+			},
+			sums: [5]float64{4999, 0, 350, 267.45, 5616.45},
+		},
+		{
+			name:  "railway: per order fee, tax on fees only",
+			quote: quote("railway", 1234.5),
+			rules: defaults["railway"],
+			lines: []model.PriceLine{
+				seat("1", 1234.5),
+				{Kind: model.PriceFee, Code: "convenience", Label: "Convenience fee", Amount: 20},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST on fees", Amount: 3.6},
+			},
+			sums: [5]float64{1234.5, 0, 20, 3.6, 1258.1},
+		},
+		{
+			name:  "event below the group size",
+			quote: quote("event", 1500, 1500),
+			rules: defaults["event"],
+			lines: []model.PriceLine{
+				seat("1", 1500),
+				seat("2", 1500),
+				{Kind: model.PriceFee, Code: "booking", Label: "Booking fee", Amount: 150},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST on fees", Amount: 27},
+			},
+			sums: [5]float64{3000, 0, 150, 27, 3177},
+		},
+		{
+			name:  "event group discount",
+			quote: quote("event", 100, 100, 100, 100, 100, 100, 100, 100, 100, 100),
+			rules: defaults["event"],
+			lines: []model.PriceLine{
+				seat("1", 100), seat("2", 100), seat("3", 100), seat("4", 100), seat("5", 100),
+				seat("6", 100), seat("7", 100), seat("8", 100), seat("9", 100), seat("10", 100),
+				{Kind: model.PriceDiscount, Code: "group", Label: "Group discount", Amount: -100},
+				// the booking fee is charged on the discounted seats
+				{Kind: model.PriceFee, Code: "booking", Label: "Booking fee", Amount: 45},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST on fees", Amount: 8.1},
+			},
+			sums: [5]float64{1000, -100, 45, 8.1, 953.1},
+		},
+		{
+			name:  "rounds every line to the cent",
+			quote: quote("movie", 33.333, 33.333, 33.333),
+			rules: Rules{
+				Fees:  []Fee{{Code: "service", Label: "Service fee", Percent: 2.5}},
+				Taxes: []Tax{{Code: "gst", Label: "GST", Percent: 18}},
+			},
+			lines: []model.PriceLine{
+				seat("1", 33.33),
+				seat("2", 33.33),
+				seat("3", 33.33),
+				// 2.5% of 99.99 is 2.49975
+				{Kind: model.PriceFee, Code: "service", Label: "Service fee", Amount: 2.5},
+				// 18% of 102.49 is 18.4482
+				{Kind: model.PriceTax, Code: "gst", Label: "GST", Amount: 18.45},
+			},
+			sums: [5]float64{99.99, 0, 2.5, 18.45, 120.94},
+		},
+		{
+			name:  "discount larger than the subtotal stops at zero",
+			quote: quote("movie", 100, 100),
+			rules: voucher,
+			promo: "free",
+			lines: []model.PriceLine{
+				seat("1", 100),
+				seat("2", 100),
+				{Kind: model.PriceDiscount, Code: "voucher", Label: "Voucher", Amount: -200},
+				// nothing is left for the group discount, so it has no line
+				{Kind: model.PriceFee, Code: "service", Label: "Service fee", Amount: 20},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST", Amount: 3.6},
+			},
+			sums: [5]float64{200, -200, 20, 3.6, 23.6},
+		},
+		{
+			name:  "discounts come off before fees and taxes",
+			quote: quote("movie", 100, 100),
+			rules: voucher,
+			lines: []model.PriceLine{
+				seat("1", 100),
+				seat("2", 100),
+				{Kind: model.PriceDiscount, Code: "group", Label: "Group discount", Amount: -20},
+				{Kind: model.PriceFee, Code: "service", Label: "Service fee", Amount: 20},
+				{Kind: model.PriceTax, Code: "gst", Label: "GST", Amount: 36},
+			},
+			sums: [5]float64{200, -20, 20, 36, 236},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := Apply(tt.quote, tt.rules, tt.promo)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if !reflect.DeepEqual(price.Lines, tt.lines) {
+				t.Fatalf("lines\n got %+v\nwant %+v", price.Lines, tt.lines)
+			}
+			got := [5]float64{price.Subtotal, price.Discounts, price.Fees, price.Taxes, price.Total}
+			if got != tt.sums {
+				t.Fatalf("subtotal, discounts, fees, taxes, total = %v, want %v", got, tt.sums)
+			}
+			if price.Platform != tt.quote.Platform || price.InventoryID != "inv-1" || price.Currency != "INR" {
+				t.Fatalf("price for %s/%s in %s", price.Platform, price.InventoryID, price.Currency)
+			}
+		})
+	}
+}
+
+func TestApplySeatClassLabel(t *testing.T) {
+	q := quote("movie", 250)
+	q.Lines[0].Class = "Gold"
+	price, err := Apply(q, Rules{}, "")
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if label := price.Lines[0].Label; label != "A1 (Gold)" {
+		t.Fatalf("seat label %q, want %q", label, "A1 (Gold)")
+	}
+}
+
+func TestApplyUnknownPromo(t *testing.T) {
+	_, err := Apply(quote("event", 100), DefaultRules()["event"], "NOPE")
+	if !errors.Is(err, ErrUnknownPromo) {
+		t.Fatalf("got %v, want ErrUnknownPromo", err)
+	}
+}
+
+func TestDefaultRulesCoverPlatforms(t *testing.T) {
+	rules := DefaultRules()
+	for _, name := range []string{"movie", "flight", "railway", "event"} {
+		r, ok := rules[name]
+		if !ok {
+			t.Errorf("no default rules for %s", name)
+			continue
+		}
+		if len(r.Fees) == 0 || len(r.Taxes) == 0 {
+			t.Errorf("%s defaults have %d fees and %d taxes", name, len(r.Fees), len(r.Taxes))
+		}
+	}
+	// callers may change the rules they get without affecting later calls
+	rules["movie"].Fees[0].PerSeat = 0
+	if DefaultRules()["movie"].Fees[0].PerSeat == 0 {
+		t.Fatal("DefaultRules shares state between calls")
+	}
+}
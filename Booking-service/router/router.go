@@ -3,21 +3,30 @@ package router
 import (
 	"net/http"
 
+	"github.com/ansh0014/booking/config"
 	"github.com/ansh0014/booking/handler"
+	"github.com/ansh0014/booking/health"
+	"github.com/ansh0014/booking/idempotency"
+	"github.com/ansh0014/booking/metrics"
 	"github.com/ansh0014/booking/middleware"
+	"github.com/ansh0014/booking/stream"
+	"github.com/ansh0014/booking/tracing"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 // SetupRoutes configures all routes for the booking service
-func SetupRoutes(platformServices map[string]interface{}) http.Handler {
+func SetupRoutes(services handler.Services, hub *stream.Hub, internalToken string) http.Handler {
+	handler.Init(services)
+
 	r := mux.NewRouter()
 
 	// Apply global middlewares
-	r.Use(middleware.RecoverMiddleware)
+	r.Use(otelmux.Middleware(tracing.ServiceName))
+	r.Use(middleware.RequestIDMiddleware)
 	r.Use(middleware.LoggingMiddleware)
-	r.Use(middleware.CORSMiddleware)
+	r.Use(middleware.RecoverMiddleware)
 	r.Use(middleware.AuthMiddleware)
-	r.Use(middleware.ServiceInjector(platformServices))
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +34,16 @@ func SetupRoutes(platformServices map[string]interface{}) http.Handler {
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	// Liveness and dependency-aware readiness
+	checker := health.New(tracing.ServiceName).
+		Add("mongo", health.Mongo(config.MongoClient)).
+		Add("redis", health.Redis(config.RedisClient))
+	r.HandleFunc("/health/live", checker.Live).Methods("GET")
+	r.HandleFunc("/health/ready", checker.Ready).Methods("GET")
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// Flight routes
 	r.HandleFunc("/api/platforms/flight/search", handler.SearchFlightsHandler).Methods("POST")
 	r.HandleFunc("/api/platforms/flight/{id}", handler.GetFlightDetailsHandler).Methods("GET")
@@ -48,19 +67,42 @@ func SetupRoutes(platformServices map[string]interface{}) http.Handler {
 	// Movie routes
 	r.HandleFunc("/api/platforms/movie", handler.GetMoviesHandler).Methods("GET")
 	r.HandleFunc("/api/platforms/movie/search", handler.SearchMoviesHandler).Methods("POST")
+	r.HandleFunc("/api/platforms/movie/shows/{id}", handler.GetShowDetailsHandler).Methods("GET")
+	r.HandleFunc("/api/platforms/movie/shows/{id}/locks", handler.GetShowLocksHandler).Methods("GET")
+	r.HandleFunc("/api/platforms/movie/theaters/{id}", handler.GetTheaterDetailsHandler).Methods("GET")
 	r.HandleFunc("/api/platforms/movie/{id}", handler.GetMovieDetailsHandler).Methods("GET")
 	r.HandleFunc("/api/platforms/movie/{id}/shows", handler.GetMovieShowsHandler).Methods("GET")
 	r.HandleFunc("/api/platforms/movie/seats", handler.GetMovieSeatsHandler).Methods("GET")
 	r.HandleFunc("/api/platforms/movie/seats/lock", handler.LockMovieSeatsHandler).Methods("POST")
 
+	// Live seat availability (Server-Sent Events)
+	r.HandleFunc("/api/platforms/{platform}/{id}/seats/stream", hub.SeatsHandler).Methods("GET")
+
 	// Generic seat locking (works for all platforms)
 	r.HandleFunc("/api/seats/lock", handler.LockSeatsHandler).Methods("POST")
+	r.HandleFunc("/api/seats/release", handler.ReleaseSeatsHandler).Methods("POST")
+	r.HandleFunc("/api/seats/availability", handler.GetAvailabilityHandler).Methods("POST")
+
+	// Seat holds returned by the lock endpoints
+	r.HandleFunc("/api/holds/{id}", handler.GetHoldHandler).Methods("GET")
+	r.HandleFunc("/api/holds/{id}/extend", handler.ExtendHoldHandler).Methods("POST")
+	r.HandleFunc("/api/holds/{id}/release", handler.ReleaseHoldHandler).Methods("POST")
+
+	// Price seats before booking them
+	r.HandleFunc("/api/quotes", handler.QuoteHandler).Methods("POST")
 
 	// Booking routes (works for all platforms)
-	r.HandleFunc("/api/bookings", handler.CreateBookingHandler).Methods("POST")
+	// retried creates replay the first response instead of booking twice
+	idem := idempotency.NewGuard(config.RedisClient, idempotency.DefaultTTL)
+	r.Handle("/api/bookings", idem.Middleware(http.HandlerFunc(handler.CreateBookingHandler))).Methods("POST")
 	r.HandleFunc("/api/bookings/{id}", handler.GetBookingHandler).Methods("GET")
 	r.HandleFunc("/api/bookings/{id}/cancel", handler.CancelBookingHandler).Methods("POST")
 	r.HandleFunc("/api/users/me/bookings", handler.GetUserBookingsHandler).Methods("GET")
 
+	// Service-to-service callbacks, authenticated with the shared token
+	internal := r.PathPrefix("/internal").Subrouter()
+	internal.Use(middleware.InternalAuth(internalToken))
+	internal.HandleFunc("/payments/events", handler.PaymentEventHandler).Methods("POST")
+
 	return r
 }
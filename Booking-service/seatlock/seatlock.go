@@ -0,0 +1,228 @@
+// Package seatlock holds and frees groups of seat locks atomically.
+//
+// A seat lock is a Redis string key whose value names the owning user and
+// the hold that took it (see Value). Acquire runs as a single Lua script,
+// so a group of seats is either locked entirely for one owner or not at
+// all, and two users racing for the same seat can never both succeed.
+// Release only deletes keys the caller owns; ReleaseValue, Extend and
+// TakeValue only touch keys still holding one exact value, i.e. one hold.
+package seatlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrConflict is returned when another owner holds one of the seats
+var ErrConflict = errors.New("seat is locked by another user")
+
+// ErrNotHeld is returned by TakeValue when a seat no longer holds the
+// expected value, e.g. because its lock expired and someone else took it
+var ErrNotHeld = errors.New("seat is no longer held by this lock")
+
+// sep separates the owner from the token in a lock value
+const sep = "|"
+
+// Value is what a lock key stores: the owner and the token (usually a hold
+// ID) of the lock that took the seat.
+func Value(owner, token string) string {
+	return owner + sep + token
+}
+
+// Split returns the owner and token stored in a lock value. Values written
+// without a token are all owner.
+func Split(value string) (owner, token string) {
+	i := strings.LastIndex(value, sep)
+	if i < 0 {
+		return value, ""
+	}
+	return value[:i], value[i+1:]
+}
+
+// ownerOf is the Lua counterpart of Split.
+const ownerOf = `
+local function owner(v)
+	return string.match(v, '^(.*)|[^|]*$') or v
+end
+`
+
+// acquireScript locks every key for owner ARGV[1] with value ARGV[2] and a
+// TTL of ARGV[3] ms, or none of them. On conflict it returns {i}, the
+// 1-based index of the first key held by another owner. On success it
+// returns {0} followed by the index and old value of each key the owner
+// already held under a different value; those keys are taken over.
+var acquireScript = redis.NewScript(ownerOf + `
+local replaced = {0}
+for i, key in ipairs(KEYS) do
+	local holder = redis.call('GET', key)
+	if holder then
+		if owner(holder) ~= ARGV[1] then
+			return {i}
+		end
+		if holder ~= ARGV[2] then
+			table.insert(replaced, i)
+			table.insert(replaced, holder)
+		end
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('SET', key, ARGV[2], 'PX', ARGV[3])
+end
+return replaced
+`)
+
+// releaseScript deletes the keys held by owner ARGV[1] and returns the
+// 1-based index and value of each key it deleted, flattened.
+var releaseScript = redis.NewScript(ownerOf + `
+local released = {}
+for i, key in ipairs(KEYS) do
+	local holder = redis.call('GET', key)
+	if holder and owner(holder) == ARGV[1] then
+		redis.call('DEL', key)
+		table.insert(released, i)
+		table.insert(released, holder)
+	end
+end
+return released
+`)
+
+// takeValueScript deletes every key if all of them hold exactly ARGV[1]
+// and returns 0, or changes nothing and returns the 1-based index of the
+// first key that does not.
+var takeValueScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	if redis.call('GET', key) ~= ARGV[1] then
+		return i
+	end
+end
+redis.call('DEL', unpack(KEYS))
+return 0
+`)
+
+// releaseValueScript deletes the keys whose value is exactly ARGV[1] and
+// returns the index and value of each, flattened.
+var releaseValueScript = redis.NewScript(`
+local released = {}
+for i, key in ipairs(KEYS) do
+	local holder = redis.call('GET', key)
+	if holder == ARGV[1] then
+		redis.call('DEL', key)
+		table.insert(released, i)
+		table.insert(released, holder)
+	end
+end
+return released
+`)
+
+// extendScript resets the TTL of the keys whose value is exactly ARGV[1]
+// to ARGV[2] ms and returns how many it extended.
+var extendScript = redis.NewScript(`
+local n = 0
+for _, key in ipairs(KEYS) do
+	if redis.call('GET', key) == ARGV[1] then
+		redis.call('PEXPIRE', key, ARGV[2])
+		n = n + 1
+	end
+end
+return n
+`)
+
+// Acquire locks all keys for owner for ttl, storing Value(owner, token).
+// When any key is held by another owner nothing is changed and the error
+// wraps ErrConflict. Keys the owner already held under another token are
+// returned with their old value, by index into keys.
+func Acquire(ctx context.Context, rdb *redis.Client, keys []string, owner, token string, ttl time.Duration) (map[int]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if owner == "" {
+		return nil, errors.New("lock owner is required")
+	}
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		return nil, errors.New("lock ttl must be positive")
+	}
+
+	res, err := acquireScript.Run(ctx, rdb, keys, owner, Value(owner, token), ms).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if idx, _ := res[0].(int64); idx > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrConflict, keys[idx-1])
+	}
+	return pairs(res[1:]), nil
+}
+
+// Release deletes the keys owned by owner. It returns the stored value of
+// each released key by its index into keys; keys held by someone else, or
+// already expired, are left alone.
+func Release(ctx context.Context, rdb *redis.Client, keys []string, owner string) (map[int]string, error) {
+	if len(keys) == 0 || owner == "" {
+		return nil, nil
+	}
+	return run(ctx, rdb, releaseScript, keys, owner)
+}
+
+// ReleaseValue deletes the keys still holding exactly value, such as the
+// seats of one hold, and returns them by index.
+func ReleaseValue(ctx context.Context, rdb *redis.Client, keys []string, value string) (map[int]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return run(ctx, rdb, releaseValueScript, keys, value)
+}
+
+// Extend resets the TTL of the keys still holding exactly value and returns
+// how many it extended.
+func Extend(ctx context.Context, rdb *redis.Client, keys []string, value string, ttl time.Duration) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		return 0, errors.New("lock ttl must be positive")
+	}
+	return extendScript.Run(ctx, rdb, keys, value, ms).Int()
+}
+
+// TakeValue deletes all keys, as when seats are sold, provided every one
+// still holds exactly value. Otherwise nothing is changed and the error
+// wraps ErrNotHeld.
+func TakeValue(ctx context.Context, rdb *redis.Client, keys []string, value string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	idx, err := takeValueScript.Run(ctx, rdb, keys, value).Int()
+	if err != nil {
+		return err
+	}
+	if idx > 0 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, keys[idx-1])
+	}
+	return nil
+}
+
+// run executes a script returning flattened (index, value) pairs.
+func run(ctx context.Context, rdb *redis.Client, script *redis.Script, keys []string, args ...interface{}) (map[int]string, error) {
+	res, err := script.Run(ctx, rdb, keys, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	return pairs(res), nil
+}
+
+// pairs decodes flattened (1-based index, value) pairs.
+func pairs(res []interface{}) map[int]string {
+	out := make(map[int]string, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		idx, _ := res[i].(int64)
+		val, _ := res[i+1].(string)
+		out[int(idx)-1] = val
+	}
+	return out
+}
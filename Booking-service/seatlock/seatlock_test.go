@@ -0,0 +1,475 @@
+package seatlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// These tests run against an in-memory miniredis, or against the disposable
+// Redis at REDIS_URL when it is set (skipped when that is not reachable).
+// testRedis also returns a function that lets lock TTLs run out.
+func testRedis(t *testing.T) (*redis.Client, func(time.Duration)) {
+	t.Helper()
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		return rdb, mr.FastForward
+	}
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("parse REDIS_URL: %v", err)
+	}
+	rdb := redis.NewClient(opt)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		t.Skipf("redis not available at %s: %v", url, err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, time.Sleep
+}
+
+// seatKeys returns n lock keys unique to this test run
+func seatKeys(t *testing.T, rdb *redis.Client, n int) []string {
+	t.Helper()
+	prefix := fmt.Sprintf("seatlock_test:%s:%d:", t.Name(), time.Now().UnixNano())
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%sS%d", prefix, i)
+	}
+	t.Cleanup(func() { rdb.Del(context.Background(), keys...) })
+	return keys
+}
+
+// holder returns the owner of a lock key, or "" when it is free
+func holder(ctx context.Context, rdb *redis.Client, key string) string {
+	val, _ := rdb.Get(ctx, key).Result()
+	owner, _ := Split(val)
+	return owner
+}
+
+func TestAcquireAllOrNothing(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 3)
+
+	if _, err := Acquire(ctx, rdb, keys[:1], "alice", "", time.Minute); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	_, err := Acquire(ctx, rdb, keys, "bob", "", time.Minute)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("bob lock: got %v, want ErrConflict", err)
+	}
+	// bob's free seats must not have been taken
+	for _, key := range keys[1:] {
+		if n, _ := rdb.Exists(ctx, key).Result(); n != 0 {
+			t.Fatalf("%s was locked by a failed acquire", key)
+		}
+	}
+
+	// re-locking your own seats refreshes them under the new token
+	replaced, err := Acquire(ctx, rdb, keys, "alice", "hold-2", time.Minute)
+	if err != nil {
+		t.Fatalf("alice relock: %v", err)
+	}
+	if len(replaced) != 1 || replaced[0] != Value("alice", "") {
+		t.Fatalf("alice relock replaced %v, want only key 0", replaced)
+	}
+	for _, key := range keys {
+		if owner := holder(ctx, rdb, key); owner != "alice" {
+			t.Fatalf("%s owned by %q, want alice", key, owner)
+		}
+	}
+}
+
+func TestReleaseChecksOwnership(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 2)
+
+	if _, err := Acquire(ctx, rdb, keys[:1], "alice", "", time.Minute); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	if _, err := Acquire(ctx, rdb, keys[1:], "bob", "", time.Minute); err != nil {
+		t.Fatalf("bob lock: %v", err)
+	}
+
+	released, err := Release(ctx, rdb, keys, "bob")
+	if err != nil {
+		t.Fatalf("bob release: %v", err)
+	}
+	if len(released) != 1 || released[1] != Value("bob", "") {
+		t.Fatalf("bob released %v, want only key 1", released)
+	}
+	if owner := holder(ctx, rdb, keys[0]); owner != "alice" {
+		t.Fatalf("alice's seat owned by %q after bob's release", owner)
+	}
+}
+
+func TestExtendAndReleaseValueMatchOneHold(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 2)
+
+	if _, err := Acquire(ctx, rdb, keys, "alice", "hold-1", time.Second); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	// the second seat moves to alice's newer hold
+	if _, err := Acquire(ctx, rdb, keys[1:], "alice", "hold-2", time.Second); err != nil {
+		t.Fatalf("alice relock: %v", err)
+	}
+
+	n, err := Extend(ctx, rdb, keys, Value("alice", "hold-1"), time.Minute)
+	if err != nil || n != 1 {
+		t.Fatalf("extend hold-1: n=%d err=%v, want 1 key", n, err)
+	}
+	if ttl, _ := rdb.PTTL(ctx, keys[0]).Result(); ttl <= time.Second {
+		t.Fatalf("hold-1 seat ttl %v was not extended", ttl)
+	}
+
+	released, err := ReleaseValue(ctx, rdb, keys, Value("alice", "hold-1"))
+	if err != nil || len(released) != 1 {
+		t.Fatalf("release hold-1: %v err=%v, want 1 key", released, err)
+	}
+	if owner := holder(ctx, rdb, keys[1]); owner != "alice" {
+		t.Fatalf("hold-2 seat released with hold-1 (owner %q)", owner)
+	}
+}
+
+func TestAcquireExpires(t *testing.T) {
+	rdb, elapse := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 1)
+
+	if _, err := Acquire(ctx, rdb, keys, "alice", "", 50*time.Millisecond); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	elapse(150 * time.Millisecond)
+	if _, err := Acquire(ctx, rdb, keys, "bob", "", time.Minute); err != nil {
+		t.Fatalf("bob lock after expiry: %v", err)
+	}
+}
+
+func TestSplitValue(t *testing.T) {
+	tests := []struct {
+		value, owner, token string
+	}{
+		{Value("alice", "hold-1"), "alice", "hold-1"},
+		{Value("alice", ""), "alice", ""},
+		{"alice", "alice", ""},
+		// owners may contain the separator; the token never does
+		{Value("team|alice", "hold-1"), "team|alice", "hold-1"},
+	}
+	for _, tt := range tests {
+		owner, token := Split(tt.value)
+		if owner != tt.owner || token != tt.token {
+			t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.value, owner, token, tt.owner, tt.token)
+		}
+	}
+}
+
+func TestTakeValueAllOrNothing(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 3)
+
+	if _, err := Acquire(ctx, rdb, keys[:2], "alice", "hold-1", time.Minute); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	if _, err := Acquire(ctx, rdb, keys[2:], "alice", "hold-2", time.Minute); err != nil {
+		t.Fatalf("alice second hold: %v", err)
+	}
+
+	// one seat belongs to another hold, so nothing is taken
+	err := TakeValue(ctx, rdb, keys, Value("alice", "hold-1"))
+	if !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("take across holds: got %v, want ErrNotHeld", err)
+	}
+	for _, key := range keys {
+		if owner := holder(ctx, rdb, key); owner != "alice" {
+			t.Fatalf("%s changed by a failed take (owner %q)", key, owner)
+		}
+	}
+
+	if err := TakeValue(ctx, rdb, keys[:2], Value("alice", "hold-1")); err != nil {
+		t.Fatalf("take hold-1: %v", err)
+	}
+	if n, _ := rdb.Exists(ctx, keys[:2]...).Result(); n != 0 {
+		t.Fatalf("%d hold-1 seats still locked after take", n)
+	}
+	if owner := holder(ctx, rdb, keys[2]); owner != "alice" {
+		t.Fatalf("hold-2 seat taken with hold-1 (owner %q)", owner)
+	}
+}
+
+// After alice's hold expires and bob locks the seat, nothing alice still
+// knows about her hold may touch bob's lock.
+func TestExpiredHoldCannotTouchNewOwner(t *testing.T) {
+	rdb, elapse := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 2)
+	alice := Value("alice", "hold-1")
+
+	if _, err := Acquire(ctx, rdb, keys, "alice", "hold-1", 50*time.Millisecond); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+	elapse(150 * time.Millisecond)
+	if _, err := Acquire(ctx, rdb, keys[1:], "bob", "hold-2", time.Minute); err != nil {
+		t.Fatalf("bob lock after expiry: %v", err)
+	}
+
+	if err := TakeValue(ctx, rdb, keys, alice); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("alice take: got %v, want ErrNotHeld", err)
+	}
+	if n, err := Extend(ctx, rdb, keys, alice, time.Minute); err != nil || n != 0 {
+		t.Fatalf("alice extend: n=%d err=%v, want 0 keys", n, err)
+	}
+	if released, err := ReleaseValue(ctx, rdb, keys, alice); err != nil || len(released) != 0 {
+		t.Fatalf("alice release: %v err=%v, want none", released, err)
+	}
+	if released, err := Release(ctx, rdb, keys, "alice"); err != nil || len(released) != 0 {
+		t.Fatalf("alice release by owner: %v err=%v, want none", released, err)
+	}
+	if owner := holder(ctx, rdb, keys[1]); owner != "bob" {
+		t.Fatalf("bob's seat owned by %q", owner)
+	}
+}
+
+// Several confirmations of the same hold race; exactly one may sell it.
+func TestConcurrentTakeValueOnce(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 4)
+	value := Value("alice", "hold-1")
+
+	if _, err := Acquire(ctx, rdb, keys, "alice", "hold-1", time.Minute); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+
+	const callers = 32
+	var wins int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for c := 0; c < callers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := TakeValue(ctx, rdb, keys, value)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&wins, 1)
+			case !errors.Is(err, ErrNotHeld):
+				t.Errorf("take: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if wins != 1 {
+		t.Fatalf("%d callers took the same hold, want exactly 1", wins)
+	}
+}
+
+// A hold's owner extends it while others try to take its seats; the seats
+// never change hands and stay locked for the owner.
+func TestConcurrentExtendAndAcquire(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 3)
+	value := Value("alice", "hold-1")
+
+	if _, err := Acquire(ctx, rdb, keys, "alice", "hold-1", time.Minute); err != nil {
+		t.Fatalf("alice lock: %v", err)
+	}
+
+	const users, rounds = 8, 20
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for r := 0; r < rounds; r++ {
+			if n, err := Extend(ctx, rdb, keys, value, time.Minute); err != nil || n != len(keys) {
+				t.Errorf("extend: n=%d err=%v", n, err)
+				return
+			}
+		}
+	}()
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			owner := fmt.Sprintf("user-%d", u)
+			for r := 0; r < rounds; r++ {
+				if _, err := Acquire(ctx, rdb, keys, owner, "", time.Minute); !errors.Is(err, ErrConflict) {
+					t.Errorf("%s acquired a held seat: %v", owner, err)
+					return
+				}
+				if released, err := Release(ctx, rdb, keys, owner); err != nil || len(released) != 0 {
+					t.Errorf("%s released %v err=%v", owner, released, err)
+					return
+				}
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		if val, _ := rdb.Get(ctx, key).Result(); val != value {
+			t.Fatalf("%s holds %q, want %q", key, val, value)
+		}
+	}
+}
+
+// Many users race for the same seats; exactly one may win them all.
+func TestConcurrentAcquireSameSeats(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 4)
+
+	const users = 64
+	var wins int32
+	var winner atomic.Value
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			owner := fmt.Sprintf("user-%d", u)
+			// lock in a random order so scripts see keys differently
+			mine := append([]string(nil), keys...)
+			rand.Shuffle(len(mine), func(i, j int) { mine[i], mine[j] = mine[j], mine[i] })
+			<-start
+			_, err := Acquire(ctx, rdb, mine, owner, "", time.Minute)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&wins, 1)
+				winner.Store(owner)
+			case !errors.Is(err, ErrConflict):
+				t.Errorf("%s: %v", owner, err)
+			}
+		}(u)
+	}
+	close(start)
+	wg.Wait()
+
+	if wins != 1 {
+		t.Fatalf("%d users locked the same seats, want exactly 1", wins)
+	}
+	for _, key := range keys {
+		if owner := holder(ctx, rdb, key); owner != winner.Load() {
+			t.Fatalf("%s owned by %q, want %q", key, owner, winner.Load())
+		}
+	}
+}
+
+// Users lock overlapping pairs; every seat ends up with exactly one owner
+// and every successful group is held entirely by its owner.
+func TestConcurrentAcquireOverlappingGroups(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 16)
+
+	const users = 100
+	var mu sync.Mutex
+	won := map[string][]string{}
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			owner := fmt.Sprintf("user-%d", u)
+			i := u % len(keys)
+			group := []string{keys[i], keys[(i+1)%len(keys)]}
+			<-start
+			_, err := Acquire(ctx, rdb, group, owner, "", time.Minute)
+			if err == nil {
+				mu.Lock()
+				won[owner] = group
+				mu.Unlock()
+			} else if !errors.Is(err, ErrConflict) {
+				t.Errorf("%s: %v", owner, err)
+			}
+		}(u)
+	}
+	close(start)
+	wg.Wait()
+
+	held := map[string]string{}
+	for owner, group := range won {
+		for _, key := range group {
+			if prev, ok := held[key]; ok {
+				t.Fatalf("%s granted to both %s and %s", key, prev, owner)
+			}
+			held[key] = owner
+			if got := holder(ctx, rdb, key); got != owner {
+				t.Fatalf("%s owned by %q, want %q", key, got, owner)
+			}
+		}
+	}
+}
+
+// Users repeatedly lock and release one seat; nobody may ever share it.
+func TestConcurrentLockReleaseExclusive(t *testing.T) {
+	rdb, _ := testRedis(t)
+	ctx := context.Background()
+	keys := seatKeys(t, rdb, 1)
+
+	const users, rounds = 16, 50
+	var holders, maxHolders, grants int32
+	var wg sync.WaitGroup
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			owner := fmt.Sprintf("user-%d", u)
+			for r := 0; r < rounds; r++ {
+				_, err := Acquire(ctx, rdb, keys, owner, "", time.Minute)
+				if errors.Is(err, ErrConflict) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("%s: %v", owner, err)
+					return
+				}
+				n := atomic.AddInt32(&holders, 1)
+				for {
+					m := atomic.LoadInt32(&maxHolders)
+					if n <= m || atomic.CompareAndSwapInt32(&maxHolders, m, n) {
+						break
+					}
+				}
+				atomic.AddInt32(&grants, 1)
+				atomic.AddInt32(&holders, -1)
+				if _, err := Release(ctx, rdb, keys, owner); err != nil {
+					t.Errorf("%s release: %v", owner, err)
+					return
+				}
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	if maxHolders > 1 {
+		t.Fatalf("%d users held the seat at once", maxHolders)
+	}
+	if grants == 0 {
+		t.Fatal("no user ever acquired the seat")
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MigrationResult counts what MigrateReferences did
+type MigrationResult struct {
+	Migrated int `json:"migrated"`
+	// Unresolved bookings name an inventory item no platform knows; they
+	// get an inventory ID but no platform, seat snapshots or display info
+	Unresolved int `json:"unresolved"`
+}
+
+// MigrateReferences fills in the platform, inventory ID, seat snapshots and
+// display info of bookings saved before bookings carried them. Bookings
+// without a platform are matched to the platform whose inventory has their
+// show ID. It only touches bookings without an inventory ID, so it is safe
+// to run on every start and from several replicas.
+func (s *BookingService) MigrateReferences(ctx context.Context, platforms *platform.Registry) (MigrationResult, error) {
+	var result MigrationResult
+
+	cursor, err := s.bookingColl.Find(ctx, bson.M{"inventory_id": bson.M{"$exists": false}})
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var booking model.Booking
+		if err := cursor.Decode(&booking); err != nil {
+			return result, err
+		}
+
+		set := bson.M{"inventory_id": booking.ShowID}
+		inv := findInventory(ctx, platforms, booking.Platform, booking.ShowID)
+		if inv == nil {
+			result.Unresolved++
+			slog.WarnContext(ctx, "booking inventory not found during migration", "booking_id", booking.ID, "platform", booking.Platform, "show_id", booking.ShowID)
+		} else {
+			set["platform"] = inv.Platform
+			set["seat_details"] = snapshotSeats(inv, booking)
+			set["display"] = bookingDisplay(inv.Display)
+			if booking.Currency == "" {
+				set["currency"] = platform.DefaultCurrency
+			}
+		}
+
+		res, err := s.bookingColl.UpdateOne(
+			ctx,
+			bson.M{"_id": booking.ID, "inventory_id": bson.M{"$exists": false}},
+			bson.M{
+				"$set": set,
+				"$inc": bson.M{"version": 1},
+			},
+		)
+		if err != nil {
+			return result, err
+		}
+		if res.ModifiedCount > 0 {
+			result.Migrated++
+		}
+	}
+
+	return result, cursor.Err()
+}
+
+// findInventory loads a booking's inventory item from its platform, or from
+// every platform when the booking does not name one
+func findInventory(ctx context.Context, platforms *platform.Registry, name, inventoryID string) *platform.Inventory {
+	names := platforms.Names()
+	if name != "" {
+		names = []string{name}
+	}
+	for _, n := range names {
+		p, err := platforms.Get(n)
+		if err != nil {
+			continue
+		}
+		if inv, err := p.Inventory(ctx, inventoryID); err == nil {
+			return inv
+		}
+	}
+	return nil
+}
+
+// snapshotSeats describes a migrated booking's seats. The price is what
+// the booking was charged per seat, not the seat's current price.
+func snapshotSeats(inv *platform.Inventory, booking model.Booking) []model.BookedSeat {
+	byID := make(map[string]platform.Seat, len(inv.Seats))
+	for _, seat := range inv.Seats {
+		byID[seat.ID] = seat
+	}
+
+	var charged float64
+	if len(booking.Seats) > 0 {
+		charged = booking.TotalPrice / float64(len(booking.Seats))
+	}
+
+	seats := make([]model.BookedSeat, 0, len(booking.Seats))
+	for _, id := range booking.Seats {
+		seat := byID[id]
+		seats = append(seats, model.BookedSeat{ID: id, Label: seat.Label, Class: seat.Class, Price: charged})
+	}
+	return seats
+}
@@ -3,14 +3,43 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/metrics"
 	"github.com/ansh0014/booking/model"
+	"github.com/ansh0014/booking/outbox"
+	"github.com/ansh0014/booking/pricing"
+	"github.com/ansh0014/booking/tracing"
 	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxTransitionRetries bounds how often a system transition re-reads a
+// booking that changed under it
+const maxTransitionRetries = 3
+
+// bookingEventTypes maps the states announced to other services to their
+// event types
+var bookingEventTypes = map[string]string{
+	model.BookingConfirmed: outbox.BookingConfirmed,
+	model.BookingCancelled: outbox.BookingCancelled,
+	model.BookingExpired:   outbox.BookingExpired,
+}
+
+var (
+	// ErrBookingNotFound is returned for an unknown booking ID
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrInvalidTransition is returned when the lifecycle forbids a status change
+	ErrInvalidTransition = errors.New("invalid booking status change")
+	// ErrVersionConflict is returned when a booking changed since it was read
+	ErrVersionConflict = errors.New("booking was modified concurrently")
 )
 
 // BookingService handles booking-related functionality
@@ -19,6 +48,9 @@ type BookingService struct {
 	bookingColl *mongo.Collection
 	redisClient *redis.Client
 	seatService *SeatService
+	holdService *HoldService
+	pricing     *pricing.Engine
+	outbox      *outbox.Store
 }
 
 // NewBookingService creates a new booking service
@@ -35,8 +67,30 @@ func (s *BookingService) SetSeatService(seatService *SeatService) {
 	s.seatService = seatService
 }
 
+// SetHoldService sets the hold service that payments are checked against
+func (s *BookingService) SetHoldService(holdService *HoldService) {
+	s.holdService = holdService
+}
+
+// SetOutbox sets the outbox that booking events are written to along with
+// the changes they announce
+func (s *BookingService) SetOutbox(store *outbox.Store) {
+	s.outbox = store
+}
+
+// SetPricingEngine sets the engine that prices new bookings
+func (s *BookingService) SetPricingEngine(engine *pricing.Engine) {
+	s.pricing = engine
+}
+
 // CreateBooking creates a new booking
 func (s *BookingService) CreateBooking(ctx context.Context, req model.BookingRequest, userID string) (*model.Booking, error) {
+	ctx, span := tracer.Start(ctx, "BookingService.CreateBooking", trace.WithAttributes(
+		attribute.String("booking.platform", req.Platform),
+		attribute.Int("booking.seat_count", len(req.SeatIDs)),
+	))
+	defer span.End()
+
 	// Validate request
 	if req.Platform == "" {
 		return nil, errors.New("platform is required")
@@ -57,57 +111,92 @@ func (s *BookingService) CreateBooking(ctx context.Context, req model.BookingReq
 		SeatIDs:    req.SeatIDs,
 	}
 
-	err := s.seatService.LockSeats(ctx, seatReq, userID)
+	// Price the seats before holding them
+	quote, err := s.seatService.Quote(ctx, seatReq)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	price, err := s.pricing.Price(quote, req.PromoCode)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	seatHold, err := s.seatService.LockSeats(ctx, seatReq, userID)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
 	// Create booking ID
 	id := primitive.NewObjectID()
 
-	// Calculate price (in a real system, fetch from the relevant platform service)
-	seatPrice := 100.0 // Default price per seat
-	totalPrice := seatPrice * float64(len(req.SeatIDs))
-
 	// Create booking
+	now := time.Now()
 	booking := &model.Booking{
 		ID:          id.Hex(),
 		UserID:      userID,
-		ShowID:      req.PlatformID, // Using platformID as showID
+		Platform:    req.Platform,
+		HoldID:      seatHold.ID,
+		InventoryID: req.PlatformID,
+		ShowID:      req.PlatformID,
 		Seats:       req.SeatIDs,
-		TotalPrice:  totalPrice,
-		Status:      "pending",
-		BookingTime: time.Now(),
-		ExpiryTime:  time.Now().Add(5 * time.Minute),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		SeatDetails: bookedSeats(quote.Lines),
+		Display:     bookingDisplay(quote.Display),
+		TotalPrice:  price.Total,
+		Currency:    price.Currency,
+		Price:       price,
+		Status:      model.BookingHeld,
+		Version:     1,
+		History: []model.BookingEvent{
+			{To: model.BookingHeld, Actor: model.UserActor(userID), Reason: "seats held", At: now},
+		},
+		BookingTime: now,
+		ExpiryTime:  seatHold.ExpiresAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
-	// Save to database
-	_, err = s.bookingColl.InsertOne(ctx, booking)
+	// Save to database along with the event announcing it
+	created, err := outbox.NewEvent(outbox.BookingCreated, booking.ID, booking)
+	if err != nil {
+		tracing.RecordError(span, err)
+		s.seatService.ReleaseSeats(ctx, seatReq, userID)
+		return nil, err
+	}
+	insertCtx, insertSpan := tracer.Start(ctx, "BookingService.InsertBooking")
+	err = s.write(insertCtx, func(ctx context.Context) error {
+		_, err := s.bookingColl.InsertOne(ctx, booking)
+		return err
+	}, created)
+	tracing.RecordError(insertSpan, err)
+	insertSpan.End()
 	if err != nil {
 		// If insert fails, release the seats
+		tracing.RecordError(span, err)
 		s.seatService.ReleaseSeats(ctx, seatReq, userID)
 		return nil, err
 	}
+	span.SetAttributes(attribute.String("booking.id", booking.ID))
 
+	metrics.BookingsCreated.Inc()
 	return booking, nil
 }
 
 // GetBooking retrieves a booking by ID
 func (s *BookingService) GetBooking(ctx context.Context, bookingID string) (*model.Booking, error) {
-	// Convert string ID to ObjectID
-	id, err := primitive.ObjectIDFromHex(bookingID)
-	if err != nil {
-		return nil, errors.New("invalid booking ID")
+	// Booking IDs are ObjectIDs stored in hex
+	if _, err := primitive.ObjectIDFromHex(bookingID); err != nil {
+		return nil, ErrBookingNotFound
 	}
 
 	// Find booking
 	var booking model.Booking
-	err = s.bookingColl.FindOne(ctx, bson.M{"_id": id}).Decode(&booking)
+	err := s.bookingColl.FindOne(ctx, bson.M{"_id": bookingID}).Decode(&booking)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("booking not found")
+			return nil, ErrBookingNotFound
 		}
 		return nil, err
 	}
@@ -156,48 +245,334 @@ func (s *BookingService) GetUserBookings(ctx context.Context, userID string, pag
 	return bookings, total, nil
 }
 
-// CancelBooking cancels a booking
-func (s *BookingService) CancelBooking(ctx context.Context, bookingID string) error {
-	// Get the booking first
-	booking, err := s.GetBooking(ctx, bookingID)
+// Transition moves a booking to status to if the lifecycle allows it. The
+// write only succeeds while the booking still has the version it was read
+// at; it bumps the version and appends the change to the booking's history.
+func (s *BookingService) Transition(ctx context.Context, booking *model.Booking, to, actor, reason string) (*model.Booking, error) {
+	return s.transition(ctx, booking, to, actor, reason, "")
+}
+
+// transition is Transition that also records the payment behind the change
+// when paymentID is set
+func (s *BookingService) transition(ctx context.Context, booking *model.Booking, to, actor, reason, paymentID string) (*model.Booking, error) {
+	if !model.CanTransition(booking.Status, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, booking.Status, to)
+	}
+
+	now := time.Now()
+	event := model.BookingEvent{From: booking.Status, To: to, Actor: actor, Reason: reason, At: now}
+
+	filter := bson.M{"_id": booking.ID, "status": booking.Status, "version": booking.Version}
+	if booking.Version == 0 {
+		// Bookings created before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{int64(0), nil}}
+	}
+
+	set := bson.M{
+		"status":     to,
+		"updated_at": now,
+	}
+	if paymentID != "" {
+		set["payment_id"] = paymentID
+	}
+
+	updated := *booking
+	updated.Status = to
+	updated.Version++
+	updated.UpdatedAt = now
+	if paymentID != "" {
+		updated.PaymentID = paymentID
+	}
+	updated.History = append(append([]model.BookingEvent(nil), booking.History...), event)
+
+	// Announce the states other services act on
+	var events []outbox.Event
+	if eventType, ok := bookingEventTypes[to]; ok {
+		ev, err := outbox.NewEvent(eventType, booking.ID, &updated)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.bookingColl.UpdateOne(
+			ctx,
+			filter,
+			bson.M{
+				"$set":  set,
+				"$inc":  bson.M{"version": 1},
+				"$push": bson.M{"history": event},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	}, events...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Only pending or confirmed bookings can be cancelled
-	if booking.Status != "pending" && booking.Status != "confirmed" {
-		return errors.New("booking cannot be cancelled in its current state")
+	switch to {
+	case model.BookingConfirmed:
+		metrics.BookingsConfirmed.Inc()
+	case model.BookingCancelled:
+		metrics.BookingsCancelled.Inc()
+	case model.BookingExpired:
+		metrics.BookingsExpired.Inc()
+	}
+
+	return &updated, nil
+}
+
+// write runs fn and stores events in the outbox in the same transaction
+func (s *BookingService) write(ctx context.Context, fn func(ctx context.Context) error, events ...outbox.Event) error {
+	if s.outbox == nil {
+		return fn(ctx)
+	}
+	return s.outbox.Write(ctx, fn, events...)
+}
+
+// transitionWhere transitions the booking matching filter, re-reading it
+// when it changes between the read and the write
+func (s *BookingService) transitionWhere(ctx context.Context, filter bson.M, to, actor, reason string) (*model.Booking, error) {
+	for attempt := 1; ; attempt++ {
+		var booking model.Booking
+		if err := s.bookingColl.FindOne(ctx, filter).Decode(&booking); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrBookingNotFound
+			}
+			return nil, err
+		}
+
+		updated, err := s.Transition(ctx, &booking, to, actor, reason)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxTransitionRetries {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// CancelBooking cancels a booking on behalf of actor
+func (s *BookingService) CancelBooking(ctx context.Context, booking *model.Booking, actor, reason string) (*model.Booking, error) {
+	cancelled, err := s.Transition(ctx, booking, model.BookingCancelled, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open bookings free their held seats straight away
+	if isOpen(booking.Status) {
+		s.releaseHold(ctx, booking)
+	}
+
+	// Release seats if they were reserved
+	// Bookings made before the platform was recorded cannot be released here
+	if isReserved(booking.Status) && booking.Platform != "" {
+		// Create a seat lock request
+		seatReq := model.SeatLockRequest{
+			Platform:   booking.Platform,
+			PlatformID: booking.InventoryKey(),
+			SeatIDs:    booking.Seats,
+		}
+
+		// Put the seats back on sale
+		s.seatService.UnsellSeats(ctx, seatReq)
 	}
 
-	// Update booking status
-	id, _ := primitive.ObjectIDFromHex(bookingID)
-	_, err = s.bookingColl.UpdateOne(
+	return cancelled, nil
+}
+
+// ExpireBooking expires the open booking made with a seat hold that ran
+// out. Bookings already confirmed or cancelled are left alone.
+func (s *BookingService) ExpireBooking(ctx context.Context, holdID string) error {
+	filter := bson.M{
+		"hold_id": holdID,
+		"status":  bson.M{"$in": model.OpenBookingStatuses},
+	}
+
+	_, err := s.transitionWhere(ctx, filter, model.BookingExpired, model.ActorSystem, "seat hold expired")
+	if errors.Is(err, ErrBookingNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ExtendBooking moves the expiry of the open booking made with a seat hold
+// to the hold's new expiry. The version is left alone: it guards status
+// changes, and a client holding the booking must still be able to cancel it.
+func (s *BookingService) ExtendBooking(ctx context.Context, holdID string, expiresAt time.Time) error {
+	_, err := s.bookingColl.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
+		bson.M{"hold_id": holdID, "status": bson.M{"$in": model.OpenBookingStatuses}},
 		bson.M{
 			"$set": bson.M{
-				"status":     "cancelled",
-				"updated_at": time.Now(),
+				"expiry_time": expiresAt,
+				"updated_at":  time.Now(),
 			},
 		},
 	)
+	return err
+}
+
+// ExpireStaleBookings expires up to limit open bookings whose expiry time
+// has passed and returns the bookings it expired
+func (s *BookingService) ExpireStaleBookings(ctx context.Context, now time.Time, limit int64) ([]model.Booking, error) {
+	filter := bson.M{
+		"status":      bson.M{"$in": model.OpenBookingStatuses},
+		"expiry_time": bson.M{"$lt": now},
+	}
+
+	cursor, err := s.bookingColl.Find(ctx, filter, options.Find().SetLimit(limit))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// Release seats if it was a confirmed booking
-	// For pending bookings, they will be automatically released when the lock expires
-	if booking.Status == "confirmed" {
-		// Create a seat lock request
-		seatReq := model.SeatLockRequest{
-			Platform:   "unknown", // We would need to store this in the booking
-			PlatformID: booking.ShowID,
-			SeatIDs:    booking.Seats,
+	var stale []model.Booking
+	if err = cursor.All(ctx, &stale); err != nil {
+		return nil, err
+	}
+
+	expired := make([]model.Booking, 0, len(stale))
+	for i := range stale {
+		booking, err := s.Transition(ctx, &stale[i], model.BookingExpired, model.ActorSystem, "booking not paid in time")
+		// Skip bookings that changed since they were read
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, *booking)
+	}
+
+	return expired, nil
+}
+
+// SeatClaims are the seats held by reserved and open bookings, by
+// inventory ID. Inventory IDs are unique across platforms, so claims are
+// matched by inventory ID whatever platform a booking names.
+type SeatClaims struct {
+	Reserved map[string][]string
+	Open     map[string][]string
+	// Platform names the platform of each inventory ID, when its bookings
+	// name one
+	Platform map[string]string
+	// Unresolved holds inventory IDs with bookings that name no platform,
+	// e.g. legacy bookings the backfill could not resolve
+	Unresolved map[string]bool
+}
+
+// ClaimedSeats lists the seats of all reserved and open bookings
+func (s *BookingService) ClaimedSeats(ctx context.Context) (*SeatClaims, error) {
+	filter := bson.M{"status": bson.M{"$in": claimingStatuses()}}
+	opts := options.Find().SetProjection(bson.M{"platform": 1, "inventory_id": 1, "show_id": 1, "seats": 1, "status": 1})
+
+	cursor, err := s.bookingColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bookings []model.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+
+	claims := &SeatClaims{
+		Reserved:   make(map[string][]string),
+		Open:       make(map[string][]string),
+		Platform:   make(map[string]string),
+		Unresolved: make(map[string]bool),
+	}
+	for _, booking := range bookings {
+		inventoryID := booking.InventoryKey()
+		if booking.Platform == "" {
+			claims.Unresolved[inventoryID] = true
+		} else {
+			claims.Platform[inventoryID] = booking.Platform
 		}
+		if isReserved(booking.Status) {
+			claims.Reserved[inventoryID] = append(claims.Reserved[inventoryID], booking.Seats...)
+		} else {
+			claims.Open[inventoryID] = append(claims.Open[inventoryID], booking.Seats...)
+		}
+	}
+	return claims, nil
+}
+
+// ClaimedSeatsIn returns which of seatIDs of an inventory item belong to a
+// booking in one of statuses right now
+func (s *BookingService) ClaimedSeatsIn(ctx context.Context, inventoryID string, seatIDs []string, statuses []string) (map[string]bool, error) {
+	filter := bson.M{
+		"$or":    bson.A{bson.M{"inventory_id": inventoryID}, bson.M{"show_id": inventoryID}},
+		"status": bson.M{"$in": statuses},
+		"seats":  bson.M{"$in": seatIDs},
+	}
+	opts := options.Find().SetProjection(bson.M{"seats": 1})
 
-		// Release the seats
-		s.seatService.ReleaseSeats(ctx, seatReq, booking.UserID)
+	cursor, err := s.bookingColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bookings []model.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		return nil, err
 	}
 
-	return nil
+	wanted := seatSet(seatIDs)
+	claimed := make(map[string]bool)
+	for _, booking := range bookings {
+		for _, seatID := range booking.Seats {
+			if wanted[seatID] {
+				claimed[seatID] = true
+			}
+		}
+	}
+	return claimed, nil
+}
+
+// claimingStatuses are the booking states that keep seats from sale
+func claimingStatuses() []string {
+	return append(append([]string(nil), model.ReservedBookingStatuses...), model.OpenBookingStatuses...)
+}
+
+// isReserved reports whether a booking in status holds its seats for good
+func isReserved(status string) bool {
+	for _, reserved := range model.ReservedBookingStatuses {
+		if status == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// isOpen reports whether a booking in status still waits on its seat hold
+func isOpen(status string) bool {
+	for _, open := range model.OpenBookingStatuses {
+		if status == open {
+			return true
+		}
+	}
+	return false
+}
+
+// bookedSeats snapshots quoted seats for a booking
+func bookedSeats(lines []platform.QuoteLine) []model.BookedSeat {
+	seats := make([]model.BookedSeat, 0, len(lines))
+	for _, line := range lines {
+		seats = append(seats, model.BookedSeat{ID: line.SeatID, Label: line.Label, Class: line.Class, Price: line.Price})
+	}
+	return seats
+}
+
+// bookingDisplay copies a platform's display info onto a booking
+func bookingDisplay(d platform.Display) *model.BookingDisplay {
+	return &model.BookingDisplay{Title: d.Title, Venue: d.Venue, StartsAt: d.StartsAt}
 }
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ansh0014/booking/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// updated answers an update command as matching n documents
+func updated(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+// found answers a find command with one booking
+func found(mt *mtest.T, booking model.Booking) bson.D {
+	doc, err := bson.Marshal(booking)
+	if err != nil {
+		mt.Fatalf("marshal booking: %v", err)
+	}
+	var d bson.D
+	if err := bson.Unmarshal(doc, &d); err != nil {
+		mt.Fatalf("unmarshal booking: %v", err)
+	}
+	return mtest.CreateCursorResponse(0, "booking.bookings", mtest.FirstBatch, d)
+}
+
+func TestTransition(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+	held := model.Booking{
+		ID:      "b1",
+		UserID:  "u1",
+		Status:  model.BookingHeld,
+		Version: 3,
+		History: []model.BookingEvent{{To: model.BookingHeld, Actor: "user:u1"}},
+	}
+
+	mt.Run("applies the change", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+		mt.AddMockResponses(updated(1))
+
+		booking := held
+		got, err := s.transition(ctx, &booking, model.BookingPendingPayment, model.ActorPayment, "payment started", "p1")
+		if err != nil {
+			mt.Fatalf("transition: %v", err)
+		}
+		if got.Status != model.BookingPendingPayment || got.Version != 4 || got.PaymentID != "p1" {
+			mt.Fatalf("got status %s version %d payment %q", got.Status, got.Version, got.PaymentID)
+		}
+		if n := len(got.History); n != 2 {
+			mt.Fatalf("history has %d events, want 2", n)
+		}
+		if last := got.History[1]; last.From != model.BookingHeld || last.To != model.BookingPendingPayment || last.Actor != model.ActorPayment {
+			mt.Fatalf("history event %+v", last)
+		}
+		// the caller's booking is left as it was read
+		if booking.Status != model.BookingHeld || booking.Version != 3 || len(booking.History) != 1 {
+			mt.Fatalf("input booking changed to %+v", booking)
+		}
+
+		cmd := mt.GetStartedEvent().Command
+		filter := cmd.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+		if v := filter.Lookup("version").AsInt64(); v != 3 {
+			mt.Fatalf("update filtered on version %d, want 3", v)
+		}
+		if status := filter.Lookup("status").StringValue(); status != model.BookingHeld {
+			mt.Fatalf("update filtered on status %q, want %q", status, model.BookingHeld)
+		}
+	})
+
+	mt.Run("reports a version conflict", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+		mt.AddMockResponses(updated(0))
+
+		booking := held
+		got, err := s.transition(ctx, &booking, model.BookingCancelled, "user:u1", "changed my mind", "")
+		if !errors.Is(err, ErrVersionConflict) {
+			mt.Fatalf("got %v, want ErrVersionConflict", err)
+		}
+		if got != nil {
+			mt.Fatalf("conflict returned booking %+v", got)
+		}
+	})
+
+	mt.Run("rejects invalid changes without writing", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+
+		booking := held
+		booking.Status = model.BookingRefunded
+		if _, err := s.transition(ctx, &booking, model.BookingConfirmed, model.ActorPayment, "", ""); !errors.Is(err, ErrInvalidTransition) {
+			mt.Fatalf("got %v, want ErrInvalidTransition", err)
+		}
+		if ev := mt.GetStartedEvent(); ev != nil {
+			mt.Fatalf("invalid transition sent %s", ev.CommandName)
+		}
+	})
+
+	mt.Run("retries after a conflict", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+		// the booking moved on to pending_payment between the first read
+		// and write; the retry reads and transitions the new version
+		pending := held
+		pending.Status = model.BookingPendingPayment
+		pending.Version = 4
+		mt.AddMockResponses(found(mt, held), updated(0), found(mt, pending), updated(1))
+
+		got, err := s.transitionWhere(ctx, bson.M{"_id": held.ID}, model.BookingExpired, model.ActorSystem, "seat hold expired")
+		if err != nil {
+			mt.Fatalf("transitionWhere: %v", err)
+		}
+		if got.Version != 5 || got.History[len(got.History)-1].From != model.BookingPendingPayment {
+			mt.Fatalf("got version %d history %+v", got.Version, got.History)
+		}
+	})
+}
+
+func TestClaimedSeatsIn(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("returns only the asked seats still claimed", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+		mt.AddMockResponses(found(mt, model.Booking{ID: "b1", InventoryID: "inv-1", Seats: []string{"s1", "s3", "s9"}, Status: model.BookingConfirmed}))
+
+		claimed, err := s.ClaimedSeatsIn(context.Background(), "inv-1", []string{"s1", "s2", "s3"}, model.ReservedBookingStatuses)
+		if err != nil {
+			mt.Fatalf("ClaimedSeatsIn: %v", err)
+		}
+		if len(claimed) != 2 || !claimed["s1"] || !claimed["s3"] {
+			mt.Fatalf("claimed %v, want s1 and s3", claimed)
+		}
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		statuses, _ := filter.Lookup("status", "$in").Array().Values()
+		if len(statuses) != len(model.ReservedBookingStatuses) {
+			mt.Fatalf("filtered on %d statuses, want %d", len(statuses), len(model.ReservedBookingStatuses))
+		}
+	})
+}
+
+func TestExtendBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("moves the expiry without changing the version", func(mt *mtest.T) {
+		s := NewBookingService(mt.DB, nil)
+		mt.AddMockResponses(updated(1))
+
+		expiresAt := time.Now().Add(10 * time.Minute)
+		if err := s.ExtendBooking(context.Background(), "h1", expiresAt); err != nil {
+			mt.Fatalf("ExtendBooking: %v", err)
+		}
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		if got := update.Lookup("$set", "expiry_time").Time(); !got.Equal(expiresAt.Truncate(time.Millisecond)) {
+			mt.Fatalf("expiry set to %v, want %v", got, expiresAt)
+		}
+		if _, err := update.LookupErr("$inc"); err == nil {
+			mt.Fatalf("extending bumped the version: %v", update)
+		}
+	})
+}
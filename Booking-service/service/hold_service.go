@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/metrics"
+	"github.com/ansh0014/booking/stream"
+	"github.com/go-redis/redis/v8"
+)
+
+// sweepBatch bounds how many expired holds one sweep handles
+const sweepBatch = 100
+
+// HoldService manages seat holds after they are taken: extension, early
+// release and expiry
+type HoldService struct {
+	holds          *hold.Store
+	platforms      *platform.Registry
+	redisClient    *redis.Client
+	bookingService *BookingService
+}
+
+// NewHoldService creates a new hold service
+func NewHoldService(holds *hold.Store, platforms *platform.Registry, redisClient *redis.Client) *HoldService {
+	return &HoldService{
+		holds:       holds,
+		platforms:   platforms,
+		redisClient: redisClient,
+	}
+}
+
+// SetBookingService sets the booking service whose open bookings expire
+// with their holds
+func (s *HoldService) SetBookingService(bookingService *BookingService) {
+	s.bookingService = bookingService
+}
+
+// GetHold returns a hold owned by userID
+func (s *HoldService) GetHold(ctx context.Context, holdID, userID string) (*hold.SeatHold, error) {
+	h, err := s.holds.Get(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if h.Owner != userID {
+		return nil, hold.ErrNotOwner
+	}
+	return h, nil
+}
+
+// ExtendHold extends a hold by the given duration, or by the platform's
+// hold TTL when by is zero, up to the platform's maximum
+func (s *HoldService) ExtendHold(ctx context.Context, holdID, userID string, by time.Duration) (*hold.SeatHold, error) {
+	h, err := s.GetHold(ctx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.platforms.Get(h.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := p.HoldPolicy()
+	if by <= 0 {
+		by = policy.TTL
+	}
+	h, err = s.holds.Extend(ctx, holdID, userID, by, policy.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the open booking made with the hold from expiring early
+	if s.bookingService != nil {
+		if err := s.bookingService.ExtendBooking(ctx, h.ID, h.ExpiresAt); err != nil {
+			slog.WarnContext(ctx, "extending booking failed", "hold_id", h.ID, "error", err.Error())
+		}
+	}
+	return h, nil
+}
+
+// HeldSeats maps the held seats of an inventory item to their owners
+func (s *HoldService) HeldSeats(ctx context.Context, platformName, inventoryID string) (map[string]string, error) {
+	return s.holds.Locked(ctx, platformName, inventoryID)
+}
+
+// ReleaseHold frees all seats of a hold before it expires
+func (s *HoldService) ReleaseHold(ctx context.Context, holdID, userID string) (*hold.SeatHold, error) {
+	h, freed, err := s.holds.ReleaseHold(ctx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsReleased, Platform: h.Platform, InventoryID: h.InventoryID, SeatIDs: freed})
+	return h, nil
+}
+
+// RunExpiry sweeps expired holds every interval until ctx is cancelled.
+// Each expiry is announced on the seat stream and on hold.ExpiredChannel,
+// and the open booking made with the hold is expired.
+func (s *HoldService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep handles the holds that expired since the last run
+func (s *HoldService) sweep(ctx context.Context) {
+	for {
+		expired, err := s.holds.Sweep(ctx, time.Now(), sweepBatch)
+		for _, h := range expired {
+			s.expire(ctx, h)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "hold sweep failed", "error", err.Error())
+			return
+		}
+		if len(expired) < sweepBatch {
+			return
+		}
+	}
+}
+
+func (s *HoldService) expire(ctx context.Context, h *hold.SeatHold) {
+	metrics.HoldsExpired.WithLabelValues(h.Platform).Inc()
+	slog.InfoContext(ctx, "seat hold expired", "hold_id", h.ID, "platform", h.Platform, "inventory_id", h.InventoryID, "seats", len(h.Seats))
+
+	// The seat keys ran out before the sweep, so some seats may be held
+	// again already; only the ones still free are announced
+	free, err := s.holds.Free(ctx, h.Platform, h.InventoryID, h.Seats)
+	if err != nil {
+		slog.WarnContext(ctx, "reading expired seats failed", "hold_id", h.ID, "error", err.Error())
+	}
+	if len(free) > 0 {
+		stream.Publish(ctx, s.redisClient, stream.SeatEvent{Type: stream.SeatsExpired, Platform: h.Platform, InventoryID: h.InventoryID, SeatIDs: free})
+	}
+	if data, err := json.Marshal(h); err == nil {
+		if err := s.redisClient.Publish(ctx, hold.ExpiredChannel, data).Err(); err != nil {
+			slog.WarnContext(ctx, "hold expiry publish failed", "hold_id", h.ID, "error", err.Error())
+		}
+	}
+
+	if s.bookingService != nil {
+		if err := s.bookingService.ExpireBooking(ctx, h.ID); err != nil {
+			slog.WarnContext(ctx, "expiring booking failed", "hold_id", h.ID, "error", err.Error())
+		}
+	}
+}
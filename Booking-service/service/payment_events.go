@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/model"
+)
+
+// paymentLockTTL bounds how long one payment event may keep a booking
+// locked, so a crashed replica cannot block redeliveries for good
+const paymentLockTTL = 30 * time.Second
+
+// ErrPaymentInProgress is returned while another payment event for the
+// same booking is being applied; the sender should retry
+var ErrPaymentInProgress = errors.New("a payment event for this booking is already being applied")
+
+// ApplyPayment moves a booking along after its payment changed status:
+//
+//	pending    open bookings wait for the payment
+//	completed  the held seats are confirmed and the booking is confirmed
+//	failed     the booking is cancelled and its hold released
+//	refunded   the booking is refunded and reserved seats go back on sale
+//
+// Events may be delivered more than once and in any order; an event that no
+// longer applies leaves the booking alone. A completed payment that cannot
+// be kept, because the seat hold ran out or the booking was paid already,
+// is answered with Refund set.
+func (s *BookingService) ApplyPayment(ctx context.Context, ev model.PaymentEvent) (*model.PaymentOutcome, error) {
+	// One event per booking at a time, so a redelivery cannot confirm the
+	// same seats twice
+	lockKey := "payment_lock:" + ev.BookingID
+	locked, err := s.redisClient.SetNX(ctx, lockKey, ev.PaymentID, paymentLockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrPaymentInProgress
+	}
+	defer s.redisClient.Del(context.WithoutCancel(ctx), lockKey)
+
+	booking, err := s.GetBooking(ctx, ev.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ev.Status {
+	case model.PaymentPending:
+		return s.startPayment(ctx, booking, ev)
+	case model.PaymentCompleted:
+		return s.ConfirmBooking(ctx, booking, ev)
+	case model.PaymentFailed:
+		return s.failPayment(ctx, booking, ev)
+	case model.PaymentRefunded:
+		return s.refundPayment(ctx, booking, ev)
+	}
+	return paymentOutcome(booking, false, ""), nil
+}
+
+// startPayment marks an open booking as waiting for a payment
+func (s *BookingService) startPayment(ctx context.Context, booking *model.Booking, ev model.PaymentEvent) (*model.PaymentOutcome, error) {
+	if !model.CanTransition(booking.Status, model.BookingPendingPayment) {
+		return paymentOutcome(booking, false, ""), nil
+	}
+	updated, err := s.transition(ctx, booking, model.BookingPendingPayment, model.ActorPayment, "payment started", ev.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	return paymentOutcome(updated, false, ""), nil
+}
+
+// ConfirmBooking confirms an open booking paid by ev. The booking's seat
+// hold must still cover every seat; the seats are then reserved through the
+// booking's platform before the booking is confirmed.
+func (s *BookingService) ConfirmBooking(ctx context.Context, booking *model.Booking, ev model.PaymentEvent) (*model.PaymentOutcome, error) {
+	switch {
+	case isReserved(booking.Status) && booking.PaymentID == ev.PaymentID:
+		// redelivery of the payment that confirmed the booking
+		return paymentOutcome(booking, false, ""), nil
+	case isReserved(booking.Status):
+		return paymentOutcome(booking, true, "booking was already paid"), nil
+	case !isOpen(booking.Status):
+		return paymentOutcome(booking, true, "booking is "+booking.Status), nil
+	case ev.Amount > 0 && math.Abs(ev.Amount-booking.TotalPrice) >= 0.01:
+		return paymentOutcome(booking, true, "payment amount does not match the booking total"), nil
+	}
+
+	covered, err := s.holdCovers(ctx, booking)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		expired, err := s.transition(ctx, booking, model.BookingExpired, model.ActorPayment, "paid after the seat hold expired", ev.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		return paymentOutcome(expired, true, "seat hold expired before the payment completed"), nil
+	}
+
+	seatReq := model.SeatLockRequest{
+		Platform:   booking.Platform,
+		PlatformID: booking.InventoryKey(),
+		SeatIDs:    booking.Seats,
+	}
+	if err := s.seatService.ConfirmSeats(ctx, seatReq, booking.UserID, booking.HoldID); err != nil {
+		slog.WarnContext(ctx, "confirming paid seats failed", "booking_id", booking.ID, "payment_id", ev.PaymentID, "error", err.Error())
+		cancelled, terr := s.transition(ctx, booking, model.BookingCancelled, model.ActorPayment, "seats could not be confirmed", ev.PaymentID)
+		if terr != nil {
+			return nil, terr
+		}
+		// Free whatever seats the hold still has, e.g. after losing one
+		// of them to another user
+		s.releaseHold(ctx, booking)
+		return paymentOutcome(cancelled, true, "seats are no longer available"), nil
+	}
+
+	// The seats are sold now; only a booking that changed under us may stop
+	// the confirmation, so re-read it on a version conflict
+	for attempt := 1; ; attempt++ {
+		confirmed, err := s.transition(ctx, booking, model.BookingConfirmed, model.ActorPayment, "payment completed", ev.PaymentID)
+		if err == nil {
+			return paymentOutcome(confirmed, false, ""), nil
+		}
+		if errors.Is(err, ErrVersionConflict) && attempt < maxTransitionRetries {
+			if booking, err = s.GetBooking(ctx, booking.ID); err == nil {
+				continue
+			}
+		}
+
+		// Put the seats back on sale rather than sell them without a booking
+		if rerr := s.seatService.UnsellSeats(ctx, seatReq); rerr != nil {
+			slog.ErrorContext(ctx, "releasing unconfirmed seats failed", "booking_id", booking.ID, "error", rerr.Error())
+		}
+		if errors.Is(err, ErrInvalidTransition) {
+			return paymentOutcome(booking, true, "booking is "+booking.Status), nil
+		}
+		return nil, err
+	}
+}
+
+// failPayment cancels an open booking whose payment failed and frees its
+// seats straight away
+func (s *BookingService) failPayment(ctx context.Context, booking *model.Booking, ev model.PaymentEvent) (*model.PaymentOutcome, error) {
+	// Another payment may still complete the booking
+	if !isOpen(booking.Status) || (booking.PaymentID != "" && booking.PaymentID != ev.PaymentID) {
+		return paymentOutcome(booking, false, ""), nil
+	}
+
+	cancelled, err := s.transition(ctx, booking, model.BookingCancelled, model.ActorPayment, "payment failed", ev.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	s.releaseHold(ctx, booking)
+	return paymentOutcome(cancelled, false, ""), nil
+}
+
+// refundPayment refunds the booking the refunded payment paid for and puts
+// its seats back on sale if they were still reserved
+func (s *BookingService) refundPayment(ctx context.Context, booking *model.Booking, ev model.PaymentEvent) (*model.PaymentOutcome, error) {
+	// Refunds of payments the booking never kept change nothing
+	if booking.PaymentID != ev.PaymentID || !model.CanTransition(booking.Status, model.BookingRefunded) {
+		return paymentOutcome(booking, false, ""), nil
+	}
+
+	refunded, err := s.transition(ctx, booking, model.BookingRefunded, model.ActorPayment, "payment refunded", ev.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if isReserved(booking.Status) && booking.Platform != "" {
+		seatReq := model.SeatLockRequest{
+			Platform:   booking.Platform,
+			PlatformID: booking.InventoryKey(),
+			SeatIDs:    booking.Seats,
+		}
+		if err := s.seatService.UnsellSeats(ctx, seatReq); err != nil {
+			slog.WarnContext(ctx, "releasing refunded seats failed", "booking_id", booking.ID, "error", err.Error())
+		}
+	}
+	return paymentOutcome(refunded, false, ""), nil
+}
+
+// releaseHold frees the seats the booking's hold still has. Holds that ended
+// already are left alone.
+func (s *BookingService) releaseHold(ctx context.Context, booking *model.Booking) {
+	if booking.HoldID == "" || s.holdService == nil {
+		return
+	}
+	_, err := s.holdService.ReleaseHold(ctx, booking.HoldID, booking.UserID)
+	if err != nil && !errors.Is(err, hold.ErrNotActive) && !errors.Is(err, hold.ErrNotFound) {
+		slog.WarnContext(ctx, "releasing booking hold failed", "booking_id", booking.ID, "hold_id", booking.HoldID, "error", err.Error())
+	}
+}
+
+// holdCovers reports whether the booking's seat hold is still active and
+// holds every seat of the booking
+func (s *BookingService) holdCovers(ctx context.Context, booking *model.Booking) (bool, error) {
+	if booking.HoldID == "" || booking.Platform == "" || s.holdService == nil {
+		return false, nil
+	}
+	h, err := s.holdService.GetHold(ctx, booking.HoldID, booking.UserID)
+	if errors.Is(err, hold.ErrNotFound) || errors.Is(err, hold.ErrNotOwner) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if h.Status != hold.StatusHeld {
+		return false, nil
+	}
+
+	held := seatSet(h.Seats)
+	for _, seatID := range booking.Seats {
+		if !held[seatID] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func paymentOutcome(booking *model.Booking, refund bool, reason string) *model.PaymentOutcome {
+	return &model.PaymentOutcome{
+		BookingID:     booking.ID,
+		BookingStatus: booking.Status,
+		Refund:        refund,
+		Reason:        reason,
+	}
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
+	"github.com/ansh0014/booking/leader"
+	"github.com/ansh0014/booking/metrics"
+	"github.com/ansh0014/booking/model"
+)
+
+// staleBatch bounds how many stale bookings one pass expires
+const staleBatch = 200
+
+// unclaimedGrace is how long an unavailable seat must stay without a
+// booking or hold before it goes back on sale. It outlasts a payment, so a
+// seat caught between its Mongo and booking writes is never freed.
+const unclaimedGrace = 30 * time.Minute
+
+// SeatFix is one correction the reconciler made to seat flags
+type SeatFix struct {
+	Platform    string   `json:"platform"`
+	InventoryID string   `json:"inventory_id"`
+	SeatIDs     []string `json:"seat_ids"`
+	Available   bool     `json:"available"`
+}
+
+// ReconcileReport lists the discrepancies one reconcile pass fixed
+type ReconcileReport struct {
+	Seats []SeatFix `json:"seats"`
+	// Counters lists the inventory IDs whose seat counters were reset, by platform
+	Counters map[string][]string `json:"counters"`
+	// Unclaimed lists unavailable seats no booking or hold claims that are
+	// still within their grace period; later passes put them back on sale
+	Unclaimed []SeatFix `json:"unclaimed"`
+}
+
+// Fixed reports whether the pass corrected anything
+func (r *ReconcileReport) Fixed() bool {
+	return len(r.Seats) > 0 || len(r.Counters) > 0
+}
+
+// ReconcileService expires open bookings left behind by lost holds and
+// repairs seat flags and counters in Mongo that drifted from bookings and
+// holds, e.g. after a crash between a Redis and a Mongo write. Sold seats
+// are taken off sale; unavailable seats nobody claims go back on sale once
+// they stayed unclaimed for unclaimedGrace. Only the replica holding the
+// leader lease does either.
+type ReconcileService struct {
+	bookingService *BookingService
+	holdService    *HoldService
+	platforms      *platform.Registry
+	lease          *leader.Lease
+	leading        bool
+
+	mu sync.Mutex
+	// unclaimedSince records when each unclaimed seat was first seen, by
+	// unclaimedKey. It starts over on the replica that takes the lead.
+	unclaimedSince map[string]time.Time
+}
+
+// NewReconcileService creates a new reconcile service
+func NewReconcileService(bookingService *BookingService, holdService *HoldService, platforms *platform.Registry, lease *leader.Lease) *ReconcileService {
+	return &ReconcileService{
+		bookingService: bookingService,
+		holdService:    holdService,
+		platforms:      platforms,
+		lease:          lease,
+		unclaimedSince: make(map[string]time.Time),
+	}
+}
+
+// Run expires stale bookings every expireEvery and reconciles seats every
+// reconcileEvery while this replica leads, until ctx is cancelled
+func (s *ReconcileService) Run(ctx context.Context, expireEvery, reconcileEvery time.Duration) {
+	expireTicker := time.NewTicker(expireEvery)
+	defer expireTicker.Stop()
+	reconcileTicker := time.NewTicker(reconcileEvery)
+	defer reconcileTicker.Stop()
+
+	// Hand leadership over straight away on shutdown
+	defer func() {
+		if !s.leading {
+			return
+		}
+		resignCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := s.lease.Resign(resignCtx); err != nil {
+			slog.Warn("resigning reconcile leadership failed", "error", err.Error())
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expireTicker.C:
+			if s.lead(ctx) {
+				s.expireStale(ctx)
+			}
+		case <-reconcileTicker.C:
+			if !s.lead(ctx) {
+				continue
+			}
+			report, err := s.Reconcile(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "seat reconcile failed", "error", err.Error())
+			}
+			if report.Fixed() {
+				slog.WarnContext(ctx, "seat reconcile fixed discrepancies", "seat_fixes", len(report.Seats), "counter_fixes", len(report.Counters))
+			}
+			if len(report.Unclaimed) > 0 {
+				slog.WarnContext(ctx, "seat reconcile found unclaimed unavailable seats", "inventories", len(report.Unclaimed))
+			}
+		}
+	}
+}
+
+// lead takes or renews the leader lease and logs leadership changes
+func (s *ReconcileService) lead(ctx context.Context) bool {
+	ok, err := s.lease.Acquire(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "reconcile leader election failed", "error", err.Error())
+		ok = false
+	}
+	if ok != s.leading {
+		slog.InfoContext(ctx, "reconcile leadership changed", "leader", ok, "instance", s.lease.ID())
+		s.leading = ok
+	}
+	return ok
+}
+
+// expireStale expires open bookings past their expiry time and releases
+// whatever their holds still have
+func (s *ReconcileService) expireStale(ctx context.Context) {
+	for {
+		expired, err := s.bookingService.ExpireStaleBookings(ctx, time.Now(), staleBatch)
+		for _, booking := range expired {
+			slog.InfoContext(ctx, "stale booking expired", "booking_id", booking.ID, "hold_id", booking.HoldID)
+			if booking.HoldID == "" {
+				continue
+			}
+			_, err := s.holdService.ReleaseHold(ctx, booking.HoldID, booking.UserID)
+			if err != nil && !errors.Is(err, hold.ErrNotActive) && !errors.Is(err, hold.ErrNotFound) {
+				slog.WarnContext(ctx, "releasing stale booking hold failed", "booking_id", booking.ID, "hold_id", booking.HoldID, "error", err.Error())
+			}
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "expiring stale bookings failed", "error", err.Error())
+			return
+		}
+		if len(expired) < staleBatch {
+			return
+		}
+	}
+}
+
+// Reconcile checks every platform that keeps seat state in Mongo against
+// bookings and holds and fixes what drifted. The report covers the fixes
+// made even when a later platform fails.
+func (s *ReconcileService) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &ReconcileReport{Counters: map[string][]string{}}
+	claims, err := s.bookingService.ClaimedSeats(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	// Seats that are no longer unclaimed, or were not seen this pass, start
+	// their grace period over
+	seen := make(map[string]time.Time)
+	defer func() { s.unclaimedSince = seen }()
+	for _, name := range s.platforms.Names() {
+		p, err := s.platforms.Get(name)
+		if err != nil {
+			return report, err
+		}
+		r, ok := p.(platform.Reconciler)
+		if !ok {
+			continue
+		}
+		if err := s.reconcilePlatform(ctx, name, r, claims, seen, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (s *ReconcileService) reconcilePlatform(ctx context.Context, name string, r platform.Reconciler, claims *SeatClaims, seen map[string]time.Time, report *ReconcileReport) error {
+	unavailable, err := r.UnavailableSeats(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Unavailable seats no booking or active hold claims go back on sale
+	// after their grace period. Inventory with bookings of unknown platform
+	// is skipped, as those bookings may own any of its seats.
+	for inventoryID, seatIDs := range unavailable {
+		if claims.Unresolved[inventoryID] {
+			continue
+		}
+		booked := seatSet(claims.Reserved[inventoryID], claims.Open[inventoryID])
+		var candidates []string
+		for _, seatID := range seatIDs {
+			if !booked[seatID] {
+				candidates = append(candidates, seatID)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		held, err := s.holdService.HeldSeats(ctx, name, inventoryID)
+		if err != nil {
+			return err
+		}
+		var free []string
+		for _, seatID := range candidates {
+			if held[seatID] == "" {
+				free = append(free, seatID)
+			}
+		}
+		if err := s.releaseUnclaimed(ctx, r, name, inventoryID, free, seen, report); err != nil {
+			return err
+		}
+	}
+
+	// Reserved seats still on sale are taken off
+	for inventoryID, seatIDs := range claims.Reserved {
+		if claims.Platform[inventoryID] != name {
+			continue
+		}
+		marked := seatSet(unavailable[inventoryID])
+		var missing []string
+		for _, seatID := range seatIDs {
+			if !marked[seatID] {
+				missing = append(missing, seatID)
+			}
+		}
+		if err := s.markBooked(ctx, r, name, inventoryID, missing, report); err != nil {
+			return err
+		}
+	}
+
+	// Counters are recomputed last so they include the flags fixed above
+	counters, err := r.RecountSeats(ctx)
+	if len(counters) > 0 {
+		report.Counters[name] = counters
+		metrics.ReconcileFixes.WithLabelValues(name, "counter").Add(float64(len(counters)))
+		slog.WarnContext(ctx, "reconciled seat counters", "platform", name, "inventory_ids", counters)
+	}
+	return err
+}
+
+// releaseUnclaimed puts unclaimed seats back on sale once their grace period
+// is over and reports the ones still waiting
+func (s *ReconcileService) releaseUnclaimed(ctx context.Context, r platform.Reconciler, name, inventoryID string, seatIDs []string, seen map[string]time.Time, report *ReconcileReport) error {
+	if len(seatIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var waiting, due []string
+	for _, seatID := range seatIDs {
+		key := unclaimedKey(name, inventoryID, seatID)
+		since, ok := s.unclaimedSince[key]
+		if !ok {
+			since = now
+		}
+		if now.Sub(since) < unclaimedGrace {
+			seen[key] = since
+			waiting = append(waiting, seatID)
+		} else {
+			due = append(due, seatID)
+		}
+	}
+	if len(waiting) > 0 {
+		report.Unclaimed = append(report.Unclaimed, SeatFix{Platform: name, InventoryID: inventoryID, SeatIDs: waiting, Available: true})
+		slog.WarnContext(ctx, "unavailable seats have no booking", "platform", name, "inventory_id", inventoryID, "seats", waiting)
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	// A booking made since the claims were read keeps its seats
+	claimed, err := s.bookingService.ClaimedSeatsIn(ctx, inventoryID, due, claimingStatuses())
+	if err != nil {
+		return err
+	}
+	var release []string
+	for _, seatID := range due {
+		if !claimed[seatID] {
+			release = append(release, seatID)
+		}
+	}
+	if len(release) == 0 {
+		return nil
+	}
+	n, err := r.MarkSeats(ctx, inventoryID, release, true)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	report.Seats = append(report.Seats, SeatFix{Platform: name, InventoryID: inventoryID, SeatIDs: release, Available: true})
+	metrics.ReconcileFixes.WithLabelValues(name, "seat_unclaimed").Add(float64(n))
+	slog.WarnContext(ctx, "reconciled seat flags", "platform", name, "inventory_id", inventoryID, "seats", release, "available", true)
+	return nil
+}
+
+// markBooked takes reserved seats off sale and records the seats that were
+// wrong. Seats whose booking was cancelled or refunded since the claims
+// were read are left on sale.
+func (s *ReconcileService) markBooked(ctx context.Context, r platform.Reconciler, name, inventoryID string, seatIDs []string, report *ReconcileReport) error {
+	if len(seatIDs) == 0 {
+		return nil
+	}
+	reserved, err := s.bookingService.ClaimedSeatsIn(ctx, inventoryID, seatIDs, model.ReservedBookingStatuses)
+	if err != nil {
+		return err
+	}
+	var still []string
+	for _, seatID := range seatIDs {
+		if reserved[seatID] {
+			still = append(still, seatID)
+		}
+	}
+	if len(still) == 0 {
+		return nil
+	}
+	seatIDs = still
+
+	n, err := r.MarkSeats(ctx, inventoryID, seatIDs, false)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	report.Seats = append(report.Seats, SeatFix{Platform: name, InventoryID: inventoryID, SeatIDs: seatIDs, Available: false})
+	metrics.ReconcileFixes.WithLabelValues(name, "seat_booked").Add(float64(n))
+	slog.WarnContext(ctx, "reconciled seat flags", "platform", name, "inventory_id", inventoryID, "seats", seatIDs, "available", false)
+	return nil
+}
+
+// unclaimedKey identifies a seat in ReconcileService.unclaimedSince
+func unclaimedKey(platformName, inventoryID, seatID string) string {
+	return platformName + ":" + inventoryID + ":" + seatID
+}
+
+// seatSet builds a lookup set from seat ID lists
+func seatSet(lists ...[]string) map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range lists {
+		for _, id := range list {
+			set[id] = true
+		}
+	}
+	return set
+}
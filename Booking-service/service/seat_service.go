@@ -3,141 +3,114 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
-	"time"
 
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/ansh0014/booking/hold"
 	"github.com/ansh0014/booking/model"
-	"github.com/go-redis/redis/v8"
+	"github.com/ansh0014/booking/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SeatService provides seat-related functionality
+var tracer = tracing.Tracer("github.com/ansh0014/booking/service")
+
+// SeatService dispatches the generic seat endpoints to the registered platforms
 type SeatService struct {
-	redisClient      *redis.Client
-	platformServices map[string]interface{}
+	platforms *platform.Registry
 }
 
 // NewSeatService creates a new seat service
-func NewSeatService(redisClient *redis.Client, platformServices map[string]interface{}) *SeatService {
-	return &SeatService{
-		redisClient:      redisClient,
-		platformServices: platformServices,
-	}
+func NewSeatService(platforms *platform.Registry) *SeatService {
+	return &SeatService{platforms: platforms}
 }
 
-// LockSeats locks seats for a specific platform and ID
-func (s *SeatService) LockSeats(ctx context.Context, req model.SeatLockRequest, userID string) error {
-	if req.Platform == "" {
-		return errors.New("platform is required")
-	}
-
-	if req.PlatformID == "" {
-		return errors.New("platform ID is required")
-	}
-
-	if len(req.SeatIDs) == 0 {
-		return errors.New("at least one seat must be selected")
-	}
-
-	// Create a key format based on platform and ID
-	keyPrefix := fmt.Sprintf("%s:%s", req.Platform, req.PlatformID)
+// Platform returns the registered platform for name
+func (s *SeatService) Platform(name string) (platform.Platform, error) {
+	return s.platforms.Get(name)
+}
 
-	// Check if seats are already locked or booked
-	for _, seatID := range req.SeatIDs {
-		key := fmt.Sprintf("%s:seat:%s", keyPrefix, seatID)
-		val, err := s.redisClient.Get(ctx, key).Result()
-		if err == nil && val != "" && val != userID {
-			return errors.New("one or more seats are already locked")
-		}
+// LockSeats holds seats through the request's platform
+func (s *SeatService) LockSeats(ctx context.Context, req model.SeatLockRequest, userID string) (*hold.SeatHold, error) {
+	p, err := s.resolve(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Lock all seats
-	for _, seatID := range req.SeatIDs {
-		key := fmt.Sprintf("%s:seat:%s", keyPrefix, seatID)
-		err := s.redisClient.Set(ctx, key, userID, 5*time.Minute).Err()
-		if err != nil {
-			// Clean up on error
-			s.ReleaseSeats(ctx, req, userID)
-			return err
-		}
-	}
+	ctx, span := tracer.Start(ctx, "SeatService.LockSeats", trace.WithAttributes(
+		attribute.String("booking.platform", req.Platform),
+		attribute.String("booking.platform_id", req.PlatformID),
+		attribute.Int("booking.seat_count", len(req.SeatIDs)),
+	))
+	defer span.End()
 
-	return nil
+	h, err := p.Lock(ctx, req.PlatformID, req.SeatIDs, userID)
+	tracing.RecordError(span, err)
+	return h, err
 }
 
-// ReleaseSeats unlocks seats for a specific platform and ID
+// ReleaseSeats frees the seats userID holds through the request's platform
 func (s *SeatService) ReleaseSeats(ctx context.Context, req model.SeatLockRequest, userID string) error {
-	if req.Platform == "" {
-		return errors.New("platform is required")
+	p, err := s.resolve(req)
+	if err != nil {
+		return err
 	}
+	return p.Release(ctx, req.PlatformID, req.SeatIDs, userID)
+}
 
-	if req.PlatformID == "" {
-		return errors.New("platform ID is required")
+// UnsellSeats puts a cancelled or refunded booking's seats back on sale
+// through the request's platform
+func (s *SeatService) UnsellSeats(ctx context.Context, req model.SeatLockRequest) error {
+	p, err := s.resolve(req)
+	if err != nil {
+		return err
 	}
+	return p.Unsell(ctx, req.PlatformID, req.SeatIDs)
+}
 
-	if len(req.SeatIDs) == 0 {
-		return errors.New("at least one seat must be selected")
+// ConfirmSeats permanently reserves seats held by userID's hold holdID
+// through the request's platform
+func (s *SeatService) ConfirmSeats(ctx context.Context, req model.SeatLockRequest, userID, holdID string) error {
+	p, err := s.resolve(req)
+	if err != nil {
+		return err
 	}
+	return p.Confirm(ctx, req.PlatformID, req.SeatIDs, userID, holdID)
+}
 
-	// Create a key format based on platform and ID
-	keyPrefix := fmt.Sprintf("%s:%s", req.Platform, req.PlatformID)
-
-	// Release seats
-	for _, seatID := range req.SeatIDs {
-		key := fmt.Sprintf("%s:seat:%s", keyPrefix, seatID)
-		// Only release if locked by this user
-		val, err := s.redisClient.Get(ctx, key).Result()
-		if err == nil && val == userID {
-			s.redisClient.Del(ctx, key)
-		}
+// Quote prices seats at the request's platform's listed prices
+func (s *SeatService) Quote(ctx context.Context, req model.SeatLockRequest) (*platform.Quote, error) {
+	p, err := s.resolve(req)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return p.Quote(ctx, req.PlatformID, req.SeatIDs)
 }
 
-// GetAvailability returns all seat statuses for a specific show ID
-func (s *SeatService) GetAvailability(ctx context.Context, showID string) (map[string]string, error) {
-	if showID == "" {
-		return nil, errors.New("show ID is required")
+// GetAvailability returns the seat map of one inventory item
+func (s *SeatService) GetAvailability(ctx context.Context, platformName, inventoryID string) (*platform.Inventory, error) {
+	if inventoryID == "" {
+		return nil, errors.New("platform ID is required")
 	}
-
-	pattern := fmt.Sprintf("*:%s:seat:*", showID)
-	keys, err := s.redisClient.Keys(ctx, pattern).Result()
+	p, err := s.platforms.Get(platformName)
 	if err != nil {
 		return nil, err
 	}
+	return p.Inventory(ctx, inventoryID)
+}
 
-	result := make(map[string]string)
-	for _, key := range keys {
-		// Extract seat ID from key
-		parts := splitKey(key)
-		if len(parts) >= 4 {
-			seatID := parts[len(parts)-1]
-			userID, err := s.redisClient.Get(ctx, key).Result()
-			if err == nil {
-				result[seatID] = userID
-			}
-		}
+// resolve validates req and looks up its platform
+func (s *SeatService) resolve(req model.SeatLockRequest) (platform.Platform, error) {
+	if req.Platform == "" {
+		return nil, errors.New("platform is required")
 	}
 
-	return result, nil
-}
-
-// Helper function to split a Redis key
-func splitKey(key string) []string {
-	var result []string
-	var current string
-
-	for _, r := range key {
-		if r == ':' {
-			result = append(result, current)
-			current = ""
-		} else {
-			current += string(r)
-		}
+	if req.PlatformID == "" {
+		return nil, errors.New("platform ID is required")
 	}
-	if current != "" {
-		result = append(result, current)
+
+	if len(req.SeatIDs) == 0 {
+		return nil, errors.New("at least one seat must be selected")
 	}
 
-	return result
+	return s.platforms.Get(req.Platform)
 }
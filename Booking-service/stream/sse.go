@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ansh0014/booking/utils"
+	"github.com/gorilla/mux"
+)
+
+// heartbeat keeps idle connections open through proxies.
+const heartbeat = 15 * time.Second
+
+// SeatsHandler serves GET /api/platforms/{platform}/{id}/seats/stream as
+// Server-Sent Events. Each message is a SeatEvent with the event type as the
+// SSE event name.
+func (h *Hub) SeatsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	platform, inventoryID := vars["platform"], vars["id"]
+	if !h.platforms.Has(platform) {
+		utils.NotFoundResponse(w, "Unknown platform")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// the server's WriteTimeout would otherwise cut the stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		utils.ServerErrorResponse(w, "Streaming unsupported")
+		return
+	}
+
+	events, cancel := h.Subscribe(platform, inventoryID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\nevent: ready\ndata: {\"platform\":%q,\"inventory_id\":%q}\n\n", platform, inventoryID)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package stream fans seat availability changes out to live subscribers.
+//
+// Every replica publishes seat deltas to a Redis channel per inventory item
+// (show, flight, train or event). Each replica runs one Hub that pattern-
+// subscribes to all seat channels and forwards events to its local SSE
+// clients, so a client sees changes made through any replica.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	platform "github.com/ansh0014/booking/Platform"
+	"github.com/go-redis/redis/v8"
+)
+
+// Seat event types
+const (
+	SeatsLocked    = "locked"
+	SeatsReleased  = "released"
+	SeatsConfirmed = "confirmed"
+	SeatsExpired   = "expired"
+)
+
+const channelPrefix = "seats:"
+
+// SeatEvent is a delta for one inventory item.
+type SeatEvent struct {
+	Type        string    `json:"type"`
+	Platform    string    `json:"platform"`
+	InventoryID string    `json:"inventory_id"`
+	SeatIDs     []string  `json:"seat_ids"`
+	At          time.Time `json:"at"`
+}
+
+// Channel returns the Redis channel carrying events for an inventory item.
+func Channel(platform, inventoryID string) string {
+	return channelPrefix + platform + ":" + inventoryID
+}
+
+// Publish sends ev to every replica. Failures are logged and otherwise
+// ignored: the stream is best-effort and clients can always re-read seats.
+func Publish(ctx context.Context, rdb *redis.Client, ev SeatEvent) {
+	if rdb == nil || len(ev.SeatIDs) == 0 {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if err := rdb.Publish(ctx, Channel(ev.Platform, ev.InventoryID), data).Err(); err != nil {
+		slog.WarnContext(ctx, "seat event publish failed", "channel", Channel(ev.Platform, ev.InventoryID), "error", err.Error())
+	}
+}
+
+// Hub delivers events received from Redis to local subscribers.
+type Hub struct {
+	rdb       *redis.Client
+	platforms *platform.Registry
+
+	mu   sync.Mutex
+	subs map[string]map[chan SeatEvent]struct{}
+}
+
+// NewHub creates a hub. Call Run to start receiving events.
+func NewHub(rdb *redis.Client, platforms *platform.Registry) *Hub {
+	return &Hub{rdb: rdb, platforms: platforms, subs: map[string]map[chan SeatEvent]struct{}{}}
+}
+
+// Run consumes the Redis pattern subscription until ctx is cancelled. On
+// return every subscriber is closed, ending its stream so the client
+// reconnects elsewhere.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.closeAll()
+
+	pubsub := h.rdb.PSubscribe(ctx, channelPrefix+"*")
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var ev SeatEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				continue
+			}
+			h.dispatch(strings.TrimPrefix(msg.Channel, channelPrefix), ev)
+		}
+	}
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for topic, subs := range h.subs {
+		for sub := range subs {
+			close(sub)
+		}
+		delete(h.subs, topic)
+	}
+}
+
+func (h *Hub) dispatch(topic string, ev SeatEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[topic] {
+		select {
+		case sub <- ev:
+		default:
+			// slow client: drop it rather than block every other subscriber
+			delete(h.subs[topic], sub)
+			close(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for an inventory item. The returned
+// channel is closed when cancel is called or the subscriber falls behind.
+func (h *Hub) Subscribe(platform, inventoryID string) (<-chan SeatEvent, func()) {
+	topic := platform + ":" + inventoryID
+	sub := make(chan SeatEvent, 32)
+
+	h.mu.Lock()
+	if h.subs[topic] == nil {
+		h.subs[topic] = map[chan SeatEvent]struct{}{}
+	}
+	h.subs[topic][sub] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[topic][sub]; ok {
+			delete(h.subs[topic], sub)
+			close(sub)
+		}
+		if len(h.subs[topic]) == 0 {
+			delete(h.subs, topic)
+		}
+	}
+	return sub, cancel
+}
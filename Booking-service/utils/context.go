@@ -2,9 +2,14 @@ package utils
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 )
 
+// RequestIDHeader is the header used to correlate a request across services
+const RequestIDHeader = "X-Request-ID"
+
 // GetUserFromContext extracts the user ID from the context
 func GetUserFromContext(ctx context.Context) (string, error) {
 	userID, ok := ctx.Value("userID").(string)
@@ -13,3 +18,23 @@ func GetUserFromContext(ctx context.Context) (string, error) {
 	}
 	return userID, nil
 }
+
+// WithRequestID stores the request ID in the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, "requestID", requestID)
+}
+
+// GetRequestIDFromContext returns the request ID stored in the context, or ""
+func GetRequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value("requestID").(string)
+	return requestID
+}
+
+// NewRequestID generates a random request ID for requests that arrive without one
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand unavailable: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
@@ -131,13 +131,21 @@ func ValidationErrorResponse(w http.ResponseWriter, errors []ValidationError) {
 
 // ReadJSON reads the request body into the provided destination struct
 func ReadJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	return readJSON(w, r, dst, true)
+}
+
+// readJSON decodes a single JSON object of at most 1MB. Unknown fields are
+// rejected when strict is set.
+func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}, strict bool) error {
 	// Limit the size of the request body to 1MB
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
 
 	// Initialize the json.Decoder
 	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
+	if strict {
+		dec.DisallowUnknownFields()
+	}
 
 	// Decode the request body into the target destination
 	err := dec.Decode(dst)
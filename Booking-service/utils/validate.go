@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// report fields by their JSON names, as clients send them
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks dst's `validate` struct tags and returns one error per
+// failing field, or nil when dst is valid.
+func Validate(dst interface{}) []ValidationError {
+	err := validate.Struct(dst)
+	if err == nil {
+		return nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []ValidationError{{Field: "", Message: err.Error()}}
+	}
+	out := make([]ValidationError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		out = append(out, ValidationError{Field: fe.Field(), Message: validationMessage(fe)})
+	}
+	return out
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "len":
+		return fmt.Sprintf("must have length %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Array {
+			return fmt.Sprintf("must contain at least %s item(s)", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Array {
+			return fmt.Sprintf("must contain at most %s item(s)", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "unique":
+		return "must not contain duplicates"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %q check", fe.Tag())
+	}
+}
+
+// DecodeAndValidate reads a JSON body into dst and validates it. Unknown
+// fields are ignored so older clients keep working. On failure it writes the
+// error response and returns false.
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := readJSON(w, r, dst, false); err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if errs := Validate(dst); len(errs) > 0 {
+		ValidationErrorResponse(w, errs)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/ansh0014/booking/model"
+)
+
+func TestValidateSeatIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		seats   []string
+		field   string
+		message string
+	}{
+		{name: "valid", seats: []string{"s1", "s2"}},
+		{name: "missing", seats: nil, field: "seat_ids", message: "is required"},
+		{name: "empty list", seats: []string{}, field: "seat_ids", message: "must contain at least 1 item(s)"},
+		{name: "too many", seats: []string{"s1", "s2", "s3", "s4", "s5", "s6", "s7", "s8", "s9", "s10", "s11"}, field: "seat_ids", message: "must contain at most 10 item(s)"},
+		{name: "duplicate", seats: []string{"s1", "s2", "s1"}, field: "seat_ids", message: "must not contain duplicates"},
+		{name: "empty id", seats: []string{"s1", ""}, field: "seat_ids[1]", message: "is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := map[string]interface{}{
+				"booking": model.BookingRequest{Platform: "movie", PlatformID: "show-1", SeatIDs: tt.seats},
+				"lock":    model.SeatLockRequest{Platform: "movie", PlatformID: "show-1", SeatIDs: tt.seats},
+				"quote":   model.QuoteRequest{Platform: "movie", PlatformID: "show-1", SeatIDs: tt.seats},
+			}
+			for kind, req := range requests {
+				errs := Validate(req)
+				if tt.field == "" {
+					if errs != nil {
+						t.Errorf("%s request: unexpected errors %v", kind, errs)
+					}
+					continue
+				}
+				if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Message != tt.message {
+					t.Errorf("%s request: got %v, want %s %s", kind, errs, tt.field, tt.message)
+				}
+			}
+		})
+	}
+}
@@ -5,8 +5,10 @@ import (
     "os"
     "time"
 
+    "github.com/ansh0014/payment/metrics"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
+    "go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var (
@@ -19,7 +21,9 @@ func InitMongo() error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGODB_URI")))
+    client, err := mongo.Connect(ctx, options.Client().
+        ApplyURI(os.Getenv("MONGODB_URI")).
+        SetMonitor(metrics.MongoMonitor(otelmongo.NewMonitor())))
     if err != nil {
         return err
     }
@@ -34,6 +38,14 @@ func InitMongo() error {
     return nil
 }
 
+// Close releases the Mongo connection.
+func Close(ctx context.Context) error {
+    if MongoClient == nil {
+        return nil
+    }
+    return MongoClient.Disconnect(ctx)
+}
+
 // PaymentGateway configuration
 type PaymentGatewayConfig struct {
     Name      string
@@ -51,4 +63,21 @@ func GetPaymentGatewayConfig() PaymentGatewayConfig {
         BaseURL:   os.Getenv("PAYMENT_GATEWAY_BASE_URL"),
         IsTest:    os.Getenv("PAYMENT_GATEWAY_MODE") != "production",
     }
-}
\ No newline at end of file
+}
+
+// BookingServiceConfig is how payment outcomes reach the booking service
+type BookingServiceConfig struct {
+    URL           string
+    InternalToken string
+}
+
+func GetBookingServiceConfig() BookingServiceConfig {
+    url := os.Getenv("BOOKING_SERVICE_URL")
+    if url == "" {
+        url = "http://localhost:8002"
+    }
+    return BookingServiceConfig{
+        URL:           url,
+        InternalToken: os.Getenv("BOOKING_INTERNAL_TOKEN"),
+    }
+}
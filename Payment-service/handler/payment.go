@@ -1,7 +1,6 @@
 package handler
 
 import (
-    "encoding/json"
     "net/http"
 
     "github.com/ansh0014/payment/model"
@@ -13,12 +12,11 @@ import (
 // CreatePaymentHandler handles payment initialization
 func CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
     var req model.CreatePaymentRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        utils.BadRequestResponse(w, "Invalid request", nil)
+    if !utils.DecodeAndValidate(w, r, &req) {
         return
     }
 
-    payment, err := service.CreatePayment(&req)
+    payment, err := service.CreatePayment(r.Context(), &req)
     if err != nil {
         utils.ServerErrorResponse(w, "Failed to create payment: "+err.Error())
         return
@@ -38,7 +36,7 @@ func GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     paymentID := vars["id"]
 
-    payment, err := service.GetPayment(paymentID)
+    payment, err := service.GetPayment(r.Context(), paymentID)
     if err != nil {
         utils.NotFoundResponse(w, "Payment not found")
         return
@@ -50,12 +48,11 @@ func GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
 // RefundPaymentHandler processes refunds
 func RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
     var req model.RefundRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        utils.BadRequestResponse(w, "Invalid request", nil)
+    if !utils.DecodeAndValidate(w, r, &req) {
         return
     }
 
-    err := service.RefundPayment(&req)
+    err := service.RefundPayment(r.Context(), &req)
     if err != nil {
         utils.BadRequestResponse(w, err.Error(), nil)
         return
@@ -67,12 +64,11 @@ func RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
 // VerifyPaymentHandler checks payment status
 func VerifyPaymentHandler(w http.ResponseWriter, r *http.Request) {
     var req model.VerifyPaymentRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        utils.BadRequestResponse(w, "Invalid request", nil)
+    if !utils.DecodeAndValidate(w, r, &req) {
         return
     }
 
-    payment, err := service.GetPayment(req.PaymentID)
+    payment, err := service.GetPayment(r.Context(), req.PaymentID)
     if err != nil {
         utils.NotFoundResponse(w, "Payment not found")
         return
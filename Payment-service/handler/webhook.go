@@ -18,7 +18,7 @@ func WebhookHandler(w http.ResponseWriter, r *http.Request) {
     }
 
     // Process the webhook
-    err := service.ProcessWebhook(&webhook)
+    err := service.ProcessWebhook(r.Context(), &webhook)
     if err != nil {
         utils.ServerErrorResponse(w, "Failed to process webhook: "+err.Error())
         return
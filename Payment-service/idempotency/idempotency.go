@@ -0,0 +1,225 @@
+// Package idempotency makes retried payment creation safe.
+//
+// The first response for an (user, Idempotency-Key) pair is stored in a Mongo
+// collection with a TTL index and replayed for later requests with the same
+// key. Reusing a key with a different body is rejected with 422; a retry that
+// arrives while the first request is still running gets 409.
+package idempotency
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/ansh0014/payment/utils"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Header names
+const (
+    Header         = "Idempotency-Key"
+    ReplayedHeader = "Idempotent-Replayed"
+    userHeader     = "X-User-ID"
+)
+
+// DefaultTTL is how long a stored response can be replayed.
+const DefaultTTL = 24 * time.Hour
+
+// lease is how long an in-progress claim blocks retries. A request that
+// dies without answering (a crash, a lost replica) frees its key after it.
+const lease = time.Minute
+
+// maxKeyLen bounds client-chosen keys.
+const maxKeyLen = 255
+
+// maxBody bounds the request body read for fingerprinting.
+const maxBody = 1 << 20
+
+const (
+    stateInProgress = "in_progress"
+    stateDone       = "done"
+)
+
+type record struct {
+    ID          string    `bson:"_id"`
+    Fingerprint string    `bson:"fingerprint"`
+    State       string    `bson:"state"`
+    Status      int       `bson:"status,omitempty"`
+    ContentType string    `bson:"content_type,omitempty"`
+    Body        []byte    `bson:"body,omitempty"`
+    CreatedAt   time.Time `bson:"created_at"`
+}
+
+// Guard stores and replays responses.
+type Guard struct {
+    coll *mongo.Collection
+    ttl  time.Duration
+}
+
+// NewGuard returns a guard keeping responses in coll for ttl. It creates the
+// TTL index that expires old records.
+func NewGuard(coll *mongo.Collection, ttl time.Duration) *Guard {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "created_at", Value: 1}},
+        Options: options.Index().SetExpireAfterSeconds(int32(ttl.Seconds())),
+    })
+    if err != nil {
+        slog.Warn("idempotency TTL index", "error", err.Error())
+    }
+    return &Guard{coll: coll, ttl: ttl}
+}
+
+// Middleware applies idempotency to requests carrying an Idempotency-Key.
+// Requests without the header pass straight through.
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get(Header)
+        if key == "" {
+            next.ServeHTTP(w, r)
+            return
+        }
+        if len(key) > maxKeyLen {
+            utils.BadRequestResponse(w, "Idempotency-Key is too long", nil)
+            return
+        }
+
+        body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+        if err != nil {
+            utils.BadRequestResponse(w, "Invalid request body", nil)
+            return
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+
+        ctx := r.Context()
+        id := r.Header.Get(userHeader) + ":" + key
+        fp := fingerprint(r, body)
+
+        claimed, existing, err := g.claim(ctx, id, fp)
+        if err != nil {
+            // fail open: better a possible duplicate than a failed payment
+            slog.WarnContext(ctx, "idempotency store unavailable", "error", err.Error())
+            next.ServeHTTP(w, r)
+            return
+        }
+        if !claimed {
+            replay(w, existing, fp)
+            return
+        }
+
+        // A request that ends in a server error or a panic has nothing to
+        // replay; dropping its claim lets the client retry with the same key
+        ctx = context.WithoutCancel(ctx)
+        replayable := false
+        defer func() {
+            if !replayable {
+                g.coll.DeleteOne(ctx, bson.M{"_id": id})
+            }
+        }()
+
+        rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+        next.ServeHTTP(rec, r)
+
+        if rec.status >= 500 {
+            return
+        }
+        replayable = true
+        // the stored response is kept for the full TTL from now
+        _, err = g.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+            "state":        stateDone,
+            "created_at":   time.Now(),
+            "status":       rec.status,
+            "content_type": rec.Header().Get("Content-Type"),
+            "body":         rec.body.Bytes(),
+        }})
+        if err != nil {
+            slog.WarnContext(ctx, "idempotency store failed", "error", err.Error())
+        }
+    })
+}
+
+// claim inserts an in-progress record for id. When one already exists it is
+// returned instead, unless it has outlived its lifetime, in which case it is
+// replaced: the lease for an in-progress claim, the TTL for a stored response
+// (Mongo expires documents lazily).
+func (g *Guard) claim(ctx context.Context, id, fp string) (bool, *record, error) {
+    for attempt := 0; attempt < 2; attempt++ {
+        _, err := g.coll.InsertOne(ctx, record{ID: id, Fingerprint: fp, State: stateInProgress, CreatedAt: time.Now()})
+        if err == nil {
+            return true, nil, nil
+        }
+        if !mongo.IsDuplicateKeyError(err) {
+            return false, nil, err
+        }
+
+        var existing record
+        err = g.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&existing)
+        if err == mongo.ErrNoDocuments {
+            continue
+        }
+        if err != nil {
+            return false, nil, err
+        }
+        lifetime := g.ttl
+        if existing.State != stateDone {
+            lifetime = lease
+        }
+        if time.Since(existing.CreatedAt) < lifetime {
+            return false, &existing, nil
+        }
+        if _, err := g.coll.DeleteOne(ctx, bson.M{"_id": id, "created_at": existing.CreatedAt}); err != nil {
+            return false, nil, err
+        }
+    }
+    return false, nil, mongo.ErrNoDocuments
+}
+
+func replay(w http.ResponseWriter, rec *record, fp string) {
+    if rec.Fingerprint != fp {
+        utils.ErrorResponse(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity, nil)
+        return
+    }
+    if rec.State != stateDone {
+        utils.ConflictResponse(w, "A request with this Idempotency-Key is already in progress")
+        return
+    }
+    if rec.ContentType != "" {
+        w.Header().Set("Content-Type", rec.ContentType)
+    }
+    w.Header().Set(ReplayedHeader, "true")
+    w.WriteHeader(rec.Status)
+    w.Write(rec.Body)
+}
+
+// fingerprint identifies the request a key was first used with.
+func fingerprint(r *http.Request, body []byte) string {
+    h := sha256.New()
+    io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+    h.Write(body)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response through while keeping a copy.
+type recorder struct {
+    http.ResponseWriter
+    status int
+    body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+    r.status = code
+    r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+    r.body.Write(b)
+    return r.ResponseWriter.Write(b)
+}
@@ -1,19 +1,40 @@
 package main
 
 import (
+    "context"
     "log"
+    "log/slog"
     "net/http"
     "os"
+    "os/signal"
+    "syscall"
+    "time"
 
     "github.com/ansh0014/payment/config"
     "github.com/ansh0014/payment/router"
+    "github.com/ansh0014/payment/tracing"
     "github.com/joho/godotenv"
 )
 
 func main() {
+    slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
     // Load environment variables
     godotenv.Load("D:\\Ticket-System\\Ticket-system\\Payment-service\\.env")
 
+    // Booking-service refuses payment events without it, so no paid
+    // booking would ever be confirmed or refunded
+    if config.GetBookingServiceConfig().InternalToken == "" {
+        log.Fatal("BOOKING_INTERNAL_TOKEN is required")
+    }
+
+    // Initialize tracing
+    shutdownTracing, err := tracing.Init(context.Background())
+    if err != nil {
+        log.Fatalf("Tracing setup failed: %v", err)
+    }
+    defer shutdownTracing(context.Background())
+
     // Initialize MongoDB
     if err := config.InitMongo(); err != nil {
         log.Fatalf("Failed to connect to MongoDB: %v", err)
@@ -29,6 +50,35 @@ func main() {
         port = "8003" // Default port for Payment Service
     }
 
-    log.Printf("Payment service starting on port %s...", port)
-    log.Fatal(http.ListenAndServe(":"+port, r))
+    server := &http.Server{
+        Addr:         ":" + port,
+        Handler:      r,
+        ReadTimeout:  15 * time.Second,
+        WriteTimeout: 30 * time.Second, // provider calls can be slow
+        IdleTimeout:  60 * time.Second,
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    go func() {
+        log.Printf("Payment service starting on port %s...", port)
+        if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Fatal(err)
+        }
+    }()
+
+    <-ctx.Done()
+    stop()
+
+    // Drain in-flight payments before closing Mongo
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+    log.Println("Shutting down payment service...")
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        log.Printf("Forced shutdown: %v", err)
+    }
+    if err := config.Close(shutdownCtx); err != nil {
+        log.Printf("Closing MongoDB: %v", err)
+    }
 }
\ No newline at end of file
@@ -0,0 +1,88 @@
+// Package metrics defines the Prometheus metrics exported by the payment
+// service on /metrics.
+package metrics
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    "go.mongodb.org/mongo-driver/event"
+)
+
+const namespace = "payment"
+
+var (
+    // HTTPRequests counts handled requests by method, route template and status
+    HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "http_requests_total",
+        Help:      "HTTP requests handled, by method, route and status.",
+    }, []string{"method", "route", "status"})
+
+    // HTTPDuration observes request latency by method, route template and status
+    HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "http_request_duration_seconds",
+        Help:      "HTTP request latency, by method, route and status.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"method", "route", "status"})
+
+    // MongoDuration observes MongoDB command latency
+    MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "mongo_command_duration_seconds",
+        Help:      "MongoDB command latency, by command and outcome.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"command", "outcome"})
+
+    // Payments counts payment status changes, by resulting status
+    Payments = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "payments_total",
+        Help:      "Payments reaching each status.",
+    }, []string{"status"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format
+func Handler() http.Handler {
+    return promhttp.Handler()
+}
+
+// ObserveHTTP records one handled request. An empty route means no route matched.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+    if route == "" {
+        route = "unmatched"
+    }
+    code := strconv.Itoa(status)
+    HTTPRequests.WithLabelValues(method, route, code).Inc()
+    HTTPDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// MongoMonitor returns a command monitor that records MongoDB command latency
+// and then forwards each event to next, if any
+func MongoMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+    return &event.CommandMonitor{
+        Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+            if next != nil && next.Started != nil {
+                next.Started(ctx, e)
+            }
+        },
+        Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+            MongoDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+            if next != nil && next.Succeeded != nil {
+                next.Succeeded(ctx, e)
+            }
+        },
+        Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+            MongoDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+            if next != nil && next.Failed != nil {
+                next.Failed(ctx, e)
+            }
+        },
+    }
+}
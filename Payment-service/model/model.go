@@ -55,7 +55,7 @@ type CreatePaymentRequest struct {
     BookingID   string  `json:"booking_id" validate:"required"`
     UserID      string  `json:"user_id" validate:"required"`
     Amount      float64 `json:"amount" validate:"required,gt=0"`
-    Currency    string  `json:"currency" validate:"required"`
+    Currency    string  `json:"currency" validate:"required,len=3"`
     CallbackURL string  `json:"callback_url"`
 }
 
@@ -82,4 +82,22 @@ type RefundRequest struct {
     PaymentID string  `json:"payment_id" validate:"required"`
     Amount    float64 `json:"amount" validate:"required,gt=0"`
     Reason    string  `json:"reason"`
-}
\ No newline at end of file
+}
+
+// BookingPaymentEvent reports a payment status change to the booking service
+type BookingPaymentEvent struct {
+    PaymentID string        `json:"payment_id"`
+    BookingID string        `json:"booking_id"`
+    Status    PaymentStatus `json:"status"`
+    Amount    float64       `json:"amount"`
+    Currency  string        `json:"currency,omitempty"`
+}
+
+// BookingOutcome is the booking service's answer to a BookingPaymentEvent.
+// Refund is set when the booking cannot keep the payment.
+type BookingOutcome struct {
+    BookingID     string `json:"booking_id"`
+    BookingStatus string `json:"booking_status"`
+    Refund        bool   `json:"refund"`
+    Reason        string `json:"reason,omitempty"`
+}
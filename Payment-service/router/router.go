@@ -3,16 +3,26 @@ package router
 import (
 	"net/http"
 
+	"github.com/ansh0014/payment/config"
 	"github.com/ansh0014/payment/handler"
+	"github.com/ansh0014/payment/health"
+	"github.com/ansh0014/payment/idempotency"
+	"github.com/ansh0014/payment/metrics"
+	"github.com/ansh0014/payment/middleware"
+	"github.com/ansh0014/payment/tracing"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 // SetupRoutes configures all API routes for the payment service
 func SetupRoutes() http.Handler {
 	r := mux.NewRouter()
+	r.Use(otelmux.Middleware(tracing.ServiceName))
 
 	// Payment endpoints
-	r.HandleFunc("/api/payments", handler.CreatePaymentHandler).Methods("POST")
+	// retried creates replay the first response instead of charging twice
+	idem := idempotency.NewGuard(config.MongoDB.Collection("idempotency_keys"), idempotency.DefaultTTL)
+	r.Handle("/api/payments", idem.Middleware(http.HandlerFunc(handler.CreatePaymentHandler))).Methods("POST")
 	r.HandleFunc("/api/payments/{id}", handler.GetPaymentHandler).Methods("GET")
 	r.HandleFunc("/api/payments/refund", handler.RefundPaymentHandler).Methods("POST")
 	r.HandleFunc("/api/payments/verify", handler.VerifyPaymentHandler).Methods("POST")
@@ -27,30 +37,19 @@ func SetupRoutes() http.Handler {
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	// Liveness and dependency-aware readiness
+	checker := health.New(tracing.ServiceName).Add("mongo", health.Mongo(config.MongoClient))
+	r.HandleFunc("/health/live", checker.Live).Methods("GET")
+	r.HandleFunc("/health/ready", checker.Ready).Methods("GET")
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// Simple root endpoint
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Payment Service"))
 	}).Methods("GET")
 
-	// CORS middleware
-	return addMiddleware(r)
-}
-
-// addMiddleware adds CORS and other middleware to the router
-func addMiddleware(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Call the original handler
-		handler.ServeHTTP(w, r)
-	})
+	// Request ID and access log middleware; CORS is handled by the gateway
+	return middleware.RequestID(middleware.Logging(r))
 }
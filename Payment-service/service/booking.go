@@ -0,0 +1,124 @@
+package service
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/ansh0014/payment/config"
+    "github.com/ansh0014/payment/model"
+    "github.com/ansh0014/payment/utils"
+)
+
+// bookingNotifyAttempts bounds how often one payment event is sent to the
+// booking service before giving up
+const bookingNotifyAttempts = 3
+
+// bookingClient forwards the request ID and trace context to the booking service
+var bookingClient = utils.NewHTTPClient(5 * time.Second)
+
+// errRetryable marks booking service responses worth sending again
+type errRetryable struct{ err error }
+
+func (e errRetryable) Error() string { return e.err.Error() }
+
+// notifyBookingService reports a payment status change to the booking
+// service. A completed payment the booking can no longer use, e.g. because
+// its seat hold expired first, is refunded straight away.
+func notifyBookingService(ctx context.Context, payment *model.Payment, status model.PaymentStatus) error {
+    outcome, err := sendPaymentEvent(ctx, payment, status)
+    if err != nil {
+        slog.ErrorContext(ctx, "notifying booking service failed",
+            "request_id", utils.GetRequestIDFromContext(ctx),
+            "payment_id", payment.ID,
+            "booking_id", payment.BookingID,
+            "status", status,
+            "error", err.Error())
+        return err
+    }
+    slog.InfoContext(ctx, "booking service notified",
+        "request_id", utils.GetRequestIDFromContext(ctx),
+        "payment_id", payment.ID,
+        "booking_id", payment.BookingID,
+        "status", status,
+        "booking_status", outcome.BookingStatus,
+        "refund", outcome.Refund)
+
+    if !outcome.Refund || status != model.PaymentStatusCompleted {
+        return nil
+    }
+    slog.WarnContext(ctx, "refunding payment the booking cannot use",
+        "payment_id", payment.ID,
+        "booking_id", payment.BookingID,
+        "reason", outcome.Reason)
+    return RefundPayment(ctx, &model.RefundRequest{
+        PaymentID: payment.ID,
+        Amount:    payment.Amount,
+        Reason:    "booking not confirmed: " + outcome.Reason,
+    })
+}
+
+// sendPaymentEvent posts a payment event to the booking service, retrying
+// network errors, conflicts and server errors
+func sendPaymentEvent(ctx context.Context, payment *model.Payment, status model.PaymentStatus) (*model.BookingOutcome, error) {
+    body, err := json.Marshal(model.BookingPaymentEvent{
+        PaymentID: payment.ID,
+        BookingID: payment.BookingID,
+        Status:    status,
+        Amount:    payment.Amount,
+        Currency:  payment.Currency,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    for attempt := 1; ; attempt++ {
+        outcome, err := postPaymentEvent(ctx, body)
+        if _, retry := err.(errRetryable); !retry || attempt == bookingNotifyAttempts {
+            return outcome, err
+        }
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+        }
+    }
+}
+
+func postPaymentEvent(ctx context.Context, body []byte) (*model.BookingOutcome, error) {
+    cfg := config.GetBookingServiceConfig()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL+"/internal/payments/events", bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-Internal-Token", cfg.InternalToken)
+
+    resp, err := bookingClient.Do(req)
+    if err != nil {
+        return nil, errRetryable{err}
+    }
+    defer resp.Body.Close()
+
+    data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+    if resp.StatusCode != http.StatusOK {
+        err := fmt.Errorf("booking service answered %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+        if resp.StatusCode == http.StatusConflict || resp.StatusCode >= 500 {
+            return nil, errRetryable{err}
+        }
+        return nil, err
+    }
+
+    var envelope struct {
+        Data model.BookingOutcome `json:"data"`
+    }
+    if err := json.Unmarshal(data, &envelope); err != nil {
+        return nil, fmt.Errorf("decode booking service response: %w", err)
+    }
+    return &envelope.Data, nil
+}
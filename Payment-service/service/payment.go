@@ -7,14 +7,26 @@ import (
     "time"
 
     "github.com/ansh0014/payment/config"
+    "github.com/ansh0014/payment/metrics"
     "github.com/ansh0014/payment/model"
+    "github.com/ansh0014/payment/tracing"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("github.com/ansh0014/payment/service")
+
 // CreatePayment initializes a new payment
-func CreatePayment(req *model.CreatePaymentRequest) (*model.Payment, error) {
+func CreatePayment(ctx context.Context, req *model.CreatePaymentRequest) (*model.Payment, error) {
+    ctx, span := tracer.Start(ctx, "CreatePayment", trace.WithAttributes(
+        attribute.String("payment.booking_id", req.BookingID),
+        attribute.String("payment.currency", req.Currency),
+    ))
+    defer span.End()
+
     // Create a new payment record
     payment := &model.Payment{
         ID:          primitive.NewObjectID().Hex(),
@@ -29,8 +41,9 @@ func CreatePayment(req *model.CreatePaymentRequest) (*model.Payment, error) {
     }
 
     // Generate payment URL or gateway reference using payment provider
-    gatewayRef, paymentURL, err := createPaymentWithProvider(payment)
+    gatewayRef, paymentURL, err := createPaymentWithProvider(ctx, payment)
     if err != nil {
+        tracing.RecordError(span, err)
         return nil, err
     }
 
@@ -38,19 +51,26 @@ func CreatePayment(req *model.CreatePaymentRequest) (*model.Payment, error) {
     payment.PaymentURL = paymentURL
 
     // Insert payment into MongoDB
-    _, err = config.MongoDB.Collection("payments").InsertOne(context.Background(), payment)
+    _, err = config.MongoDB.Collection("payments").InsertOne(ctx, payment)
     if err != nil {
+        tracing.RecordError(span, err)
         return nil, err
     }
+    span.SetAttributes(attribute.String("payment.id", payment.ID))
+    metrics.Payments.WithLabelValues(string(payment.Status)).Inc()
+
+    // Best effort: the booking only waits for the payment, nothing is lost
+    // if it does not hear about it
+    notifyBookingService(ctx, payment, model.PaymentStatusPending)
 
     return payment, nil
 }
 
 // GetPayment retrieves a payment by ID
-func GetPayment(paymentID string) (*model.Payment, error) {
+func GetPayment(ctx context.Context, paymentID string) (*model.Payment, error) {
     var payment model.Payment
     err := config.MongoDB.Collection("payments").FindOne(
-        context.Background(),
+        ctx,
         bson.M{"_id": paymentID},
     ).Decode(&payment)
 
@@ -64,7 +84,7 @@ func GetPayment(paymentID string) (*model.Payment, error) {
 }
 
 // UpdatePaymentStatus updates the status of a payment
-func UpdatePaymentStatus(paymentID string, status model.PaymentStatus) error {
+func UpdatePaymentStatus(ctx context.Context, paymentID string, status model.PaymentStatus) error {
     update := bson.M{
         "$set": bson.M{
             "status":     status,
@@ -79,7 +99,7 @@ func UpdatePaymentStatus(paymentID string, status model.PaymentStatus) error {
     }
 
     _, err := config.MongoDB.Collection("payments").UpdateOne(
-        context.Background(),
+        ctx,
         bson.M{"_id": paymentID},
         update,
     )
@@ -87,25 +107,37 @@ func UpdatePaymentStatus(paymentID string, status model.PaymentStatus) error {
     if err != nil {
         return err
     }
+    metrics.Payments.WithLabelValues(string(status)).Inc()
 
-    // If payment completed or failed, notify the booking service
-    if status == model.PaymentStatusCompleted || status == model.PaymentStatusFailed {
-        payment, err := GetPayment(paymentID)
+    // Tell the booking service. Completed and failed payments decide the
+    // booking, so a failed notification is returned and the gateway
+    // redelivers the webhook; the booking service ignores repeats.
+    switch status {
+    case model.PaymentStatusCompleted, model.PaymentStatusFailed:
+        payment, err := GetPayment(ctx, paymentID)
+        if err != nil {
+            return err
+        }
+        return notifyBookingService(ctx, payment, status)
+    case model.PaymentStatusRefunded:
+        // The refund is done; a missed notification only leaves the
+        // booking's status behind
+        payment, err := GetPayment(ctx, paymentID)
         if err != nil {
             return err
         }
-        notifyBookingService(payment, string(status))
+        notifyBookingService(ctx, payment, status)
     }
 
     return nil
 }
 
 // ProcessWebhook processes payment gateway webhook events
-func ProcessWebhook(webhook *model.WebhookRequest) error {
+func ProcessWebhook(ctx context.Context, webhook *model.WebhookRequest) error {
     // Find payment by gateway reference
     var payment model.Payment
     err := config.MongoDB.Collection("payments").FindOne(
-        context.Background(),
+        ctx,
         bson.M{"gateway_reference": webhook.GatewayReference},
     ).Decode(&payment)
 
@@ -113,13 +145,18 @@ func ProcessWebhook(webhook *model.WebhookRequest) error {
         return err
     }
 
+    // A late or repeated webhook must not revive a refunded payment
+    if payment.Status == model.PaymentStatusRefunded {
+        return nil
+    }
+
     // Update payment status
-    return UpdatePaymentStatus(payment.ID, webhook.Status)
+    return UpdatePaymentStatus(ctx, payment.ID, webhook.Status)
 }
 
 // Refund a payment (fully or partially)
-func RefundPayment(req *model.RefundRequest) error {
-    payment, err := GetPayment(req.PaymentID)
+func RefundPayment(ctx context.Context, req *model.RefundRequest) error {
+    payment, err := GetPayment(ctx, req.PaymentID)
     if err != nil {
         return err
     }
@@ -133,7 +170,7 @@ func RefundPayment(req *model.RefundRequest) error {
     }
 
     // Process refund with payment provider
-    refundRef, err := refundPaymentWithProvider(payment, req.Amount, req.Reason)
+    refundRef, err := refundPaymentWithProvider(ctx, payment, req.Amount, req.Reason)
     if err != nil {
         return err
     }
@@ -149,21 +186,24 @@ func RefundPayment(req *model.RefundRequest) error {
         CreatedAt: time.Now(),
     }
 
-    _, err = config.MongoDB.Collection("transactions").InsertOne(context.Background(), transaction)
+    _, err = config.MongoDB.Collection("transactions").InsertOne(ctx, transaction)
     if err != nil {
         return err
     }
 
     // Update payment status if full refund
     if req.Amount == payment.Amount {
-        return UpdatePaymentStatus(payment.ID, model.PaymentStatusRefunded)
+        return UpdatePaymentStatus(ctx, payment.ID, model.PaymentStatusRefunded)
     }
 
     return nil
 }
 
 // Helper function to mock payment provider integration (to be implemented)
-func createPaymentWithProvider(payment *model.Payment) (string, string, error) {
+func createPaymentWithProvider(ctx context.Context, payment *model.Payment) (string, string, error) {
+    _, span := tracer.Start(ctx, "createPaymentWithProvider")
+    defer span.End()
+
     // In real implementation, this would call the payment gateway API
     // For now, we'll return mock values
     gatewayRef := fmt.Sprintf("PAY_%s", payment.ID)
@@ -172,17 +212,8 @@ func createPaymentWithProvider(payment *model.Payment) (string, string, error) {
 }
 
 // Helper function to mock refund (to be implemented)
-func refundPaymentWithProvider(payment *model.Payment, amount float64, reason string) (string, error) {
+func refundPaymentWithProvider(ctx context.Context, payment *model.Payment, amount float64, reason string) (string, error) {
     // In real implementation, this would call the payment gateway API
     // For now, we'll return a mock reference
     return fmt.Sprintf("REF_%s", payment.ID), nil
 }
-
-// Helper function to notify the booking service (to be implemented)
-func notifyBookingService(payment *model.Payment, status string) error {
-    // In real implementation, this would call the booking service API
-    // For now, we'll just log it
-    fmt.Printf("Notifying booking service: Payment %s for booking %s is %s\n",
-        payment.ID, payment.BookingID, status)
-    return nil
-}
\ No newline at end of file
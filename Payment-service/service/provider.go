@@ -2,6 +2,7 @@ package service
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "errors"
     "fmt"
@@ -12,13 +13,14 @@ import (
 
     "github.com/ansh0014/payment/config"
     "github.com/ansh0014/payment/model"
+    "github.com/ansh0014/payment/utils"
 )
 
 // PaymentProvider is an interface for different payment gateways
 type PaymentProvider interface {
-    CreatePayment(amount float64, currency string, description string, metadata map[string]string) (string, string, error)
-    VerifyPayment(gatewayReference string) (model.PaymentStatus, error)
-    RefundPayment(gatewayReference string, amount float64, reason string) (string, error)
+    CreatePayment(ctx context.Context, amount float64, currency string, description string, metadata map[string]string) (string, string, error)
+    VerifyPayment(ctx context.Context, gatewayReference string) (model.PaymentStatus, error)
+    RefundPayment(ctx context.Context, gatewayReference string, amount float64, reason string) (string, error)
 }
 
 // RazorpayProvider implements PaymentProvider for Razorpay
@@ -82,7 +84,7 @@ func NewPaymentProvider() PaymentProvider {
 type MockProvider struct{}
 
 // CreatePayment implements payment creation for MockProvider
-func (p *MockProvider) CreatePayment(amount float64, currency string, description string, metadata map[string]string) (string, string, error) {
+func (p *MockProvider) CreatePayment(ctx context.Context, amount float64, currency string, description string, metadata map[string]string) (string, string, error) {
     // Generate a mock reference
     reference := fmt.Sprintf("mock_%d", time.Now().UnixNano())
     
@@ -101,20 +103,20 @@ func (p *MockProvider) CreatePayment(amount float64, currency string, descriptio
 }
 
 // VerifyPayment implements payment verification for MockProvider
-func (p *MockProvider) VerifyPayment(gatewayReference string) (model.PaymentStatus, error) {
+func (p *MockProvider) VerifyPayment(ctx context.Context, gatewayReference string) (model.PaymentStatus, error) {
     // For testing, always return success
     return model.PaymentStatusCompleted, nil
 }
 
 // RefundPayment implements refund for MockProvider
-func (p *MockProvider) RefundPayment(gatewayReference string, amount float64, reason string) (string, error) {
+func (p *MockProvider) RefundPayment(ctx context.Context, gatewayReference string, amount float64, reason string) (string, error) {
     // Generate a mock refund reference
     refundRef := fmt.Sprintf("refund_%s_%d", gatewayReference, time.Now().UnixNano())
     return refundRef, nil
 }
 
 // CreatePayment implements payment creation for RazorpayProvider
-func (p *RazorpayProvider) CreatePayment(amount float64, currency string, description string, metadata map[string]string) (string, string, error) {
+func (p *RazorpayProvider) CreatePayment(ctx context.Context, amount float64, currency string, description string, metadata map[string]string) (string, string, error) {
     if p.IsTest {
         // In test mode, use predefined test values
         fmt.Println("[TEST MODE] Creating Razorpay payment")
@@ -139,7 +141,7 @@ func (p *RazorpayProvider) CreatePayment(amount float64, currency string, descri
     jsonData, _ := json.Marshal(reqBody)
 
     // Create HTTP request
-    req, err := http.NewRequest("POST", p.BaseURL+"/orders", bytes.NewBuffer(jsonData))
+    req, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/orders", bytes.NewBuffer(jsonData))
     if err != nil {
         return "", "", err
     }
@@ -149,7 +151,7 @@ func (p *RazorpayProvider) CreatePayment(amount float64, currency string, descri
     req.Header.Set("Content-Type", "application/json")
 
     // Send request
-    client := &http.Client{Timeout: 10 * time.Second}
+    client := utils.NewHTTPClient(10 * time.Second)
     resp, err := client.Do(req)
     if err != nil {
         return "", "", err
@@ -183,14 +185,14 @@ func (p *RazorpayProvider) CreatePayment(amount float64, currency string, descri
 }
 
 // VerifyPayment implements payment verification for RazorpayProvider
-func (p *RazorpayProvider) VerifyPayment(gatewayReference string) (model.PaymentStatus, error) {
+func (p *RazorpayProvider) VerifyPayment(ctx context.Context, gatewayReference string) (model.PaymentStatus, error) {
     // In a real implementation, you would verify the payment with Razorpay
     // For now, return completed
     return model.PaymentStatusCompleted, nil
 }
 
 // RefundPayment implements refund for RazorpayProvider
-func (p *RazorpayProvider) RefundPayment(gatewayReference string, amount float64, reason string) (string, error) {
+func (p *RazorpayProvider) RefundPayment(ctx context.Context, gatewayReference string, amount float64, reason string) (string, error) {
     if p.IsTest {
         // In test mode, use predefined test values
         fmt.Println("[TEST MODE] Processing Razorpay refund")
@@ -208,7 +210,7 @@ func (p *RazorpayProvider) RefundPayment(gatewayReference string, amount float64
     jsonData, _ := json.Marshal(reqBody)
 
     // Create HTTP request
-    req, err := http.NewRequest("POST", p.BaseURL+"/payments/"+gatewayReference+"/refund", bytes.NewBuffer(jsonData))
+    req, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/payments/"+gatewayReference+"/refund", bytes.NewBuffer(jsonData))
     if err != nil {
         return "", err
     }
@@ -218,7 +220,7 @@ func (p *RazorpayProvider) RefundPayment(gatewayReference string, amount float64
     req.Header.Set("Content-Type", "application/json")
 
     // Send request
-    client := &http.Client{Timeout: 10 * time.Second}
+    client := utils.NewHTTPClient(10 * time.Second)
     resp, err := client.Do(req)
     if err != nil {
         return "", err
@@ -0,0 +1,58 @@
+package utils
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "net/http"
+    "time"
+
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// RequestIDHeader is the header used to correlate a request across services
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID in the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+    return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// GetRequestIDFromContext returns the request ID stored in the context, or ""
+func GetRequestIDFromContext(ctx context.Context) string {
+    requestID, _ := ctx.Value(requestIDKey{}).(string)
+    return requestID
+}
+
+// NewRequestID generates a random request ID for requests that arrive without one
+func NewRequestID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        panic("crypto/rand unavailable: " + err.Error())
+    }
+    return hex.EncodeToString(b)
+}
+
+// NewHTTPClient returns an HTTP client for outbound calls that forwards the
+// request ID and W3C trace context found in each outgoing request's context
+// and records a client span per call
+func NewHTTPClient(timeout time.Duration) *http.Client {
+    return &http.Client{
+        Timeout:   timeout,
+        Transport: otelhttp.NewTransport(requestIDTransport{base: http.DefaultTransport}),
+    }
+}
+
+type requestIDTransport struct {
+    base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if id := GetRequestIDFromContext(req.Context()); id != "" && req.Header.Get(RequestIDHeader) == "" {
+        req = req.Clone(req.Context())
+        req.Header.Set(RequestIDHeader, id)
+    }
+    return t.base.RoundTrip(req)
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "reflect"
+    "strings"
+
+    "github.com/go-playground/validator/v10"
+)
+
+// maxBodyBytes caps JSON request bodies.
+const maxBodyBytes = 1 << 20
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+    v := validator.New(validator.WithRequiredStructEnabled())
+    // report fields by their JSON names, as clients send them
+    v.RegisterTagNameFunc(func(f reflect.StructField) string {
+        name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+        if name == "-" {
+            return ""
+        }
+        return name
+    })
+    return v
+}
+
+// Validate checks dst's `validate` struct tags and returns one error per
+// failing field, or nil when dst is valid.
+func Validate(dst interface{}) []ValidationError {
+    err := validate.Struct(dst)
+    if err == nil {
+        return nil
+    }
+    var fieldErrs validator.ValidationErrors
+    if !errors.As(err, &fieldErrs) {
+        return []ValidationError{{Field: "", Message: err.Error()}}
+    }
+    out := make([]ValidationError, 0, len(fieldErrs))
+    for _, fe := range fieldErrs {
+        out = append(out, ValidationError{Field: fe.Field(), Message: validationMessage(fe)})
+    }
+    return out
+}
+
+func validationMessage(fe validator.FieldError) string {
+    switch fe.Tag() {
+    case "required":
+        return "is required"
+    case "len":
+        return fmt.Sprintf("must be exactly %s characters", fe.Param())
+    case "min":
+        return fmt.Sprintf("must be at least %s", fe.Param())
+    case "max":
+        return fmt.Sprintf("must be at most %s", fe.Param())
+    case "gt":
+        return fmt.Sprintf("must be greater than %s", fe.Param())
+    case "oneof":
+        return fmt.Sprintf("must be one of: %s", fe.Param())
+    default:
+        return fmt.Sprintf("failed the %q check", fe.Tag())
+    }
+}
+
+// DecodeAndValidate reads a single JSON object of at most 1MB into dst and
+// validates it. On failure it writes the error response and returns false.
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+    r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+    dec := json.NewDecoder(r.Body)
+    if err := dec.Decode(dst); err != nil {
+        var tooLarge *http.MaxBytesError
+        switch {
+        case errors.As(err, &tooLarge):
+            ErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge, nil)
+        case errors.Is(err, io.EOF):
+            BadRequestResponse(w, "Request body must not be empty", nil)
+        default:
+            BadRequestResponse(w, "Invalid request body: "+err.Error(), nil)
+        }
+        return false
+    }
+    if dec.More() {
+        BadRequestResponse(w, "Request body must only contain a single JSON object", nil)
+        return false
+    }
+    if errs := Validate(dst); len(errs) > 0 {
+        ValidationErrorResponse(w, errs)
+        return false
+    }
+    return true
+}
@@ -0,0 +1,237 @@
+// Package admin serves the operator API on its own listener: the route
+// table, upstream health and circuits, rate-limit counters, and runtime
+// controls for draining upstreams, purging the cache and maintenance mode.
+// Every request must carry "Authorization: Bearer <GATEWAY_ADMIN_TOKEN>".
+package admin
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/netip"
+    "sort"
+    "strings"
+
+    "github.com/ansh0014/api/cache"
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/health"
+    "github.com/ansh0014/api/middleware"
+    "github.com/ansh0014/api/pkg"
+
+    "github.com/gorilla/mux"
+)
+
+// Deps are the gateway components the admin API inspects and controls.
+// Cache may be nil when the response cache is disabled.
+type Deps struct {
+    Router      http.Handler
+    Proxies     *pkg.ProxyMap
+    Health      *health.Aggregator
+    Cache       *cache.Cache
+    CacheRules  []config.CacheRule
+    Streams     *middleware.StreamLimiter
+    Bots        *middleware.BotGuard
+    Maintenance *middleware.Maintenance
+}
+
+// Server is the admin API.
+type Server struct {
+    token string
+    d     Deps
+}
+
+// New returns the admin API guarded by token.
+func New(token string, d Deps) *Server {
+    return &Server{token: token, d: d}
+}
+
+// Handler returns the authenticated admin routes.
+func (s *Server) Handler() http.Handler {
+    r := mux.NewRouter()
+    r.HandleFunc("/routes", s.routes).Methods("GET")
+    r.HandleFunc("/upstreams", s.upstreams).Methods("GET")
+    r.HandleFunc("/upstreams/{name}/{action:drain|eject|restore}", s.upstreamAction).Methods("POST")
+    r.HandleFunc("/ratelimit/{key}", s.rateLimit).Methods("GET")
+    r.HandleFunc("/cache/purge", s.purge).Methods("POST")
+    r.HandleFunc("/maintenance", s.listMaintenance).Methods("GET")
+    r.HandleFunc("/maintenance", s.setMaintenance).Methods("PUT")
+    r.HandleFunc("/maintenance", s.clearMaintenance).Methods("DELETE")
+    return s.auth(r)
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+        if s.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+            http.Error(w, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+        if r.Method != http.MethodGet {
+            slog.InfoContext(r.Context(), "admin action", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+type gatewayRoute struct {
+    Path    string   `json:"path"`
+    Methods []string `json:"methods,omitempty"`
+}
+
+// routes lists the gateway's own endpoints, the proxied prefixes and the
+// cache rules.
+func (s *Server) routes(w http.ResponseWriter, r *http.Request) {
+    var gw []gatewayRoute
+    if walker, ok := s.d.Router.(interface{ Walk(mux.WalkFunc) error }); ok {
+        walker.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+            tpl, err := route.GetPathTemplate()
+            if err != nil {
+                if tpl, err = route.GetPathRegexp(); err != nil {
+                    return nil
+                }
+            }
+            methods, _ := route.GetMethods()
+            gw = append(gw, gatewayRoute{Path: tpl, Methods: methods})
+            return nil
+        })
+    }
+    var ups []pkg.UpstreamStatus
+    for _, u := range s.d.Proxies.Upstreams() {
+        ups = append(ups, u.Status())
+    }
+    sort.Slice(ups, func(i, j int) bool { return ups[i].Name < ups[j].Name })
+    writeJSON(w, http.StatusOK, map[string]any{
+        "gateway":     gw,
+        "upstreams":   ups,
+        "cache_rules": s.d.CacheRules,
+    })
+}
+
+type upstreamView struct {
+    pkg.UpstreamStatus
+    Health *health.Upstream `json:"health,omitempty"`
+}
+
+// upstreams reports admin state, circuit and live readiness per upstream.
+func (s *Server) upstreams(w http.ResponseWriter, r *http.Request) {
+    rep := s.d.Health.Run(r.Context())
+    var out []upstreamView
+    for name, u := range s.d.Proxies.Upstreams() {
+        v := upstreamView{UpstreamStatus: u.Status()}
+        // readiness is probed for stable groups only
+        if h, ok := rep.Services[name]; ok {
+            v.Health = &h
+        }
+        out = append(out, v)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    writeJSON(w, http.StatusOK, out)
+}
+
+// upstreamAction drains, ejects or restores an upstream.
+func (s *Server) upstreamAction(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    u, ok := s.d.Proxies.Upstreams()[vars["name"]]
+    if !ok {
+        http.Error(w, "unknown upstream", http.StatusNotFound)
+        return
+    }
+    switch vars["action"] {
+    case "drain":
+        u.SetState(pkg.StateDraining)
+    case "eject":
+        u.SetState(pkg.StateEjected)
+    case "restore":
+        u.SetState(pkg.StateActive)
+    }
+    writeJSON(w, http.StatusOK, u.Status())
+}
+
+type rateLimitView struct {
+    Key      string                     `json:"key"`
+    IPLimit  *ipLimitView               `json:"ip_limit,omitempty"`
+    Velocity *middleware.VelocityStatus `json:"sensitive_routes,omitempty"`
+    Streams  streamView                 `json:"streams"`
+}
+
+type ipLimitView struct {
+    Burst     int     `json:"burst"`
+    Remaining float64 `json:"remaining"`
+}
+
+type streamView struct {
+    User  int `json:"user"`
+    Total int `json:"total"`
+}
+
+// rateLimit shows the counters kept for key, which is a client IP for the
+// request limits or a user ID for stream limits.
+func (s *Server) rateLimit(w http.ResponseWriter, r *http.Request) {
+    key := mux.Vars(r)["key"]
+    v := rateLimitView{Key: key}
+    if burst, remaining, ok := middleware.RateLimitStatus(key); ok {
+        v.IPLimit = &ipLimitView{Burst: burst, Remaining: remaining}
+    }
+    if ip, err := netip.ParseAddr(key); err == nil {
+        if st, ok := s.d.Bots.Status(ip.Unmap()); ok {
+            v.Velocity = &st
+        }
+    }
+    v.Streams.User, v.Streams.Total = s.d.Streams.Open(key)
+    writeJSON(w, http.StatusOK, v)
+}
+
+// purge flushes cache entries by tag.
+func (s *Server) purge(w http.ResponseWriter, r *http.Request) {
+    if s.d.Cache == nil {
+        http.Error(w, "cache disabled", http.StatusConflict)
+        return
+    }
+    var req struct {
+        Tags []string `json:"tags"`
+    }
+    if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || len(req.Tags) == 0 {
+        http.Error(w, "body must be {\"tags\": [...]}", http.StatusBadRequest)
+        return
+    }
+    n, err := s.d.Cache.Purge(r.Context(), req.Tags)
+    if err != nil {
+        http.Error(w, "purge failed", http.StatusInternalServerError)
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]int{"purged": n})
+}
+
+func (s *Server) listMaintenance(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, s.d.Maintenance.List())
+}
+
+// setMaintenance switches a path prefix into maintenance.
+func (s *Server) setMaintenance(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        PathPrefix string `json:"path_prefix"`
+        Message    string `json:"message"`
+    }
+    if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || !strings.HasPrefix(req.PathPrefix, "/") {
+        http.Error(w, "body must be {\"path_prefix\": \"/...\", \"message\": \"...\"}", http.StatusBadRequest)
+        return
+    }
+    writeJSON(w, http.StatusOK, s.d.Maintenance.Set(req.PathPrefix, req.Message))
+}
+
+// clearMaintenance ends maintenance for ?path_prefix=.
+func (s *Server) clearMaintenance(w http.ResponseWriter, r *http.Request) {
+    if !s.d.Maintenance.Clear(r.URL.Query().Get("path_prefix")) {
+        http.Error(w, "prefix not in maintenance", http.StatusNotFound)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,188 @@
+// Package bff serves backend-for-frontend composition endpoints. Each one
+// fans out to the upstream services concurrently, bounds every call with its
+// own timeout and merges whatever came back: a failed optional part is
+// reported under "errors" instead of failing the whole response.
+package bff
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/ansh0014/api/internal"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// maxBody bounds a single upstream response.
+const maxBody = 4 << 20
+
+// forwarded request headers; X-User-ID is trusted because JWTExtract
+// rebuilds it from the verified token.
+var forwardHeaders = []string{"Authorization", "X-User-ID", "X-API-Version", "Accept-Language"}
+
+// Upstreams holds the base URLs of the services the endpoints compose.
+type Upstreams struct {
+    Booking string
+    Payment string
+    Venue   string
+}
+
+// Handler serves the composition endpoints.
+type Handler struct {
+    up      Upstreams
+    timeout time.Duration
+    client  *http.Client
+}
+
+// New returns a handler whose upstream calls each time out after timeout.
+func New(up Upstreams, timeout time.Duration) *Handler {
+    return &Handler{
+        up:      up,
+        timeout: timeout,
+        client:  &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+    }
+}
+
+// UpstreamError is a failed upstream call.
+type UpstreamError struct {
+    Status int // 0 when the upstream was not reached
+    Msg    string
+}
+
+func (e *UpstreamError) Error() string { return e.Msg }
+
+// part is the outcome of one upstream call.
+type part struct {
+    data json.RawMessage
+    err  *UpstreamError
+}
+
+// call GETs base+path, forwarding the caller's identity, and returns the
+// response's "data" envelope when it has one.
+func (h *Handler) call(r *http.Request, base, path string) part {
+    ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+path, nil)
+    if err != nil {
+        return part{err: &UpstreamError{Msg: err.Error()}}
+    }
+    for _, name := range forwardHeaders {
+        if v := r.Header.Get(name); v != "" {
+            req.Header.Set(name, v)
+        }
+    }
+    if id := internal.RequestInfoFrom(r.Context()).ID; id != "" {
+        req.Header.Set(internal.RequestIDHeader, id)
+    }
+
+    res, err := h.client.Do(req)
+    if err != nil {
+        if ctx.Err() == context.DeadlineExceeded {
+            return part{err: &UpstreamError{Status: http.StatusGatewayTimeout, Msg: "timed out"}}
+        }
+        return part{err: &UpstreamError{Msg: "unreachable"}}
+    }
+    defer res.Body.Close()
+
+    body, err := io.ReadAll(io.LimitReader(res.Body, maxBody))
+    if err != nil {
+        return part{err: &UpstreamError{Status: res.StatusCode, Msg: err.Error()}}
+    }
+    if res.StatusCode >= 300 {
+        return part{err: &UpstreamError{Status: res.StatusCode, Msg: upstreamMessage(res.StatusCode, body)}}
+    }
+
+    var env struct {
+        Data json.RawMessage `json:"data"`
+    }
+    if json.Unmarshal(body, &env) == nil && len(env.Data) > 0 {
+        return part{data: env.Data}
+    }
+    return part{data: body}
+}
+
+// upstreamMessage extracts an error message from the usual envelopes.
+func upstreamMessage(status int, body []byte) string {
+    var env struct {
+        Message string `json:"message"`
+        Error   string `json:"error"`
+    }
+    if json.Unmarshal(body, &env) == nil {
+        if env.Message != "" {
+            return env.Message
+        }
+        if env.Error != "" {
+            return env.Error
+        }
+    }
+    return fmt.Sprintf("upstream returned %d", status)
+}
+
+// fanOut runs the named calls concurrently and waits for all of them.
+func fanOut(calls map[string]func() part) map[string]part {
+    out := make(map[string]part, len(calls))
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    for name, fn := range calls {
+        wg.Add(1)
+        go func(name string, fn func() part) {
+            defer wg.Done()
+            p := fn()
+            mu.Lock()
+            out[name] = p
+            mu.Unlock()
+        }(name, fn)
+    }
+    wg.Wait()
+    return out
+}
+
+// response is the composed payload. Optional parts that failed are null in
+// Data and explained in Errors.
+type response struct {
+    Data    map[string]interface{} `json:"data"`
+    Errors  map[string]string      `json:"errors,omitempty"`
+    Partial bool                   `json:"partial"`
+}
+
+func newResponse() *response {
+    return &response{Data: map[string]interface{}{}, Errors: map[string]string{}}
+}
+
+// add records an optional part.
+func (resp *response) add(name string, p part) {
+    if p.err != nil {
+        resp.Data[name] = nil
+        resp.Errors[name] = p.err.Msg
+        resp.Partial = true
+        return
+    }
+    resp.Data[name] = p.data
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    w.WriteHeader(code)
+    json.NewEncoder(w).Encode(v)
+}
+
+// failRequired answers when a part the page cannot do without failed.
+func failRequired(w http.ResponseWriter, name string, err *UpstreamError) {
+    code := http.StatusBadGateway
+    switch {
+    case err.Status == http.StatusNotFound, err.Status == http.StatusUnauthorized, err.Status == http.StatusForbidden:
+        code = err.Status
+    case err.Status == http.StatusGatewayTimeout:
+        code = http.StatusGatewayTimeout
+    }
+    writeJSON(w, code, map[string]interface{}{
+        "errors": map[string]string{name: err.Msg},
+    })
+}
@@ -0,0 +1,211 @@
+package bff
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+
+    "github.com/gorilla/mux"
+)
+
+// ShowPage serves GET /bff/shows/{id}: the show with its movie, theater, seat
+// map (seats flagged with live lock status) and the venue hall layout.
+// Only the show itself is required.
+func (h *Handler) ShowPage(w http.ResponseWriter, r *http.Request) {
+    showID := url.PathEscape(mux.Vars(r)["id"])
+    movieAPI := "/api/platforms/movie"
+
+    first := fanOut(map[string]func() part{
+        "show":  func() part { return h.call(r, h.up.Booking, movieAPI+"/shows/"+showID) },
+        "seats": func() part { return h.call(r, h.up.Booking, movieAPI+"/seats?show_id="+url.QueryEscape(showID)) },
+        "locks": func() part { return h.call(r, h.up.Booking, movieAPI+"/shows/"+showID+"/locks") },
+    })
+    show := first["show"]
+    if show.err != nil {
+        failRequired(w, "show", show.err)
+        return
+    }
+
+    var ref struct {
+        MovieID   string `json:"movie_id"`
+        TheaterID string `json:"theater_id"`
+        ScreenID  string `json:"screen_id"`
+    }
+    json.Unmarshal(show.data, &ref)
+
+    second := fanOut(map[string]func() part{
+        "movie":   func() part { return h.call(r, h.up.Booking, movieAPI+"/"+url.PathEscape(ref.MovieID)) },
+        "theater": func() part { return h.call(r, h.up.Booking, movieAPI+"/theaters/"+url.PathEscape(ref.TheaterID)) },
+        // screens are halls in venue-service
+        "layout": func() part { return h.call(r, h.up.Venue, "/halls/"+url.PathEscape(ref.ScreenID)+"/seats") },
+    })
+
+    resp := newResponse()
+    resp.add("show", show)
+    resp.add("movie", second["movie"])
+    resp.add("theater", second["theater"])
+    resp.add("layout", second["layout"])
+    resp.add("seats", mergeLocks(first["seats"], first["locks"], resp))
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// mergeLocks flags each seat held by another checkout. Without lock data the
+// seats are still returned and the page is marked partial.
+func mergeLocks(seats, locks part, resp *response) part {
+    if seats.err != nil {
+        return seats
+    }
+    if locks.err != nil {
+        resp.Errors["locks"] = locks.err.Msg
+        resp.Partial = true
+        return seats
+    }
+
+    var s struct {
+        Seats []map[string]interface{} `json:"seats"`
+    }
+    var l struct {
+        Locked []string `json:"locked"`
+    }
+    if json.Unmarshal(seats.data, &s) != nil || json.Unmarshal(locks.data, &l) != nil {
+        return seats
+    }
+    held := make(map[string]bool, len(l.Locked))
+    for _, id := range l.Locked {
+        held[id] = true
+    }
+    for _, seat := range s.Seats {
+        id, _ := seat["id"].(string)
+        seat["locked"] = held[id]
+    }
+    data, err := json.Marshal(s.Seats)
+    if err != nil {
+        return seats
+    }
+    return part{data: data}
+}
+
+// CheckoutSummary serves GET /bff/checkout/{bookingID}: the caller's booking
+// with its payment and a price breakdown built from the booking's own price
+// lines, for every platform. Only the booking is required.
+func (h *Handler) CheckoutSummary(w http.ResponseWriter, r *http.Request) {
+    bookingID := url.PathEscape(mux.Vars(r)["id"])
+
+    b := h.call(r, h.up.Booking, "/api/bookings/"+bookingID)
+    if b.err != nil {
+        failRequired(w, "booking", b.err)
+        return
+    }
+
+    // Booking-service wraps it as {"booking": {...}}
+    var envelope struct {
+        Booking json.RawMessage `json:"booking"`
+    }
+    if json.Unmarshal(b.data, &envelope) == nil && len(envelope.Booking) > 0 {
+        b.data = envelope.Booking
+    }
+    var bk booking
+    json.Unmarshal(b.data, &bk)
+
+    resp := newResponse()
+    resp.add("booking", b)
+    if bk.PaymentID != "" {
+        resp.add("payment", h.call(r, h.up.Payment, "/api/payments/"+url.PathEscape(bk.PaymentID)))
+    } else {
+        resp.Data["payment"] = nil
+    }
+    resp.Data["price"] = priceBreakdown(bk)
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// booking is the part of a Booking-service booking the checkout prices
+type booking struct {
+    SeatDetails []bookedSeat  `json:"seat_details"`
+    TotalPrice  float64       `json:"total_price"`
+    Currency    string        `json:"currency"`
+    Price       *bookingPrice `json:"price"`
+    PaymentID   string        `json:"payment_id"`
+}
+
+// bookedSeat is a seat as it was sold
+type bookedSeat struct {
+    ID    string  `json:"id"`
+    Label string  `json:"label"`
+    Class string  `json:"class"`
+    Price float64 `json:"price"`
+}
+
+// bookingPrice is the itemised price stored with a booking
+type bookingPrice struct {
+    Currency  string      `json:"currency"`
+    Lines     []priceItem `json:"lines"`
+    Subtotal  float64     `json:"subtotal"`
+    Discounts float64     `json:"discounts"`
+    Fees      float64     `json:"fees"`
+    Taxes     float64     `json:"taxes"`
+    Total     float64     `json:"total"`
+}
+
+// priceItem is one line item of a booking's price; discounts are negative
+type priceItem struct {
+    Kind   string  `json:"kind"`
+    Code   string  `json:"code"`
+    Label  string  `json:"label"`
+    Amount float64 `json:"amount"`
+}
+
+type priceLine struct {
+    SeatID     string  `json:"seat_id"`
+    SeatNumber string  `json:"seat_number,omitempty"`
+    Category   string  `json:"category,omitempty"`
+    Price      float64 `json:"price"`
+}
+
+type price struct {
+    Lines []priceLine `json:"lines"`
+    // Items are the discounts, fees and taxes on top of the seats
+    Items     []priceItem `json:"items"`
+    Currency  string      `json:"currency,omitempty"`
+    Subtotal  float64     `json:"subtotal"`
+    Discounts float64     `json:"discounts"`
+    Fees      float64     `json:"fees"`
+    Taxes     float64     `json:"taxes"`
+    Total     float64     `json:"total"`
+}
+
+// priceBreakdown itemises a booking from its seat details and price lines.
+// Bookings priced before line items were stored only know their seats and
+// total; whatever the total adds on top of the seats is shown as fees.
+func priceBreakdown(bk booking) price {
+    p := price{Lines: []priceLine{}, Items: []priceItem{}, Currency: bk.Currency, Total: bk.TotalPrice}
+    for _, seat := range bk.SeatDetails {
+        p.Lines = append(p.Lines, priceLine{SeatID: seat.ID, SeatNumber: seat.Label, Category: seat.Class, Price: seat.Price})
+    }
+
+    if bk.Price != nil {
+        for _, item := range bk.Price.Lines {
+            if item.Kind != "seat" {
+                p.Items = append(p.Items, item)
+            }
+        }
+        if p.Currency == "" {
+            p.Currency = bk.Price.Currency
+        }
+        p.Subtotal = bk.Price.Subtotal
+        p.Discounts = bk.Price.Discounts
+        p.Fees = bk.Price.Fees
+        p.Taxes = bk.Price.Taxes
+        p.Total = bk.Price.Total
+        return p
+    }
+
+    if len(bk.SeatDetails) == 0 {
+        p.Subtotal = bk.TotalPrice
+        return p
+    }
+    for _, line := range p.Lines {
+        p.Subtotal += line.Price
+    }
+    p.Fees = bk.TotalPrice - p.Subtotal
+    return p
+}
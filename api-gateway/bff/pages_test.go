@@ -0,0 +1,72 @@
+package bff
+
+import (
+    "encoding/json"
+    "reflect"
+    "testing"
+)
+
+func TestPriceBreakdown(t *testing.T) {
+    // a flight booking as Booking-service returns it
+    var itemised booking
+    err := json.Unmarshal([]byte(`{
+        "platform": "flight",
+        "seats": ["s1", "s2"],
+        "seat_details": [
+            {"id": "s1", "label": "12A", "class": "Economy", "price": 4000},
+            {"id": "s2", "label": "12B", "class": "Economy", "price": 4000}
+        ],
+        "total_price": 8624,
+        "currency": "INR",
+        "price": {
+            "currency": "INR",
+            "lines": [
+                {"kind": "seat", "code": "s1", "label": "12A (Economy)", "amount": 4000},
+                {"kind": "seat", "code": "s2", "label": "12B (Economy)", "amount": 4000},
+                {"kind": "discount", "code": "group", "label": "Group discount", "amount": -400},
+                {"kind": "fee", "code": "convenience", "label": "Convenience fee", "amount": 700},
+                {"kind": "tax", "code": "gst", "label": "GST", "amount": 324}
+            ],
+            "subtotal": 8000, "discounts": -400, "fees": 700, "taxes": 324, "total": 8624
+        }
+    }`), &itemised)
+    if err != nil {
+        t.Fatalf("unmarshal booking: %v", err)
+    }
+
+    got := priceBreakdown(itemised)
+    want := price{
+        Lines: []priceLine{
+            {SeatID: "s1", SeatNumber: "12A", Category: "Economy", Price: 4000},
+            {SeatID: "s2", SeatNumber: "12B", Category: "Economy", Price: 4000},
+        },
+        Items: []priceItem{
+            {Kind: "discount", Code: "group", Label: "Group discount", Amount: -400},
+            {Kind: "fee", Code: "convenience", Label: "Convenience fee", Amount: 700},
+            {Kind: "tax", Code: "gst", Label: "GST", Amount: 324},
+        },
+        Currency:  "INR",
+        Subtotal:  8000,
+        Discounts: -400,
+        Fees:      700,
+        Taxes:     324,
+        Total:     8624,
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("itemised booking\n got %+v\nwant %+v", got, want)
+    }
+
+    // bookings from before itemised prices put the difference under fees
+    legacy := booking{
+        SeatDetails: []bookedSeat{{ID: "s1", Label: "A1", Price: 250}},
+        TotalPrice:  280,
+    }
+    if got := priceBreakdown(legacy); got.Subtotal != 250 || got.Fees != 30 || got.Total != 280 || len(got.Lines) != 1 {
+        t.Fatalf("legacy booking: %+v", got)
+    }
+
+    // without seat details nothing is known beyond the total
+    if got := priceBreakdown(booking{TotalPrice: 500}); got.Subtotal != 500 || got.Fees != 0 || len(got.Lines) != 0 {
+        t.Fatalf("booking without seats: %+v", got)
+    }
+}
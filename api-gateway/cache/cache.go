@@ -0,0 +1,241 @@
+// Package cache implements the gateway response cache for catalog reads.
+//
+// Only routes listed in the cache rules are considered. Keys combine method,
+// path, query string and a hash of the request body so POST search requests
+// can be cached too. Stored responses carry a strong ETag, and entries are
+// tagged so services can purge them when the underlying data changes.
+package cache
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/internal"
+    "github.com/ansh0014/api/metrics"
+)
+
+// InternalTokenHeader authenticates service-to-gateway calls.
+const InternalTokenHeader = "X-Internal-Token"
+
+// Cache applies the configured rules on top of a Store.
+type Cache struct {
+    store   Store
+    rules   []config.CacheRule
+    maxBody int64
+    token   string
+}
+
+// New creates a cache. token protects the purge endpoint; an empty token
+// disables it.
+func New(store Store, cfg config.CacheConfig, token string) *Cache {
+    maxBody := cfg.MaxBody
+    if maxBody <= 0 {
+        maxBody = 1 << 20
+    }
+    return &Cache{store: store, rules: cfg.Rules, maxBody: maxBody, token: token}
+}
+
+// NewStore builds the backend selected in cfg.
+func NewStore(ctx context.Context, cfg config.CacheConfig) (Store, error) {
+    if cfg.Backend == "redis" {
+        return NewRedisStore(ctx, cfg.RedisURL)
+    }
+    return NewMemoryStore(cfg.MaxEntries), nil
+}
+
+// match returns the first rule for the request's method and path.
+func (c *Cache) match(r *http.Request) *config.CacheRule {
+    for i := range c.rules {
+        rule := &c.rules[i]
+        if strings.EqualFold(rule.Method, r.Method) && internal.MatchPath(rule.Path, r.URL.Path) {
+            return rule
+        }
+    }
+    return nil
+}
+
+// Key derives the cache key from method, path, sorted query, API version and
+// body hash.
+func Key(r *http.Request, body []byte) string {
+    bodySum := sha256.Sum256(body)
+    h := sha256.New()
+    // the resolved API version can change the response shape for the same path
+    io.WriteString(h, r.Method+"\n"+r.URL.Path+"\n"+r.URL.Query().Encode()+"\n"+r.Header.Get("X-API-Version")+"\n")
+    h.Write(bodySum[:])
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// Middleware serves cached responses for matching routes and stores fresh
+// upstream responses.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        rule := c.match(r)
+        if rule == nil || rule.TTLSeconds <= 0 {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        body, err := io.ReadAll(io.LimitReader(r.Body, c.maxBody+1))
+        r.Body.Close()
+        if err != nil {
+            http.Error(w, "invalid request body", http.StatusBadRequest)
+            return
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+        if int64(len(body)) > c.maxBody {
+            // too large to hash; let the upstream deal with it
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        key := Key(r, body)
+        ctx := r.Context()
+        if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+            e, ok, err := c.store.Get(ctx, key)
+            if err != nil {
+                slog.WarnContext(ctx, "cache get failed", "error", err.Error())
+            }
+            if ok {
+                metrics.CacheRequests.WithLabelValues(rule.Path, "hit").Inc()
+                internal.RequestInfoFrom(ctx).Route = rule.Path
+                serve(w, r, e, "HIT")
+                return
+            }
+        }
+        metrics.CacheRequests.WithLabelValues(rule.Path, "miss").Inc()
+
+        rec := newRecorder()
+        next.ServeHTTP(rec, r)
+        e := rec.entry()
+        if cacheable(e) {
+            if err := c.store.Set(ctx, key, e, rule.TTL(), rule.Tags); err != nil {
+                slog.WarnContext(ctx, "cache set failed", "error", err.Error())
+            }
+        }
+        serve(w, r, e, "MISS")
+    })
+}
+
+// cacheable reports whether an upstream response may be shared.
+func cacheable(e *Entry) bool {
+    if e.Status != http.StatusOK || e.Header.Get("Set-Cookie") != "" {
+        return false
+    }
+    cc := e.Header.Get("Cache-Control")
+    return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// serve writes e to w, answering 304 when If-None-Match matches its ETag.
+func serve(w http.ResponseWriter, r *http.Request, e *Entry, state string) {
+    h := w.Header()
+    for k, v := range e.Header {
+        h[k] = v
+    }
+    h.Set("X-Cache", state)
+    if state == "HIT" {
+        h.Set("Age", strconv.Itoa(int(time.Since(e.StoredAt).Seconds())))
+    }
+    if e.ETag != "" {
+        h.Set("ETag", e.ETag)
+        if etagMatch(r.Header.Get("If-None-Match"), e.ETag) {
+            h.Del("Content-Length")
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+    }
+    h.Set("Content-Length", strconv.Itoa(len(e.Body)))
+    w.WriteHeader(e.Status)
+    w.Write(e.Body)
+}
+
+func etagMatch(header, etag string) bool {
+    if header == "" {
+        return false
+    }
+    for _, candidate := range strings.Split(header, ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+            return true
+        }
+    }
+    return false
+}
+
+// recorder buffers an upstream response so it can be stored before being sent.
+type recorder struct {
+    header http.Header
+    status int
+    body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+    return &recorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+
+func (rec *recorder) WriteHeader(code int) { rec.status = code }
+
+func (rec *recorder) entry() *Entry {
+    h := rec.header.Clone()
+    for _, k := range []string{"Connection", "Keep-Alive", "Transfer-Encoding", "Content-Length", "Date", "X-Request-Id"} {
+        h.Del(k)
+    }
+    sum := sha256.Sum256(rec.body.Bytes())
+    return &Entry{
+        Status:   rec.status,
+        Header:   h,
+        Body:     rec.body.Bytes(),
+        ETag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+        StoredAt: time.Now(),
+    }
+}
+
+// Purge removes every entry carrying one of the tags.
+func (c *Cache) Purge(ctx context.Context, tags []string) (int, error) {
+    n, err := c.store.PurgeTags(ctx, tags)
+    if err == nil {
+        slog.InfoContext(ctx, "cache purged", "tags", tags, "entries", n)
+    }
+    return n, err
+}
+
+type purgeRequest struct {
+    Tags []string `json:"tags"`
+}
+
+// PurgeHandler serves POST /internal/cache/purge for services that changed
+// catalog data. Callers authenticate with the X-Internal-Token header.
+func (c *Cache) PurgeHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if c.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(InternalTokenHeader)), []byte(c.token)) != 1 {
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        var req purgeRequest
+        if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || len(req.Tags) == 0 {
+            http.Error(w, "body must be {\"tags\": [...]}", http.StatusBadRequest)
+            return
+        }
+        n, err := c.Purge(r.Context(), req.Tags)
+        if err != nil {
+            http.Error(w, "purge failed", http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(map[string]int{"purged": n})
+    })
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/ansh0014/api/config"
+)
+
+func TestKey(t *testing.T) {
+    request := func(method, target, version string) *http.Request {
+        r := httptest.NewRequest(method, target, nil)
+        if version != "" {
+            r.Header.Set("X-API-Version", version)
+        }
+        return r
+    }
+    body := []byte(`{"city":"pune"}`)
+    base := Key(request("POST", "/venue/api/search?page=1&size=20", "v1"), body)
+
+    same := []struct {
+        name string
+        key  string
+    }{
+        {"query order", Key(request("POST", "/venue/api/search?size=20&page=1", "v1"), body)},
+        {"equal body", Key(request("POST", "/venue/api/search?page=1&size=20", "v1"), []byte(`{"city":"pune"}`))},
+    }
+    for _, tt := range same {
+        if tt.key != base {
+            t.Errorf("%s changed the key", tt.name)
+        }
+    }
+
+    different := []struct {
+        name string
+        key  string
+    }{
+        {"method", Key(request("PUT", "/venue/api/search?page=1&size=20", "v1"), body)},
+        {"path", Key(request("POST", "/venue/api/shows?page=1&size=20", "v1"), body)},
+        {"query value", Key(request("POST", "/venue/api/search?page=2&size=20", "v1"), body)},
+        {"extra query", Key(request("POST", "/venue/api/search?page=1&size=20&sort=asc", "v1"), body)},
+        {"api version", Key(request("POST", "/venue/api/search?page=1&size=20", "v2"), body)},
+        {"no api version", Key(request("POST", "/venue/api/search?page=1&size=20", ""), body)},
+        {"body", Key(request("POST", "/venue/api/search?page=1&size=20", "v1"), []byte(`{"city":"goa"}`))},
+        {"no body", Key(request("POST", "/venue/api/search?page=1&size=20", "v1"), nil)},
+    }
+    for _, tt := range different {
+        if tt.key == base {
+            t.Errorf("%s did not change the key", tt.name)
+        }
+    }
+}
+
+// upstream counts calls and answers with the configured headers
+type upstream struct {
+    calls  int
+    status int
+    header http.Header
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    u.calls++
+    for k, v := range u.header {
+        w.Header()[k] = v
+    }
+    status := u.status
+    if status == 0 {
+        status = http.StatusOK
+    }
+    w.WriteHeader(status)
+    w.Write([]byte("shows for " + r.URL.Path))
+}
+
+func testCache() *Cache {
+    return New(NewMemoryStore(0), config.CacheConfig{Rules: []config.CacheRule{
+        {Method: "GET", Path: "/venue/api/shows", TTLSeconds: 60, Tags: []string{"shows"}},
+        {Method: "GET", Path: "/venue/api/venues/*", TTLSeconds: 60, Tags: []string{"venues"}},
+        {Method: "GET", Path: "/venue/api/live", TTLSeconds: 0},
+    }}, "secret")
+}
+
+// fetch sends a GET request with header through h
+func fetch(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+    r := httptest.NewRequest(http.MethodGet, path, nil)
+    for k, v := range header {
+        r.Header[k] = v
+    }
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, r)
+    return rec
+}
+
+func TestMiddlewareCaches(t *testing.T) {
+    up := &upstream{}
+    h := testCache().Middleware(up)
+
+    if rec := fetch(h, "/venue/api/shows", nil); rec.Header().Get("X-Cache") != "MISS" {
+        t.Fatalf("first request: X-Cache %q", rec.Header().Get("X-Cache"))
+    }
+    rec := fetch(h, "/venue/api/shows", nil)
+    if rec.Header().Get("X-Cache") != "HIT" || up.calls != 1 {
+        t.Fatalf("second request: X-Cache %q after %d upstream calls", rec.Header().Get("X-Cache"), up.calls)
+    }
+    if body := rec.Body.String(); body != "shows for /venue/api/shows" {
+        t.Fatalf("cached body %q", body)
+    }
+
+    // revalidation answers 304 from the cache
+    etag := rec.Header().Get("ETag")
+    if rec := fetch(h, "/venue/api/shows", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+        t.Fatalf("If-None-Match %s: status %d, want 304", etag, rec.Code)
+    }
+
+    // no-cache requests skip the lookup but refresh the entry
+    if rec := fetch(h, "/venue/api/shows", http.Header{"Cache-Control": {"no-cache"}}); rec.Header().Get("X-Cache") != "MISS" || up.calls != 2 {
+        t.Fatalf("no-cache request: X-Cache %q after %d upstream calls", rec.Header().Get("X-Cache"), up.calls)
+    }
+}
+
+func TestMiddlewareBypass(t *testing.T) {
+    tests := []struct {
+        name   string
+        path   string
+        status int
+        header http.Header
+    }{
+        {name: "no-store response", path: "/venue/api/shows", header: http.Header{"Cache-Control": {"no-store"}}},
+        {name: "private response", path: "/venue/api/shows", header: http.Header{"Cache-Control": {"private, max-age=60"}}},
+        {name: "cookie response", path: "/venue/api/shows", header: http.Header{"Set-Cookie": {"session=1"}}},
+        {name: "error response", path: "/venue/api/shows", status: http.StatusInternalServerError},
+        {name: "zero ttl route", path: "/venue/api/live"},
+        {name: "unlisted route", path: "/venue/api/other"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            up := &upstream{status: tt.status, header: tt.header}
+            h := testCache().Middleware(up)
+            fetch(h, tt.path, nil)
+            if rec := fetch(h, tt.path, nil); rec.Header().Get("X-Cache") == "HIT" {
+                t.Fatal("response was served from the cache")
+            }
+            if up.calls != 2 {
+                t.Fatalf("%d upstream calls, want 2", up.calls)
+            }
+        })
+    }
+}
+
+func TestPurgeHandler(t *testing.T) {
+    c := testCache()
+    up := &upstream{}
+    h := c.Middleware(up)
+    purge := c.PurgeHandler()
+
+    send := func(token, body string) *httptest.ResponseRecorder {
+        r := httptest.NewRequest(http.MethodPost, "/internal/cache/purge", strings.NewReader(body))
+        if token != "" {
+            r.Header.Set(InternalTokenHeader, token)
+        }
+        rec := httptest.NewRecorder()
+        purge.ServeHTTP(rec, r)
+        return rec
+    }
+
+    if rec := send("", `{"tags":["shows"]}`); rec.Code != http.StatusForbidden {
+        t.Fatalf("missing token: status %d, want 403", rec.Code)
+    }
+    if rec := send("wrong", `{"tags":["shows"]}`); rec.Code != http.StatusForbidden {
+        t.Fatalf("wrong token: status %d, want 403", rec.Code)
+    }
+    if rec := send("secret", `{"tags":[]}`); rec.Code != http.StatusBadRequest {
+        t.Fatalf("no tags: status %d, want 400", rec.Code)
+    }
+
+    for _, path := range []string{"/venue/api/shows", "/venue/api/venues/1", "/venue/api/venues/2"} {
+        fetch(h, path, nil)
+    }
+
+    rec := send("secret", `{"tags":["venues"]}`)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("purge: status %d", rec.Code)
+    }
+    var res map[string]int
+    if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || res["purged"] != 2 {
+        t.Fatalf("purge answered %v (%v), want 2 purged", res, err)
+    }
+
+    want := map[string]string{
+        "/venue/api/shows":    "HIT",
+        "/venue/api/venues/1": "MISS",
+        "/venue/api/venues/2": "MISS",
+    }
+    for path, state := range want {
+        if got := fetch(h, path, nil).Header().Get("X-Cache"); got != state {
+            t.Errorf("%s after purging venues: X-Cache %q, want %q", path, got, state)
+        }
+    }
+
+    // an empty token disables purging
+    off := New(NewMemoryStore(0), config.CacheConfig{}, "").PurgeHandler()
+    r := httptest.NewRequest(http.MethodPost, "/internal/cache/purge", strings.NewReader(`{"tags":["shows"]}`))
+    r.Header.Set(InternalTokenHeader, "")
+    rec = httptest.NewRecorder()
+    off.ServeHTTP(rec, r)
+    if rec.Code != http.StatusForbidden {
+        t.Fatalf("purge without a configured token: status %d, want 403", rec.Code)
+    }
+}
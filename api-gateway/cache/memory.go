@@ -0,0 +1,116 @@
+package cache
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+type memoryItem struct {
+    entry   *Entry
+    expires time.Time
+    tags    []string
+}
+
+// MemoryStore keeps entries in process memory. It suits a single gateway
+// instance; use RedisStore when running several.
+type MemoryStore struct {
+    mu         sync.Mutex
+    items      map[string]*memoryItem
+    tags       map[string]map[string]struct{}
+    maxEntries int
+}
+
+// NewMemoryStore creates an in-memory store holding at most maxEntries entries.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+    if maxEntries <= 0 {
+        maxEntries = 10000
+    }
+    return &MemoryStore{
+        items:      map[string]*memoryItem{},
+        tags:       map[string]map[string]struct{}{},
+        maxEntries: maxEntries,
+    }
+}
+
+func (m *MemoryStore) Get(_ context.Context, key string) (*Entry, bool, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    it, ok := m.items[key]
+    if !ok {
+        return nil, false, nil
+    }
+    if time.Now().After(it.expires) {
+        m.removeLocked(key)
+        return nil, false, nil
+    }
+    return it.entry, true, nil
+}
+
+func (m *MemoryStore) Set(_ context.Context, key string, e *Entry, ttl time.Duration, tags []string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.removeLocked(key)
+    if len(m.items) >= m.maxEntries {
+        m.evictLocked()
+    }
+    m.items[key] = &memoryItem{entry: e, expires: time.Now().Add(ttl), tags: tags}
+    for _, t := range tags {
+        if m.tags[t] == nil {
+            m.tags[t] = map[string]struct{}{}
+        }
+        m.tags[t][key] = struct{}{}
+    }
+    return nil
+}
+
+func (m *MemoryStore) PurgeTags(_ context.Context, tags []string) (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    n := 0
+    for _, t := range tags {
+        for key := range m.tags[t] {
+            if _, ok := m.items[key]; ok {
+                m.removeLocked(key)
+                n++
+            }
+        }
+        delete(m.tags, t)
+    }
+    return n, nil
+}
+
+func (m *MemoryStore) removeLocked(key string) {
+    it, ok := m.items[key]
+    if !ok {
+        return
+    }
+    delete(m.items, key)
+    for _, t := range it.tags {
+        if keys := m.tags[t]; keys != nil {
+            delete(keys, key)
+            if len(keys) == 0 {
+                delete(m.tags, t)
+            }
+        }
+    }
+}
+
+// evictLocked drops expired entries, or the entry closest to expiry if none have expired.
+func (m *MemoryStore) evictLocked() {
+    now := time.Now()
+    var oldestKey string
+    var oldest time.Time
+    for key, it := range m.items {
+        if now.After(it.expires) {
+            m.removeLocked(key)
+            continue
+        }
+        if oldestKey == "" || it.expires.Before(oldest) {
+            oldestKey, oldest = key, it.expires
+        }
+    }
+    if len(m.items) >= m.maxEntries && oldestKey != "" {
+        m.removeLocked(oldestKey)
+    }
+}
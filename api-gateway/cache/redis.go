@@ -0,0 +1,91 @@
+package cache
+
+import (
+    "context"
+    "encoding/json"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+const redisPrefix = "gwcache:"
+
+// RedisStore shares cached responses between gateway instances. Each tag is a
+// Redis set of the entry keys carrying it.
+type RedisStore struct {
+    client *redis.Client
+}
+
+// NewRedisStore connects to the Redis server at url (redis://host:port/db).
+func NewRedisStore(ctx context.Context, url string) (*RedisStore, error) {
+    opt, err := redis.ParseURL(url)
+    if err != nil {
+        return nil, err
+    }
+    client := redis.NewClient(opt)
+    if err := client.Ping(ctx).Err(); err != nil {
+        return nil, err
+    }
+    return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, bool, error) {
+    data, err := s.client.Get(ctx, redisPrefix+key).Bytes()
+    if err == redis.Nil {
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    var e Entry
+    if err := json.Unmarshal(data, &e); err != nil {
+        return nil, false, err
+    }
+    return &e, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, e *Entry, ttl time.Duration, tags []string) error {
+    data, err := json.Marshal(e)
+    if err != nil {
+        return err
+    }
+    pipe := s.client.TxPipeline()
+    pipe.Set(ctx, redisPrefix+key, data, ttl)
+    for _, t := range tags {
+        tagKey := redisPrefix + "tag:" + t
+        pipe.SAdd(ctx, tagKey, key)
+        // tag sets outlive their entries a little; stale members are harmless
+        pipe.Expire(ctx, tagKey, ttl+time.Minute)
+    }
+    _, err = pipe.Exec(ctx)
+    return err
+}
+
+func (s *RedisStore) PurgeTags(ctx context.Context, tags []string) (int, error) {
+    n := 0
+    for _, t := range tags {
+        tagKey := redisPrefix + "tag:" + t
+        keys, err := s.client.SMembers(ctx, tagKey).Result()
+        if err != nil {
+            return n, err
+        }
+        full := make([]string, 0, len(keys)+1)
+        for _, k := range keys {
+            full = append(full, redisPrefix+k)
+        }
+        full = append(full, tagKey)
+        deleted, err := s.client.Del(ctx, full...).Result()
+        if err != nil {
+            return n, err
+        }
+        if deleted > 0 {
+            n += int(deleted) - 1
+        }
+    }
+    return n, nil
+}
+
+// Close releases the Redis connection.
+func (s *RedisStore) Close() error {
+    return s.client.Close()
+}
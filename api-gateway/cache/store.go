@@ -0,0 +1,24 @@
+package cache
+
+import (
+    "context"
+    "net/http"
+    "time"
+)
+
+// Entry is a stored upstream response.
+type Entry struct {
+    Status   int         `json:"status"`
+    Header   http.Header `json:"header"`
+    Body     []byte      `json:"body"`
+    ETag     string      `json:"etag"`
+    StoredAt time.Time   `json:"stored_at"`
+}
+
+// Store is a response cache backend. Entries are indexed by tag so they can be
+// purged together.
+type Store interface {
+    Get(ctx context.Context, key string) (*Entry, bool, error)
+    Set(ctx context.Context, key string, e *Entry, ttl time.Duration, tags []string) error
+    PurgeTags(ctx context.Context, tags []string) (int, error)
+}
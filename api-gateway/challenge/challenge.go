@@ -0,0 +1,88 @@
+// Package challenge verifies CAPTCHA-style tokens that clients attach to
+// sensitive requests.
+package challenge
+
+import (
+    "context"
+    "crypto/subtle"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/ansh0014/api/config"
+)
+
+// TokenHeader carries the token solved by the client.
+const TokenHeader = "X-Captcha-Token"
+
+// Verifier checks a challenge token. It returns false for a wrong or expired
+// token and an error only when verification itself could not be done.
+type Verifier interface {
+    Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// New builds the verifier for cfg.Provider, or nil when challenges are off.
+func New(cfg config.ChallengeConfig) (Verifier, error) {
+    switch cfg.Provider {
+    case "", "none":
+        return nil, nil
+    case "fake":
+        return Fake{Token: cfg.Secret}, nil
+    case "siteverify":
+        return &SiteVerify{
+            URL:    cfg.VerifyURL,
+            Secret: cfg.Secret,
+            Client: &http.Client{Timeout: 3 * time.Second},
+        }, nil
+    }
+    return nil, fmt.Errorf("unknown challenge provider %q", cfg.Provider)
+}
+
+// Fake accepts exactly one token, for local development and tests.
+type Fake struct {
+    Token string
+}
+
+// Verify reports whether token equals the configured one.
+func (f Fake) Verify(_ context.Context, token, _ string) (bool, error) {
+    return f.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(f.Token)) == 1, nil
+}
+
+// SiteVerify calls a reCAPTCHA, hCaptcha or Turnstile compatible endpoint:
+// a form POST of secret, response and remoteip answered with {"success": bool}.
+type SiteVerify struct {
+    URL    string
+    Secret string
+    Client *http.Client
+}
+
+// Verify asks the provider whether token is valid for remoteIP.
+func (s *SiteVerify) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+    form := url.Values{"secret": {s.Secret}, "response": {token}}
+    if remoteIP != "" {
+        form.Set("remoteip", remoteIP)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, strings.NewReader(form.Encode()))
+    if err != nil {
+        return false, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    resp, err := s.Client.Do(req)
+    if err != nil {
+        return false, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return false, fmt.Errorf("siteverify: status %d", resp.StatusCode)
+    }
+    var out struct {
+        Success bool `json:"success"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        return false, fmt.Errorf("siteverify: %w", err)
+    }
+    return out.Success, nil
+}
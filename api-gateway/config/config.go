@@ -1,9 +1,13 @@
 package config
 
 import (
+    "encoding/json"
     "errors"
+    "fmt"
+    "net/netip"
     "os"
     "strconv"
+    "strings"
     "time"
 )
 
@@ -14,30 +18,348 @@ type Config struct {
     VenueURL     string
     JWTSecret    string
     Port         string
+    Env          string // "development" or "production"
     ReadTimeout  time.Duration
     WriteTimeout time.Duration
     IdleTimeout  time.Duration
+
+    // ShutdownTimeout bounds connection draining on SIGTERM/SIGINT.
+    ShutdownTimeout time.Duration
+    // BFFCallTimeout bounds each upstream call made by composition endpoints.
+    BFFCallTimeout time.Duration
+
+    // InternalToken authenticates service-to-gateway calls such as cache purges.
+    InternalToken string
+
+    Cache       CacheConfig
+    Streams     StreamConfig
+    Idempotency IdempotencyConfig
+    Versions    VersionConfig
+    Requests    RequestLimits
+    CORS        CORSConfig
+    Security    SecurityConfig
+    Breaker     BreakerConfig
+    Admin       AdminConfig
+    Splits      []SplitConfig
+}
+
+// SplitConfig divides one upstream prefix ("/booking/") between its primary
+// target, the "stable" group, and extra groups such as a canary build.
+type SplitConfig struct {
+    Prefix string       `json:"prefix"`
+    Groups []SplitGroup `json:"groups"`
+}
+
+// SplitGroup is an alternative build of an upstream. Weight is the percentage
+// of users routed to it; the stable group gets the remainder. Users are
+// always routed to it regardless of weight, e.g. internal staff.
+type SplitGroup struct {
+    Name   string   `json:"name"`
+    Target string   `json:"target"`
+    Weight int      `json:"weight"`
+    Users  []string `json:"users"`
+}
+
+// BreakerConfig trips an upstream's circuit after FailureThreshold
+// consecutive failures (transport errors or 5xx); requests then fail fast for
+// CooldownSeconds before a single probe is let through.
+type BreakerConfig struct {
+    FailureThreshold int `json:"failure_threshold"` // 0 disables the breaker
+    CooldownSeconds  int `json:"cooldown_seconds"`
+}
+
+// Cooldown returns how long a tripped circuit stays open.
+func (b BreakerConfig) Cooldown() time.Duration {
+    return time.Duration(b.CooldownSeconds) * time.Second
+}
+
+// AdminConfig configures the operator API. It listens on its own address and
+// is disabled unless Token is set.
+type AdminConfig struct {
+    Addr               string
+    Token              string
+    MaintenanceMessage string // default body for routes in maintenance
+}
+
+// SecurityConfig guards sensitive routes against unwanted networks and bots.
+type SecurityConfig struct {
+    // TrustedProxies are CIDRs whose X-Forwarded-For is believed when
+    // resolving the client IP; otherwise the connection address is used.
+    TrustedProxies []string        `json:"trusted_proxies"`
+    IPRules        []IPRule        `json:"ip_rules"`
+    Challenge      ChallengeConfig `json:"challenge"`
+    Bots           BotConfig       `json:"bots"`
+}
+
+// IPRule restricts requests under PathPrefix ("/" for everything). Deny wins
+// over Allow; a non-empty Allow admits only the listed networks. Entries are
+// CIDRs or bare IPs.
+type IPRule struct {
+    PathPrefix string   `json:"path_prefix"`
+    Allow      []string `json:"allow"`
+    Deny       []string `json:"deny"`
+}
+
+// ChallengeConfig requires a CAPTCHA-style token on Routes. Provider is
+// "none", "fake" (accepts Secret as the token, for local use) or "siteverify"
+// (a reCAPTCHA/hCaptcha/Turnstile compatible endpoint at VerifyURL).
+type ChallengeConfig struct {
+    Provider  string          `json:"provider"`
+    Secret    string          `json:"secret"`
+    VerifyURL string          `json:"verify_url"`
+    Routes    []EndpointMatch `json:"routes"`
+}
+
+// BotConfig blocks clients on Routes by user agent and request velocity.
+// More than MaxRequests within WindowSeconds from one IP blocks that IP on
+// these routes for BlockSeconds. BlockedAgents are case-insensitive
+// substrings; an empty user agent is always blocked.
+type BotConfig struct {
+    Routes        []EndpointMatch `json:"routes"`
+    WindowSeconds int             `json:"window_seconds"`
+    MaxRequests   int             `json:"max_requests"` // 0 disables velocity checks
+    BlockSeconds  int             `json:"block_seconds"`
+    BlockedAgents []string        `json:"blocked_agents"`
+}
+
+// CORSConfig is the single CORS policy for the platform; upstream services do
+// not set CORS headers themselves. Origins are exact ("https://app.example.com"),
+// subdomain wildcards ("https://*.example.com") or "*", which is never echoed
+// together with credentials. Routes override the origins and credentials for
+// matching path prefixes.
+type CORSConfig struct {
+    AllowedOrigins   []string    `json:"allowed_origins"`
+    AllowedMethods   []string    `json:"allowed_methods"`
+    AllowedHeaders   []string    `json:"allowed_headers"`
+    ExposedHeaders   []string    `json:"exposed_headers"`
+    AllowCredentials bool        `json:"allow_credentials"`
+    MaxAgeSeconds    int         `json:"max_age_seconds"` // preflight cache lifetime
+    Routes           []CORSRoute `json:"routes"`
+}
+
+// CORSRoute narrows or widens the policy under a path prefix. A nil
+// AllowCredentials keeps the global setting.
+type CORSRoute struct {
+    PathPrefix       string   `json:"path_prefix"`
+    AllowedOrigins   []string `json:"allowed_origins"`
+    AllowCredentials *bool    `json:"allow_credentials,omitempty"`
+}
+
+// RequestLimits bounds request bodies at the edge. The first rule matching
+// the method and path (with "*" segment wildcards) applies; requests matching
+// no rule get MaxBody and, when they carry a body, DefaultContentTypes.
+type RequestLimits struct {
+    MaxBody             int64         `json:"max_body_bytes"`
+    DefaultContentTypes []string      `json:"default_content_types"`
+    Rules               []RequestRule `json:"rules"`
+}
+
+// RequestRule limits one route. An empty ContentTypes list allows any type.
+type RequestRule struct {
+    Method       string   `json:"method"`
+    Path         string   `json:"path"`
+    MaxBody      int64    `json:"max_body_bytes"`
+    ContentTypes []string `json:"content_types"`
+}
+
+// VersionConfig maps public API versions onto upstream paths. Clients pick a
+// version with a /v{N} path prefix or an Accept-Version header; Default
+// applies when they do neither.
+type VersionConfig struct {
+    Default  string       `json:"default"`
+    Versions []APIVersion `json:"versions"`
+}
+
+// APIVersion describes one public version. Deprecation and Sunset are dates
+// (RFC 3339 or YYYY-MM-DD); after Sunset the version answers 410 Gone.
+type APIVersion struct {
+    Name        string        `json:"name"`
+    Deprecation string        `json:"deprecation,omitempty"`
+    Sunset      string        `json:"sunset,omitempty"`
+    Link        string        `json:"link,omitempty"` // migration guide
+    Rewrites    []PathRewrite `json:"rewrites,omitempty"`
+}
+
+// PathRewrite replaces a gateway path prefix before proxying, e.g.
+// "/booking/api/" to "/booking/api/v2/".
+type PathRewrite struct {
+    From string `json:"from"`
+    To   string `json:"to"`
+}
+
+// IdempotencyConfig lists the create endpoints that accept an Idempotency-Key.
+// When Require is set the gateway rejects those requests without one.
+type IdempotencyConfig struct {
+    Require bool            `json:"require"`
+    Routes  []EndpointMatch `json:"routes"`
+}
+
+// EndpointMatch identifies a gateway endpoint by method and path. Path
+// segments may be "*" to match any single segment.
+type EndpointMatch struct {
+    Method string `json:"method"`
+    Path   string `json:"path"`
+}
+
+// StreamConfig limits long-lived WebSocket and SSE connections.
+type StreamConfig struct {
+    MaxConns   int `json:"max_conns"`    // across the gateway, 0 = unlimited
+    MaxPerUser int `json:"max_per_user"` // per authenticated user, 0 = unlimited
+}
+
+// CacheConfig controls the gateway response cache.
+type CacheConfig struct {
+    Enabled    bool        `json:"enabled"`
+    Backend    string      `json:"backend"` // "memory" or "redis"
+    RedisURL   string      `json:"redis_url"`
+    MaxEntries int         `json:"max_entries"`
+    MaxBody    int64       `json:"max_body_bytes"`
+    Rules      []CacheRule `json:"rules"`
+}
+
+// CacheRule marks a route as cacheable. Path segments may be "*" to match any
+// single segment. A TTL of zero explicitly disables caching for the route.
+type CacheRule struct {
+    Method     string   `json:"method"`
+    Path       string   `json:"path"`
+    TTLSeconds int      `json:"ttl_seconds"`
+    Tags       []string `json:"tags"`
+}
+
+// TTL returns the rule's time-to-live.
+func (r CacheRule) TTL() time.Duration {
+    return time.Duration(r.TTLSeconds) * time.Second
+}
+
+// fileConfig is the shape of the optional JSON file named by GATEWAY_CONFIG_FILE.
+type fileConfig struct {
+    Cache       *CacheConfig       `json:"cache"`
+    Streams     *StreamConfig      `json:"streams"`
+    Idempotency *IdempotencyConfig `json:"idempotency"`
+    Versions    *VersionConfig     `json:"versions"`
+    Requests    *RequestLimits     `json:"requests"`
+    CORS        *CORSConfig        `json:"cors"`
+    Security    *SecurityConfig    `json:"security"`
+    Breaker     *BreakerConfig     `json:"breaker"`
+    Splits      []SplitConfig      `json:"splits"`
 }
 
 // Load reads configuration from environment variables and returns a Config.
+// Structured settings (cache rules) come from the JSON file named by
+// GATEWAY_CONFIG_FILE when set, otherwise built-in defaults are used.
 func Load() (*Config, error) {
     c := &Config{
-        AuthURL:    os.Getenv("AUTH_SERVICE_URL"),
-        BookingURL: os.Getenv("BOOKING_SERVICE_URL"),
-        PaymentURL: os.Getenv("PAYMENT_SERVICE_URL"),
-        VenueURL:   os.Getenv("VENUE_SERVICE_URL"),
-        JWTSecret:  os.Getenv("JWT_SECRET"),
-        Port:       os.Getenv("GATEWAY_PORT"),
+        AuthURL:       os.Getenv("AUTH_SERVICE_URL"),
+        BookingURL:    os.Getenv("BOOKING_SERVICE_URL"),
+        PaymentURL:    os.Getenv("PAYMENT_SERVICE_URL"),
+        VenueURL:      os.Getenv("VENUE_SERVICE_URL"),
+        JWTSecret:     os.Getenv("JWT_SECRET"),
+        Port:          os.Getenv("GATEWAY_PORT"),
+        Env:           os.Getenv("GATEWAY_ENV"),
+        InternalToken: os.Getenv("GATEWAY_INTERNAL_TOKEN"),
+        Cache:         defaultCacheConfig(),
+        Streams:       StreamConfig{MaxConns: 1000, MaxPerUser: 5},
+        Versions:      VersionConfig{Default: "v1", Versions: []APIVersion{{Name: "v1"}}},
+        Requests:      defaultRequestLimits(),
+        Security:      defaultSecurityConfig(),
+        Breaker:       BreakerConfig{FailureThreshold: 5, CooldownSeconds: 30},
+        Admin: AdminConfig{
+            Addr:               os.Getenv("GATEWAY_ADMIN_ADDR"),
+            Token:              os.Getenv("GATEWAY_ADMIN_TOKEN"),
+            MaintenanceMessage: os.Getenv("GATEWAY_MAINTENANCE_MESSAGE"),
+        },
+        Idempotency: IdempotencyConfig{Routes: []EndpointMatch{
+            {Method: "POST", Path: "/booking/api/bookings"},
+            {Method: "POST", Path: "/payment/api/payments"},
+        }},
     }
 
     if c.Port == "" {
         c.Port = "8080"
     }
+    if c.Admin.Addr == "" {
+        c.Admin.Addr = "127.0.0.1:9090"
+    }
+    if c.Admin.MaintenanceMessage == "" {
+        c.Admin.MaintenanceMessage = "This service is undergoing maintenance. Please try again shortly."
+    }
+    if c.Env == "" {
+        c.Env = "development"
+    }
+    c.CORS = defaultCORSConfig(c.Env)
 
     // timeouts (in seconds)
     c.ReadTimeout = parseEnvDuration("GATEWAY_READ_TIMEOUT", 15*time.Second)
     c.WriteTimeout = parseEnvDuration("GATEWAY_WRITE_TIMEOUT", 20*time.Second)
     c.IdleTimeout = parseEnvDuration("GATEWAY_IDLE_TIMEOUT", 60*time.Second)
+    c.ShutdownTimeout = parseEnvDuration("GATEWAY_SHUTDOWN_TIMEOUT", 20*time.Second)
+    c.BFFCallTimeout = parseEnvDuration("GATEWAY_BFF_CALL_TIMEOUT", 2*time.Second)
+
+    if path := os.Getenv("GATEWAY_CONFIG_FILE"); path != "" {
+        if err := c.loadFile(path); err != nil {
+            return nil, err
+        }
+    }
+
+    // env overrides for the cache
+    if v := os.Getenv("GATEWAY_CACHE_ENABLED"); v != "" {
+        c.Cache.Enabled = v == "true" || v == "1"
+    }
+    if v := os.Getenv("GATEWAY_CACHE_BACKEND"); v != "" {
+        c.Cache.Backend = v
+    }
+    if v := os.Getenv("GATEWAY_CACHE_REDIS_URL"); v != "" {
+        c.Cache.RedisURL = v
+    }
+
+    if v := os.Getenv("GATEWAY_CORS_ALLOWED_ORIGINS"); v != "" {
+        c.CORS.AllowedOrigins = splitList(v)
+    }
+    if v := os.Getenv("GATEWAY_CORS_ALLOW_CREDENTIALS"); v != "" {
+        c.CORS.AllowCredentials = v == "true" || v == "1"
+    }
+    c.CORS.MaxAgeSeconds = parseEnvInt("GATEWAY_CORS_MAX_AGE", c.CORS.MaxAgeSeconds)
+
+    // shortcut for the common case of one Booking-service canary
+    if v := os.Getenv("BOOKING_CANARY_URL"); v != "" {
+        c.Splits = append(c.Splits, SplitConfig{Prefix: "/booking/", Groups: []SplitGroup{{
+            Name:   "canary",
+            Target: v,
+            Weight: parseEnvInt("BOOKING_CANARY_WEIGHT", 0),
+            Users:  splitList(os.Getenv("BOOKING_CANARY_USERS")),
+        }}})
+    }
+
+    if v := os.Getenv("GATEWAY_TRUSTED_PROXIES"); v != "" {
+        c.Security.TrustedProxies = splitList(v)
+    }
+    if v := os.Getenv("GATEWAY_DENY_CIDRS"); v != "" {
+        c.Security.IPRules = append(c.Security.IPRules, IPRule{PathPrefix: "/", Deny: splitList(v)})
+    }
+    if v := os.Getenv("GATEWAY_WEBHOOK_ALLOWED_CIDRS"); v != "" {
+        c.Security.IPRules = append(c.Security.IPRules, IPRule{PathPrefix: "/payment/api/webhook", Allow: splitList(v)})
+    }
+    if v := os.Getenv("GATEWAY_CHALLENGE_PROVIDER"); v != "" {
+        c.Security.Challenge.Provider = v
+    }
+    if v := os.Getenv("GATEWAY_CHALLENGE_SECRET"); v != "" {
+        c.Security.Challenge.Secret = v
+    }
+    if v := os.Getenv("GATEWAY_CHALLENGE_VERIFY_URL"); v != "" {
+        c.Security.Challenge.VerifyURL = v
+    }
+    c.Security.Bots.MaxRequests = parseEnvInt("GATEWAY_BOT_MAX_REQUESTS", c.Security.Bots.MaxRequests)
+
+    if v := os.Getenv("GATEWAY_MAX_BODY_BYTES"); v != "" {
+        if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+            c.Requests.MaxBody = n
+        }
+    }
+    if v := os.Getenv("GATEWAY_REQUIRE_IDEMPOTENCY_KEY"); v != "" {
+        c.Idempotency.Require = v == "true" || v == "1"
+    }
+    c.Streams.MaxConns = parseEnvInt("GATEWAY_STREAM_MAX_CONNS", c.Streams.MaxConns)
+    c.Streams.MaxPerUser = parseEnvInt("GATEWAY_STREAM_MAX_PER_USER", c.Streams.MaxPerUser)
 
     if err := c.Validate(); err != nil {
         return nil, err
@@ -45,6 +367,45 @@ func Load() (*Config, error) {
     return c, nil
 }
 
+func (c *Config) loadFile(path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("read %s: %w", path, err)
+    }
+    // CORS fields missing from the file keep their defaults
+    cors := c.CORS
+    fc := fileConfig{CORS: &cors}
+    if err := json.Unmarshal(data, &fc); err != nil {
+        return fmt.Errorf("parse %s: %w", path, err)
+    }
+    if fc.Cache != nil {
+        c.Cache = *fc.Cache
+    }
+    if fc.Streams != nil {
+        c.Streams = *fc.Streams
+    }
+    if fc.Idempotency != nil {
+        c.Idempotency = *fc.Idempotency
+    }
+    if fc.Versions != nil {
+        c.Versions = *fc.Versions
+    }
+    if fc.Requests != nil {
+        c.Requests = *fc.Requests
+    }
+    if fc.Security != nil {
+        c.Security = *fc.Security
+    }
+    if fc.Breaker != nil {
+        c.Breaker = *fc.Breaker
+    }
+    if fc.Splits != nil {
+        c.Splits = fc.Splits
+    }
+    c.CORS = cors
+    return nil
+}
+
 func parseEnvDuration(name string, def time.Duration) time.Duration {
     if v := os.Getenv(name); v != "" {
         if s, err := strconv.Atoi(v); err == nil && s >= 0 {
@@ -54,6 +415,114 @@ func parseEnvDuration(name string, def time.Duration) time.Duration {
     return def
 }
 
+func parseEnvInt(name string, def int) int {
+    if v := os.Getenv(name); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+            return n
+        }
+    }
+    return def
+}
+
+func splitList(v string) []string {
+    var out []string
+    for _, s := range strings.Split(v, ",") {
+        if s = strings.TrimSpace(s); s != "" {
+            out = append(out, s)
+        }
+    }
+    return out
+}
+
+// defaultCORSConfig allows local frontends in development. Production has no
+// default origins; they must come from GATEWAY_CORS_ALLOWED_ORIGINS or the
+// config file.
+func defaultCORSConfig(env string) CORSConfig {
+    c := CORSConfig{
+        AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+        AllowedHeaders: []string{"Content-Type", "Authorization", "X-Requested-With", "X-Request-ID", "If-None-Match", "Idempotency-Key", "Accept-Version", "X-Captcha-Token", "X-Upstream-Group"},
+        ExposedHeaders: []string{
+            "X-Request-ID", "ETag", "X-Cache", "Idempotent-Replayed",
+            "API-Version", "Deprecation", "Sunset", "Link",
+            "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-Upstream-Group",
+        },
+        AllowCredentials: true,
+        MaxAgeSeconds:    600,
+    }
+    if env != "production" {
+        c.AllowedOrigins = []string{"http://localhost:3000", "http://localhost:5173", "http://127.0.0.1:3000", "http://127.0.0.1:5173"}
+    }
+    return c
+}
+
+// defaultSecurityConfig watches the routes scalpers and credential stuffers
+// hit: registration, login and seat locking. Challenges stay off until a
+// provider is configured.
+func defaultSecurityConfig() SecurityConfig {
+    sensitive := []EndpointMatch{
+        {Method: "POST", Path: "/auth/register"},
+        {Method: "POST", Path: "/auth/login"},
+        {Method: "POST", Path: "/booking/api/seats/lock"},
+        {Method: "POST", Path: "/booking/api/platforms/*/seats/lock"},
+    }
+    return SecurityConfig{
+        Challenge: ChallengeConfig{Provider: "none", Routes: sensitive},
+        Bots: BotConfig{
+            Routes:        sensitive,
+            WindowSeconds: 60,
+            MaxRequests:   60,
+            BlockSeconds:  300,
+            BlockedAgents: []string{"scrapy", "headlesschrome", "phantomjs", "selenium", "puppeteer", "playwright"},
+        },
+    }
+}
+
+// defaultRequestLimits keeps API bodies small and JSON; webhooks may be form
+// encoded and larger.
+func defaultRequestLimits() RequestLimits {
+    json := []string{"application/json"}
+    return RequestLimits{
+        MaxBody:             1 << 20,
+        DefaultContentTypes: json,
+        Rules: []RequestRule{
+            {Method: "POST", Path: "/auth/*", MaxBody: 8 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/booking/api/bookings", MaxBody: 16 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/booking/api/seats/lock", MaxBody: 16 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/booking/api/platforms/*/seats/lock", MaxBody: 16 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/booking/api/platforms/*/search", MaxBody: 8 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/payment/api/payments", MaxBody: 16 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/payment/api/payments/*", MaxBody: 8 << 10, ContentTypes: json},
+            {Method: "POST", Path: "/payment/api/webhook", MaxBody: 256 << 10, ContentTypes: []string{"application/json", "application/x-www-form-urlencoded"}},
+            {Method: "POST", Path: "/payment/api/webhook/*", MaxBody: 256 << 10, ContentTypes: []string{"application/json", "application/x-www-form-urlencoded"}},
+        },
+    }
+}
+
+// defaultCacheConfig caches public catalog reads in memory.
+func defaultCacheConfig() CacheConfig {
+    return CacheConfig{
+        Enabled:    true,
+        Backend:    "memory",
+        MaxEntries: 10000,
+        MaxBody:    1 << 20,
+        Rules: []CacheRule{
+            // seat maps change on every lock and must never be cached
+            {Method: "GET", Path: "/booking/api/platforms/movie/seats", TTLSeconds: 0},
+            {Method: "GET", Path: "/booking/api/platforms/movie", TTLSeconds: 60, Tags: []string{"movies"}},
+            {Method: "GET", Path: "/booking/api/platforms/movie/*", TTLSeconds: 60, Tags: []string{"movies"}},
+            {Method: "GET", Path: "/booking/api/platforms/movie/*/shows", TTLSeconds: 30, Tags: []string{"movies", "shows"}},
+            {Method: "POST", Path: "/booking/api/platforms/movie/search", TTLSeconds: 30, Tags: []string{"movies"}},
+            {Method: "POST", Path: "/booking/api/platforms/flight/search", TTLSeconds: 30, Tags: []string{"flights"}},
+            {Method: "POST", Path: "/booking/api/platforms/railway/search", TTLSeconds: 30, Tags: []string{"trains"}},
+            {Method: "POST", Path: "/booking/api/platforms/event/search", TTLSeconds: 30, Tags: []string{"events"}},
+            {Method: "GET", Path: "/venue/venues", TTLSeconds: 120, Tags: []string{"venues"}},
+            {Method: "GET", Path: "/venue/venues/*", TTLSeconds: 120, Tags: []string{"venues"}},
+            {Method: "GET", Path: "/venue/venues/*/halls", TTLSeconds: 120, Tags: []string{"venues", "halls"}},
+            {Method: "GET", Path: "/venue/halls/*/seats", TTLSeconds: 120, Tags: []string{"halls"}},
+        },
+    }
+}
+
 // Validate ensures required fields are provided.
 func (c *Config) Validate() error {
     if c.AuthURL == "" {
@@ -68,5 +537,145 @@ func (c *Config) Validate() error {
     if c.VenueURL == "" {
         return errors.New("VENUE_SERVICE_URL is required")
     }
+    if err := c.Versions.validate(); err != nil {
+        return err
+    }
+    if err := c.CORS.validate(c.Env); err != nil {
+        return err
+    }
+    if err := c.Security.validate(); err != nil {
+        return err
+    }
+    for _, s := range c.Splits {
+        if err := s.validate(); err != nil {
+            return err
+        }
+    }
+    if c.Cache.Enabled {
+        switch c.Cache.Backend {
+        case "memory":
+        case "redis":
+            if c.Cache.RedisURL == "" {
+                return errors.New("cache backend redis requires GATEWAY_CACHE_REDIS_URL")
+            }
+        default:
+            return fmt.Errorf("unknown cache backend %q", c.Cache.Backend)
+        }
+    }
+    return nil
+}
+
+func (s SplitConfig) validate() error {
+    if !strings.HasPrefix(s.Prefix, "/") || !strings.HasSuffix(s.Prefix, "/") {
+        return fmt.Errorf("split prefix %q must look like /booking/", s.Prefix)
+    }
+    total := 0
+    seen := map[string]bool{"stable": true}
+    for _, g := range s.Groups {
+        if g.Name == "" || seen[g.Name] {
+            return fmt.Errorf("split %s: group name %q is empty, reserved or duplicated", s.Prefix, g.Name)
+        }
+        seen[g.Name] = true
+        if g.Target == "" {
+            return fmt.Errorf("split %s: group %s has no target", s.Prefix, g.Name)
+        }
+        if g.Weight < 0 {
+            return fmt.Errorf("split %s: group %s has a negative weight", s.Prefix, g.Name)
+        }
+        total += g.Weight
+    }
+    if total > 100 {
+        return fmt.Errorf("split %s: weights add up to %d%%", s.Prefix, total)
+    }
+    return nil
+}
+
+func (s SecurityConfig) validate() error {
+    nets := append([]string{}, s.TrustedProxies...)
+    for _, rule := range s.IPRules {
+        nets = append(append(nets, rule.Allow...), rule.Deny...)
+    }
+    for _, n := range nets {
+        if _, err := ParsePrefix(n); err != nil {
+            return err
+        }
+    }
+    switch s.Challenge.Provider {
+    case "", "none":
+    case "fake":
+        if s.Challenge.Secret == "" {
+            return errors.New("challenge provider fake requires GATEWAY_CHALLENGE_SECRET")
+        }
+    case "siteverify":
+        if s.Challenge.Secret == "" || s.Challenge.VerifyURL == "" {
+            return errors.New("challenge provider siteverify requires GATEWAY_CHALLENGE_SECRET and GATEWAY_CHALLENGE_VERIFY_URL")
+        }
+    default:
+        return fmt.Errorf("unknown challenge provider %q", s.Challenge.Provider)
+    }
+    return nil
+}
+
+// ParsePrefix parses a CIDR or a bare IP, which covers just that address.
+func ParsePrefix(s string) (netip.Prefix, error) {
+    if strings.Contains(s, "/") {
+        p, err := netip.ParsePrefix(s)
+        if err != nil {
+            return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+        }
+        return p.Masked(), nil
+    }
+    a, err := netip.ParseAddr(s)
+    if err != nil {
+        return netip.Prefix{}, fmt.Errorf("invalid IP %q", s)
+    }
+    a = a.Unmap()
+    return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+func (c CORSConfig) validate(env string) error {
+    if env == "production" && len(c.AllowedOrigins) == 0 {
+        return errors.New("GATEWAY_CORS_ALLOWED_ORIGINS is required in production")
+    }
+    for _, o := range c.AllowedOrigins {
+        if o == "*" && env == "production" {
+            return errors.New("cors origin \"*\" is not allowed in production")
+        }
+    }
+    for _, rt := range c.Routes {
+        if !strings.HasPrefix(rt.PathPrefix, "/") {
+            return fmt.Errorf("cors route %q must start with /", rt.PathPrefix)
+        }
+    }
     return nil
 }
+
+func (v VersionConfig) validate() error {
+    known := map[string]bool{}
+    for _, ver := range v.Versions {
+        if !strings.HasPrefix(ver.Name, "v") || len(ver.Name) < 2 {
+            return fmt.Errorf("api version %q must look like v1", ver.Name)
+        }
+        for _, d := range []string{ver.Deprecation, ver.Sunset} {
+            if d == "" {
+                continue
+            }
+            if _, err := ParseDate(d); err != nil {
+                return fmt.Errorf("api version %s: %w", ver.Name, err)
+            }
+        }
+        known[ver.Name] = true
+    }
+    if v.Default != "" && !known[v.Default] {
+        return fmt.Errorf("default api version %q is not configured", v.Default)
+    }
+    return nil
+}
+
+// ParseDate accepts RFC 3339 timestamps or plain YYYY-MM-DD dates.
+func ParseDate(s string) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return t, nil
+    }
+    return time.Parse("2006-01-02", s)
+}
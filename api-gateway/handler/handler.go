@@ -6,7 +6,10 @@ import (
     "strings"
     "time"
 
+    "github.com/ansh0014/api/internal"
+    "github.com/ansh0014/api/metrics"
     "github.com/ansh0014/api/pkg"
+    "go.opentelemetry.io/otel/trace"
 )
 
 // Handler holds the proxy map and implements HTTP handling for gateway forwarding.
@@ -22,8 +25,8 @@ func New(pm *pkg.ProxyMap) http.Handler {
 // ServeHTTP routes requests to the appropriate upstream reverse proxy.
 // It also sets common proxy headers (X-Forwarded-For, X-Real-IP, X-Request-ID) if missing.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-    p := h.pm.Route(r)
-    if p == nil {
+    prefix, rt := h.pm.Match(r)
+    if rt == nil {
         http.NotFound(w, r)
         return
     }
@@ -41,13 +44,38 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
         }
     }
 
-    // X-Request-ID
-    if r.Header.Get("X-Request-ID") == "" {
-        r.Header.Set("X-Request-ID", generateReqID())
+    // X-Request-ID (normally already set by middleware.RequestID)
+    info := internal.RequestInfoFrom(r.Context())
+    info.Route = prefix
+    trace.SpanFromContext(r.Context()).SetName(r.Method + " " + prefix)
+    if r.Header.Get(internal.RequestIDHeader) == "" {
+        if info.ID == "" {
+            info.ID = internal.NewRequestID()
+        }
+        r.Header.Set(internal.RequestIDHeader, info.ID)
     }
 
-    // Forward to matched reverse proxy
-    p.ServeHTTP(w, r)
+    // Forward to the chosen group unless it is drained, ejected or tripped;
+    // an unavailable canary falls back to the stable group
+    up := rt.Pick(r)
+    reason := up.Admit()
+    if reason != "" && up != rt.Stable() {
+        up = rt.Stable()
+        reason = up.Admit()
+    }
+    if reason != "" {
+        w.Header().Set("Retry-After", "30")
+        http.Error(w, "upstream unavailable: "+reason, http.StatusServiceUnavailable)
+        return
+    }
+    defer up.Done()
+    if rt.Split() {
+        w.Header().Set(pkg.GroupHeader, up.Group)
+    }
+
+    start := time.Now()
+    up.ServeHTTP(w, r)
+    metrics.UpstreamLatency.WithLabelValues(prefix, up.Group).Observe(time.Since(start).Seconds())
 }
 
 func realIP(r *http.Request) string {
@@ -66,7 +94,3 @@ func realIP(r *http.Request) string {
     }
     return ""
 }
-
-func generateReqID() string {
-    return time.Now().UTC().Format("20060102T150405.000000000")
-}
\ No newline at end of file
@@ -0,0 +1,64 @@
+package handler
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/pkg"
+)
+
+// named answers every request with its name
+func named(name string) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, name)
+    }))
+}
+
+func TestUnavailableCanaryFallsBackToStable(t *testing.T) {
+    stable, canary := named("stable"), named("canary")
+    defer stable.Close()
+    defer canary.Close()
+
+    pm := pkg.NewProxyMap(
+        map[string]string{"/booking/": stable.URL},
+        []config.SplitConfig{{
+            Prefix: "/booking/",
+            Groups: []config.SplitGroup{{Name: "canary", Target: canary.URL, Weight: 100}},
+        }},
+        config.BreakerConfig{},
+    )
+    h := New(pm)
+    ups := pm.Upstreams()
+
+    get := func() (int, string, string) {
+        rec := httptest.NewRecorder()
+        h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/booking/api/bookings", nil))
+        return rec.Code, rec.Body.String(), rec.Header().Get(pkg.GroupHeader)
+    }
+
+    if code, body, group := get(); code != http.StatusOK || body != "canary" || group != "canary" {
+        t.Fatalf("healthy canary: %d %q group %q", code, body, group)
+    }
+
+    for _, state := range []string{pkg.StateDraining, pkg.StateEjected} {
+        ups["booking:canary"].SetState(state)
+        if code, body, group := get(); code != http.StatusOK || body != "stable" || group != pkg.StableGroup {
+            t.Fatalf("%s canary: %d %q group %q, want stable", state, code, body, group)
+        }
+    }
+
+    // with the stable group gone too there is nothing left to serve
+    ups["booking"].SetState(pkg.StateEjected)
+    if code, _, _ := get(); code != http.StatusServiceUnavailable {
+        t.Fatalf("no available group: %d, want 503", code)
+    }
+
+    ups["booking"].SetState(pkg.StateActive)
+    ups["booking:canary"].SetState(pkg.StateActive)
+    if _, body, _ := get(); body != "canary" {
+        t.Fatalf("restored canary not picked again, got %q", body)
+    }
+}
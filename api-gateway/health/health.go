@@ -0,0 +1,125 @@
+// Package health aggregates the readiness of every upstream service.
+package health
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/ansh0014/api/internal"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Status values
+const (
+    StatusOK          = "ok"
+    StatusUnavailable = "unavailable"
+)
+
+// readyPath is the readiness endpoint every service exposes.
+const readyPath = "/health/ready"
+
+// Upstream is one service's readiness as seen from the gateway.
+type Upstream struct {
+    Status     string          `json:"status"`
+    HTTPStatus int             `json:"http_status,omitempty"`
+    LatencyMS  int64           `json:"latency_ms"`
+    Error      string          `json:"error,omitempty"`
+    Report     json.RawMessage `json:"report,omitempty"`
+}
+
+// Report is the /health/full response body.
+type Report struct {
+    Status   string              `json:"status"`
+    Services map[string]Upstream `json:"services"`
+}
+
+// Aggregator fans readiness probes out to the upstream services.
+type Aggregator struct {
+    targets map[string]string
+    client  *http.Client
+}
+
+// NewAggregator probes each named base URL; timeout bounds each probe.
+func NewAggregator(targets map[string]string, timeout time.Duration) *Aggregator {
+    return &Aggregator{
+        targets: targets,
+        client:  &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+    }
+}
+
+// Run probes all upstreams concurrently.
+func (a *Aggregator) Run(ctx context.Context) Report {
+    rep := Report{Status: StatusOK, Services: make(map[string]Upstream, len(a.targets))}
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    for name, base := range a.targets {
+        wg.Add(1)
+        go func(name, base string) {
+            defer wg.Done()
+            up := a.probe(ctx, base)
+            mu.Lock()
+            rep.Services[name] = up
+            if up.Status != StatusOK {
+                rep.Status = StatusUnavailable
+            }
+            mu.Unlock()
+        }(name, base)
+    }
+    wg.Wait()
+    return rep
+}
+
+func (a *Aggregator) probe(ctx context.Context, base string) Upstream {
+    start := time.Now()
+    up := Upstream{Status: StatusUnavailable}
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+readyPath, nil)
+    if err != nil {
+        up.Error = err.Error()
+        return up
+    }
+    if id := internal.RequestInfoFrom(ctx).ID; id != "" {
+        req.Header.Set(internal.RequestIDHeader, id)
+    }
+
+    res, err := a.client.Do(req)
+    up.LatencyMS = time.Since(start).Milliseconds()
+    if err != nil {
+        up.Error = err.Error()
+        return up
+    }
+    defer res.Body.Close()
+
+    up.HTTPStatus = res.StatusCode
+    body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+    if json.Valid(body) {
+        up.Report = body
+    }
+    if res.StatusCode == http.StatusOK {
+        up.Status = StatusOK
+    } else {
+        up.Error = fmt.Sprintf("readiness returned %d", res.StatusCode)
+    }
+    return up
+}
+
+// Handler serves the aggregated report: 200 when every upstream is ready,
+// otherwise 503.
+func (a *Aggregator) Handler(w http.ResponseWriter, r *http.Request) {
+    rep := a.Run(r.Context())
+    code := http.StatusOK
+    if rep.Status != StatusOK {
+        code = http.StatusServiceUnavailable
+    }
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    w.WriteHeader(code)
+    json.NewEncoder(w).Encode(rep)
+}
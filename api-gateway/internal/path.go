@@ -0,0 +1,19 @@
+package internal
+
+import "strings"
+
+// MatchPath compares a route pattern with a request path segment by segment;
+// a "*" segment matches any single segment.
+func MatchPath(pattern, path string) bool {
+    ps := strings.Split(strings.Trim(pattern, "/"), "/")
+    xs := strings.Split(strings.Trim(path, "/"), "/")
+    if len(ps) != len(xs) {
+        return false
+    }
+    for i := range ps {
+        if ps[i] != "*" && ps[i] != xs[i] {
+            return false
+        }
+    }
+    return true
+}
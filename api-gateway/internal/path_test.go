@@ -0,0 +1,38 @@
+package internal
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+    tests := []struct {
+        pattern, path string
+        want          bool
+    }{
+        {"/auth/login", "/auth/login", true},
+        {"/auth/login", "/auth/login/", true},
+        {"auth/login", "/auth/login", true},
+        {"/auth/login", "/auth/logout", false},
+        {"/auth/login", "/auth", false},
+        {"/auth/login", "/auth/login/extra", false},
+
+        // "*" matches exactly one segment
+        {"/booking/api/platforms/*/seats/lock", "/booking/api/platforms/movie/seats/lock", true},
+        {"/booking/api/platforms/*/seats/lock", "/booking/api/platforms/seats/lock", false},
+        {"/booking/api/platforms/*/seats/lock", "/booking/api/platforms/movie/x/seats/lock", false},
+        {"/venue/api/*", "/venue/api/shows", true},
+        {"/venue/api/*", "/venue/api", false},
+        {"/venue/api/*", "/venue/api/shows/42", false},
+        {"/*/*", "/a/b", true},
+
+        // segments compare exactly
+        {"/Auth/login", "/auth/login", false},
+        {"/auth/log*", "/auth/login", false},
+
+        {"/", "/", true},
+        {"/", "/health", false},
+    }
+    for _, tt := range tests {
+        if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+            t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+        }
+    }
+}
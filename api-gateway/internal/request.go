@@ -0,0 +1,72 @@
+package internal
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+)
+
+// RequestIDHeader is the header used to correlate a request across services.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestInfo carries per-request annotations filled in by inner handlers
+// (matched route, authenticated user) so outer middleware can report on them.
+type RequestInfo struct {
+    ID     string
+    UserID string
+    Route  string
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo stores info in ctx.
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+    return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the RequestInfo stored in ctx, or an empty one if none was set.
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+    if info, ok := ctx.Value(requestInfoKey{}).(*RequestInfo); ok {
+        return info
+    }
+    return &RequestInfo{}
+}
+
+// NewRequestID returns a random RFC 4122 version 4 UUID.
+func NewRequestID() string {
+    var b [16]byte
+    if _, err := rand.Read(b[:]); err != nil {
+        panic("crypto/rand unavailable: " + err.Error())
+    }
+    b[6] = (b[6] & 0x0f) | 0x40
+    b[8] = (b[8] & 0x3f) | 0x80
+    var out [36]byte
+    hex.Encode(out[0:8], b[0:4])
+    out[8] = '-'
+    hex.Encode(out[9:13], b[4:6])
+    out[13] = '-'
+    hex.Encode(out[14:18], b[6:8])
+    out[18] = '-'
+    hex.Encode(out[19:23], b[8:10])
+    out[23] = '-'
+    hex.Encode(out[24:], b[10:])
+    return string(out[:])
+}
+
+// ValidRequestID reports whether a client-supplied request ID is safe to
+// forward: non-empty, at most 128 characters, and limited to [A-Za-z0-9._-].
+func ValidRequestID(id string) bool {
+    if id == "" || len(id) > 128 {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        c := id[i]
+        switch {
+        case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+        case c == '-', c == '_', c == '.':
+        default:
+            return false
+        }
+    }
+    return true
+}
@@ -1,53 +1,166 @@
 package main
 
 import (
+    "context"
+    "io"
     "log"
+    "log/slog"
     "net/http"
     "os"
+    "os/signal"
+    "syscall"
+    "time"
 
+    "github.com/ansh0014/api/admin"
+    "github.com/ansh0014/api/bff"
+    "github.com/ansh0014/api/cache"
+    "github.com/ansh0014/api/challenge"
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/health"
     "github.com/ansh0014/api/middleware"
     "github.com/ansh0014/api/pkg"
     "github.com/ansh0014/api/routes"
-
-    "github.com/gorilla/handlers"
+    "github.com/ansh0014/api/tracing"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func main() {
-    authURL := os.Getenv("AUTH_SERVICE_URL")
-    bookingURL := os.Getenv("BOOKING_SERVICE_URL")
-    paymentURL := os.Getenv("PAYMENT_SERVICE_URL")
-    venueURL := os.Getenv("VENUE_SERVICE_URL")
-    port := os.Getenv("GATEWAY_PORT")
-    if port == "" {
-        port = "8080"
+    slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+    shutdownTracing, err := tracing.Init(context.Background())
+    if err != nil {
+        log.Fatalf("tracing: %v", err)
     }
+    defer shutdownTracing(context.Background())
 
-    if authURL == "" || bookingURL == "" || paymentURL == "" || venueURL == "" {
-        log.Fatal("set AUTH_SERVICE_URL, BOOKING_SERVICE_URL, PAYMENT_SERVICE_URL and VENUE_SERVICE_URL")
+    cfg, err := config.Load()
+    if err != nil {
+        log.Fatal(err)
     }
 
     pm := pkg.NewProxyMap(map[string]string{
-        "/auth/":    authURL,
-        "/booking/": bookingURL,
-        "/payment/": paymentURL,
-        "/venue/":   venueURL,
-    })
-
-    r := routes.NewRouter(pm)
-
-    // apply middlewares: CORS + JWT extract + rate limit + logging
-    cors := handlers.CORS(
-        handlers.AllowedOrigins([]string{"*"}),
-        handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-        handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Requested-With", "X-User-ID"}),
-    )
-
-    h := cors(middleware.JWTExtract(r))
+        "/auth/":    cfg.AuthURL,
+        "/booking/": cfg.BookingURL,
+        "/payment/": cfg.PaymentURL,
+        "/venue/":   cfg.VenueURL,
+    }, cfg.Splits, cfg.Breaker)
+    hc := health.NewAggregator(map[string]string{
+        "auth":    cfg.AuthURL,
+        "booking": cfg.BookingURL,
+        "payment": cfg.PaymentURL,
+        "venue":   cfg.VenueURL,
+    }, 3*time.Second)
+    b := bff.New(bff.Upstreams{
+        Booking: cfg.BookingURL,
+        Payment: cfg.PaymentURL,
+        Venue:   cfg.VenueURL,
+    }, cfg.BFFCallTimeout)
+
+    var c *cache.Cache
+    var store cache.Store
+    if cfg.Cache.Enabled {
+        store, err = cache.NewStore(context.Background(), cfg.Cache)
+        if err != nil {
+            log.Fatalf("cache: %v", err)
+        }
+        c = cache.New(store, cfg.Cache, cfg.InternalToken)
+    }
+
+    r := routes.NewRouter(pm, c, hc, b)
+
+    // apply middlewares: JWT extract + stream limits + bot and IP checks +
+    // versioning + rate limit + CORS + logging
+    verifier, err := challenge.New(cfg.Security.Challenge)
+    if err != nil {
+        log.Fatalf("challenge: %v", err)
+    }
+
+    streams := middleware.NewStreamLimiter(cfg.Streams.MaxConns, cfg.Streams.MaxPerUser)
+    bots := middleware.NewBotGuard(cfg.Security)
+    maintenance := middleware.NewMaintenance(cfg.Admin.MaintenanceMessage)
+
+    h := middleware.RequireIdempotencyKey(cfg.Idempotency)(r)
+    h = middleware.BodyLimits(cfg.Requests)(h)
+    h = maintenance.Middleware(h)
+    h = middleware.StreamToken(middleware.JWTExtract(streams.Middleware(h)))
+    h = middleware.Challenge(cfg.Security, verifier)(h)
+    h = bots.Middleware(h)
+    h = middleware.IPFilter(cfg.Security)(h)
+    // outside the guards so their rules also match /v1/... paths
+    h = middleware.Versioning(cfg.Versions)(h)
     h = middleware.RateLimit(h)
+    // outside the rate limiter so 429s stay readable by browsers
+    h = middleware.CORS(cfg.CORS)(h)
+    h = otelhttp.NewHandler(h, tracing.ServiceName)
+    h = middleware.RequestID(h)
+    h = middleware.AccessLog(h)
 
-    log.Printf("api-gateway listening on :%s", port)
-    logged := handlers.CombinedLoggingHandler(log.Writer(), h)
-    if err := http.ListenAndServe(":"+port, logged); err != nil {
-        log.Fatal(err)
+    srv := &http.Server{
+        Addr:         ":" + cfg.Port,
+        Handler:      h,
+        ReadTimeout:  cfg.ReadTimeout,
+        WriteTimeout: cfg.WriteTimeout,
+        IdleTimeout:  cfg.IdleTimeout,
+    }
+    // long-lived streams would otherwise hold Shutdown until its deadline
+    srv.RegisterOnShutdown(streams.CloseAll)
+
+    // operator API on its own port, off unless a token is configured
+    var adminSrv *http.Server
+    if cfg.Admin.Token != "" {
+        adminSrv = &http.Server{
+            Addr: cfg.Admin.Addr,
+            Handler: admin.New(cfg.Admin.Token, admin.Deps{
+                Router:      r,
+                Proxies:     pm,
+                Health:      hc,
+                Cache:       c,
+                CacheRules:  cfg.Cache.Rules,
+                Streams:     streams,
+                Bots:        bots,
+                Maintenance: maintenance,
+            }).Handler(),
+            ReadTimeout:  cfg.ReadTimeout,
+            WriteTimeout: cfg.WriteTimeout,
+        }
+    } else {
+        log.Println("admin API disabled: GATEWAY_ADMIN_TOKEN not set")
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    go func() {
+        log.Printf("api-gateway listening on :%s", cfg.Port)
+        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Fatal(err)
+        }
+    }()
+
+    if adminSrv != nil {
+        go func() {
+            log.Printf("admin API listening on %s", cfg.Admin.Addr)
+            if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                log.Fatal(err)
+            }
+        }()
+    }
+
+    <-ctx.Done()
+    stop()
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+    defer cancel()
+    log.Println("api-gateway shutting down")
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        log.Printf("forced shutdown: %v", err)
+    }
+    if adminSrv != nil {
+        adminSrv.Shutdown(shutdownCtx)
+    }
+    if closer, ok := store.(io.Closer); ok {
+        if err := closer.Close(); err != nil {
+            log.Printf("cache close: %v", err)
+        }
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,93 @@
+// Package metrics defines the Prometheus metrics exported by the gateway on /metrics.
+package metrics
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gateway"
+
+var (
+    // HTTPRequests counts handled requests by method, route and status
+    HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "http_requests_total",
+        Help:      "HTTP requests handled, by method, route and status.",
+    }, []string{"method", "route", "status"})
+
+    // HTTPDuration observes request latency by method, route and status
+    HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "http_request_duration_seconds",
+        Help:      "HTTP request latency, by method, route and status.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"method", "route", "status"})
+
+    // UpstreamErrors counts proxy failures reaching an upstream
+    UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "upstream_errors_total",
+        Help:      "Requests that failed to reach an upstream, by upstream prefix.",
+    }, []string{"upstream"})
+
+    // UpstreamResponses counts proxied responses by upstream prefix, group
+    // (stable, canary, ...) and status class, so canary error rates can be
+    // compared with stable before promoting
+    UpstreamResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "upstream_responses_total",
+        Help:      "Proxied responses, by upstream, group and status class.",
+    }, []string{"upstream", "group", "class"})
+
+    // UpstreamLatency observes proxied request latency by upstream and group
+    UpstreamLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "upstream_request_duration_seconds",
+        Help:      "Proxied request latency, by upstream and group.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"upstream", "group"})
+
+    // CacheRequests counts cache lookups by rule path and result (hit or miss)
+    CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "cache_requests_total",
+        Help:      "Response cache lookups, by rule and result.",
+    }, []string{"rule", "result"})
+
+    // APIVersionRequests counts requests by resolved API version and how the
+    // client selected it (path, header or default)
+    APIVersionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "api_version_requests_total",
+        Help:      "Requests by API version, selection source and deprecation state.",
+    }, []string{"version", "source", "deprecated"})
+
+    // SecurityBlocks counts requests refused by the IP, challenge and bot
+    // checks, by check and reason
+    SecurityBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "security_blocks_total",
+        Help:      "Requests blocked by security checks, by check and reason.",
+    }, []string{"check", "reason"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format
+func Handler() http.Handler {
+    return promhttp.Handler()
+}
+
+// ObserveHTTP records one handled request. An empty route means no route matched.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+    if route == "" {
+        route = "unmatched"
+    }
+    code := strconv.Itoa(status)
+    HTTPRequests.WithLabelValues(method, route, code).Inc()
+    HTTPDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
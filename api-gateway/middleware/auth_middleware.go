@@ -7,7 +7,7 @@ import (
     "strings"
 
     "github.com/ansh0014/api/internal"
-)	
+)
 
 type ctxKey string
 
@@ -18,6 +18,8 @@ const userKey ctxKey = "user"
 func JWTExtract(next http.Handler) http.Handler {
     secret := os.Getenv("JWT_SECRET")
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // only a verified token may set the user; never trust the client's header
+        r.Header.Del("X-User-ID")
         auth := r.Header.Get("Authorization")
         if auth != "" && strings.HasPrefix(auth, "Bearer ") && secret != "" {
             tokenString := strings.TrimPrefix(auth, "Bearer ")
@@ -26,6 +28,7 @@ func JWTExtract(next http.Handler) http.Handler {
                 if sub, ok := claims["sub"].(string); ok && sub != "" {
                     r = r.WithContext(context.WithValue(r.Context(), userKey, sub))
                     r.Header.Set("X-User-ID", sub)
+                    internal.RequestInfoFrom(r.Context()).UserID = sub
                 }
             }
             _ = err
@@ -43,4 +46,4 @@ func RequireAuth(next http.Handler) http.Handler {
         }
         next.ServeHTTP(w, r)
     })
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+    "bytes"
+    "io"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/internal"
+)
+
+// BodyLimits enforces per-route body size and content type before a request
+// reaches an upstream: 413 when the body is too large, 415 when its type is
+// not allowed. Bodies of unknown length are buffered up to the limit so an
+// oversized upload is rejected cleanly instead of failing mid-proxy.
+func BodyLimits(cfg config.RequestLimits) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            max, types := cfg.MaxBody, cfg.DefaultContentTypes
+            for _, rule := range cfg.Rules {
+                if strings.EqualFold(rule.Method, r.Method) && internal.MatchPath(rule.Path, r.URL.Path) {
+                    if rule.MaxBody > 0 {
+                        max = rule.MaxBody
+                    }
+                    types = rule.ContentTypes
+                    break
+                }
+            }
+
+            hasBody := r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody
+            if !hasBody {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            if len(types) > 0 && !allowedContentType(r.Header.Get("Content-Type"), types) {
+                w.Header().Set("Accept", strings.Join(types, ", "))
+                http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+                return
+            }
+
+            if max > 0 {
+                if r.ContentLength > max {
+                    tooLarge(w, max)
+                    return
+                }
+                if r.ContentLength < 0 {
+                    body, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+                    r.Body.Close()
+                    if err != nil {
+                        http.Error(w, "invalid request body", http.StatusBadRequest)
+                        return
+                    }
+                    if int64(len(body)) > max {
+                        tooLarge(w, max)
+                        return
+                    }
+                    r.Body = io.NopCloser(bytes.NewReader(body))
+                    r.ContentLength = int64(len(body))
+                    r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+                    r.TransferEncoding = nil
+                } else {
+                    r.Body = http.MaxBytesReader(w, r.Body, max)
+                }
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+func allowedContentType(header string, allowed []string) bool {
+    mt, _, err := mime.ParseMediaType(header)
+    if err != nil {
+        return false
+    }
+    for _, a := range allowed {
+        if strings.EqualFold(mt, a) {
+            return true
+        }
+    }
+    return false
+}
+
+func tooLarge(w http.ResponseWriter, max int64) {
+    http.Error(w, "request body exceeds "+strconv.FormatInt(max, 10)+" bytes", http.StatusRequestEntityTooLarge)
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+    "log/slog"
+    "net/http"
+    "net/netip"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/ansh0014/api/challenge"
+    "github.com/ansh0014/api/config"
+)
+
+// BotGuard blocks suspicious clients on the configured sensitive routes:
+// known automation user agents, and IPs that exceed the request velocity,
+// which stay blocked for a cool-down period.
+type BotGuard struct {
+    routes   []config.EndpointMatch
+    trusted  []netip.Prefix
+    agents   []string
+    velocity *velocity
+}
+
+// NewBotGuard builds a guard from the security settings.
+func NewBotGuard(cfg config.SecurityConfig) *BotGuard {
+    bots := cfg.Bots
+    agents := make([]string, len(bots.BlockedAgents))
+    for i, a := range bots.BlockedAgents {
+        agents[i] = strings.ToLower(a)
+    }
+    return &BotGuard{
+        routes:  bots.Routes,
+        trusted: mustPrefixes(cfg.TrustedProxies),
+        agents:  agents,
+        velocity: newVelocity(
+            time.Duration(bots.WindowSeconds)*time.Second,
+            bots.MaxRequests,
+            time.Duration(bots.BlockSeconds)*time.Second,
+        ),
+    }
+}
+
+// Middleware applies the guard to the configured routes.
+func (g *BotGuard) Middleware(next http.Handler) http.Handler {
+    if len(g.routes) == 0 {
+        return next
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !matchEndpoint(g.routes, r) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        ip := resolveClientIP(r, g.trusted)
+        if reason := agentReputation(r.UserAgent(), g.agents); reason != "" {
+            logBlock(r, ip, "user_agent", reason)
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        if retry, blocked := g.velocity.hit(ip, time.Now()); blocked {
+            logBlock(r, ip, "velocity", "too_many_requests")
+            w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds()+0.5)))
+            http.Error(w, "too many requests", http.StatusTooManyRequests)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// VelocityStatus is the sensitive-route counter for one IP.
+type VelocityStatus struct {
+    Requests     int        `json:"requests"`
+    Max          int        `json:"max"`
+    WindowStart  time.Time  `json:"window_start"`
+    BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+// Status returns the velocity counter for ip, if it has one.
+func (g *BotGuard) Status(ip netip.Addr) (VelocityStatus, bool) {
+    v := g.velocity
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    e, ok := v.clients[ip]
+    if !ok {
+        return VelocityStatus{}, false
+    }
+    st := VelocityStatus{Requests: e.count, Max: v.max, WindowStart: e.start}
+    if time.Now().Before(e.blockedUntil) {
+        t := e.blockedUntil
+        st.BlockedUntil = &t
+    }
+    return st, true
+}
+
+// agentReputation returns why a user agent is untrusted, or "".
+func agentReputation(ua string, blocked []string) string {
+    if strings.TrimSpace(ua) == "" {
+        return "empty_user_agent"
+    }
+    lower := strings.ToLower(ua)
+    for _, b := range blocked {
+        if strings.Contains(lower, b) {
+            return "blocked_user_agent"
+        }
+    }
+    return ""
+}
+
+// velocity counts requests per IP in fixed windows.
+type velocity struct {
+    window time.Duration
+    max    int
+    block  time.Duration
+
+    mu        sync.Mutex
+    clients   map[netip.Addr]*velocityEntry
+    lastSweep time.Time
+}
+
+type velocityEntry struct {
+    start        time.Time
+    count        int
+    blockedUntil time.Time
+}
+
+func newVelocity(window time.Duration, max int, block time.Duration) *velocity {
+    return &velocity{window: window, max: max, block: block, clients: map[netip.Addr]*velocityEntry{}}
+}
+
+// hit records a request and reports whether the IP is blocked, and for how long.
+func (v *velocity) hit(ip netip.Addr, now time.Time) (time.Duration, bool) {
+    if v.max <= 0 || v.window <= 0 {
+        return 0, false
+    }
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    if now.Sub(v.lastSweep) > v.window {
+        for k, e := range v.clients {
+            if now.Sub(e.start) > v.window && now.After(e.blockedUntil) {
+                delete(v.clients, k)
+            }
+        }
+        v.lastSweep = now
+    }
+
+    e := v.clients[ip]
+    if e == nil {
+        e = &velocityEntry{start: now}
+        v.clients[ip] = e
+    }
+    if now.Before(e.blockedUntil) {
+        return e.blockedUntil.Sub(now), true
+    }
+    if now.Sub(e.start) > v.window {
+        e.start, e.count = now, 0
+    }
+    e.count++
+    if e.count > v.max {
+        e.blockedUntil = now.Add(v.block)
+        slog.Warn("client blocked for request velocity",
+            "ip", ip.String(), "requests", e.count, "window", v.window.String(), "blocked_for", v.block.String())
+        return v.block, true
+    }
+    return 0, false
+}
+
+// Challenge requires a valid challenge token on the configured routes. A nil
+// verifier disables the check. Verification outages fail closed with 503.
+func Challenge(cfg config.SecurityConfig, verifier challenge.Verifier) func(http.Handler) http.Handler {
+    trusted := mustPrefixes(cfg.TrustedProxies)
+    routes := cfg.Challenge.Routes
+    return func(next http.Handler) http.Handler {
+        if verifier == nil || len(routes) == 0 {
+            return next
+        }
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if !matchEndpoint(routes, r) {
+                next.ServeHTTP(w, r)
+                return
+            }
+            ip := resolveClientIP(r, trusted)
+            token := r.Header.Get(challenge.TokenHeader)
+            if token == "" {
+                logBlock(r, ip, "challenge", "missing_token")
+                http.Error(w, challenge.TokenHeader+" header is required", http.StatusForbidden)
+                return
+            }
+            ok, err := verifier.Verify(r.Context(), token, ip.String())
+            if err != nil {
+                logBlock(r, ip, "challenge", "verifier_error")
+                slog.ErrorContext(r.Context(), "challenge verification failed", "error", err.Error())
+                http.Error(w, "challenge verification unavailable", http.StatusServiceUnavailable)
+                return
+            }
+            if !ok {
+                logBlock(r, ip, "challenge", "invalid_token")
+                http.Error(w, "challenge failed", http.StatusForbidden)
+                return
+            }
+            // the token is for the gateway only
+            r.Header.Del(challenge.TokenHeader)
+            next.ServeHTTP(w, r)
+        })
+    }
+}
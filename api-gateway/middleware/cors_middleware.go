@@ -2,18 +2,142 @@ package middleware
 
 import (
     "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/ansh0014/api/config"
 )
 
-// Simple CORS middleware (if you prefer not to use gorilla/handlers)
-func CORS(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        w.Header().Set("Access-Control-Allow-Origin", "*")
-        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-User-ID")
-        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-        if r.Method == http.MethodOptions {
+// CORS applies the gateway's CORS policy. Allowed origins are echoed back
+// (never "*" when credentials are allowed), preflights are answered here
+// without reaching an upstream, and disallowed preflights get 403.
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+    methods := strings.Join(cfg.AllowedMethods, ", ")
+    exposed := strings.Join(cfg.ExposedHeaders, ", ")
+    allowedHeaders := map[string]bool{}
+    for _, h := range cfg.AllowedHeaders {
+        allowedHeaders[http.CanonicalHeaderKey(h)] = true
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            origin := r.Header.Get("Origin")
+            if origin == "" {
+                next.ServeHTTP(w, r)
+                return
+            }
+            w.Header().Add("Vary", "Origin")
+
+            origins, credentials := cfg.AllowedOrigins, cfg.AllowCredentials
+            // CORS runs before Versioning, so routes are matched on the
+            // path without its /v{N} prefix
+            if rt, ok := matchCORSRoute(cfg.Routes, unversionedPath(r.URL.Path)); ok {
+                if rt.AllowedOrigins != nil {
+                    origins = rt.AllowedOrigins
+                }
+                if rt.AllowCredentials != nil {
+                    credentials = *rt.AllowCredentials
+                }
+            }
+
+            preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+            allowed, wildcard := matchOrigin(origins, origin)
+            if !allowed {
+                if preflight {
+                    http.Error(w, "origin not allowed", http.StatusForbidden)
+                    return
+                }
+                // the browser withholds the response without CORS headers
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            if wildcard && !credentials {
+                w.Header().Set("Access-Control-Allow-Origin", "*")
+            } else {
+                w.Header().Set("Access-Control-Allow-Origin", origin)
+            }
+            if credentials {
+                w.Header().Set("Access-Control-Allow-Credentials", "true")
+            }
+
+            if !preflight {
+                if exposed != "" {
+                    w.Header().Set("Access-Control-Expose-Headers", exposed)
+                }
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            w.Header().Add("Vary", "Access-Control-Request-Method")
+            w.Header().Add("Vary", "Access-Control-Request-Headers")
+            if !containsFold(cfg.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+                http.Error(w, "method not allowed", http.StatusForbidden)
+                return
+            }
+            var headers []string
+            for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+                h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+                if h == "" {
+                    continue
+                }
+                if !allowedHeaders[h] {
+                    http.Error(w, "header "+h+" not allowed", http.StatusForbidden)
+                    return
+                }
+                headers = append(headers, h)
+            }
+            w.Header().Set("Access-Control-Allow-Methods", methods)
+            if len(headers) > 0 {
+                w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+            }
+            if cfg.MaxAgeSeconds > 0 {
+                w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAgeSeconds))
+            }
             w.WriteHeader(http.StatusNoContent)
-            return
+        })
+    }
+}
+
+// matchCORSRoute returns the route policy with the longest prefix of path.
+func matchCORSRoute(routes []config.CORSRoute, path string) (config.CORSRoute, bool) {
+    var best config.CORSRoute
+    found := false
+    for _, rt := range routes {
+        if strings.HasPrefix(path, rt.PathPrefix) && (!found || len(rt.PathPrefix) > len(best.PathPrefix)) {
+            best, found = rt, true
+        }
+    }
+    return best, found
+}
+
+// matchOrigin reports whether origin is allowed and whether it matched "*".
+func matchOrigin(allowed []string, origin string) (bool, bool) {
+    for _, a := range allowed {
+        if a == "*" {
+            return true, true
+        }
+        if strings.EqualFold(a, origin) {
+            return true, false
+        }
+        // "https://*.example.com" matches any subdomain, not the apex
+        if i := strings.Index(a, "://*."); i >= 0 {
+            scheme, suffix := a[:i+3], a[i+4:]
+            if len(origin) > len(scheme)+len(suffix) &&
+                strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)) &&
+                strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+                return true, false
+            }
+        }
+    }
+    return false, false
+}
+
+func containsFold(list []string, s string) bool {
+    for _, v := range list {
+        if strings.EqualFold(v, s) {
+            return true
         }
-        next.ServeHTTP(w, r)
-    })
-}
\ No newline at end of file
+    }
+    return false
+}
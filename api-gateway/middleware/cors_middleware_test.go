@@ -0,0 +1,54 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/ansh0014/api/config"
+)
+
+func TestCORSRoutePolicies(t *testing.T) {
+    no := false
+    cfg := config.CORSConfig{
+        AllowedOrigins:   []string{"https://app.example.com"},
+        AllowCredentials: true,
+        AllowedMethods:   []string{"GET", "POST"},
+        // the broader rule comes first; the longer prefix must still win
+        Routes: []config.CORSRoute{
+            {PathPrefix: "/venue/", AllowedOrigins: []string{"https://partner.example.com"}},
+            {PathPrefix: "/venue/api/public/", AllowedOrigins: []string{"*"}, AllowCredentials: &no},
+        },
+    }
+    h := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    }))
+
+    tests := []struct {
+        path, origin string
+        // wantOrigin is the Access-Control-Allow-Origin answer, "" for none
+        wantOrigin  string
+        credentials bool
+    }{
+        {"/booking/api/bookings", "https://app.example.com", "https://app.example.com", true},
+        {"/v1/booking/api/bookings", "https://app.example.com", "https://app.example.com", true},
+        {"/venue/api/halls", "https://partner.example.com", "https://partner.example.com", true},
+        {"/v2/venue/api/halls", "https://partner.example.com", "https://partner.example.com", true},
+        {"/v2/venue/api/halls", "https://app.example.com", "", false},
+        {"/venue/api/public/shows", "https://anyone.test", "*", false},
+        {"/v1/venue/api/public/shows", "https://anyone.test", "*", false},
+    }
+    for _, tt := range tests {
+        r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+        r.Header.Set("Origin", tt.origin)
+        rec := httptest.NewRecorder()
+        h.ServeHTTP(rec, r)
+
+        if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+            t.Errorf("%s from %s: allow origin %q, want %q", tt.path, tt.origin, got, tt.wantOrigin)
+        }
+        if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+            t.Errorf("%s from %s: credentials %v, want %v", tt.path, tt.origin, got, tt.credentials)
+        }
+    }
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+    "net/http"
+
+    "github.com/ansh0014/api/config"
+)
+
+// IdempotencyKeyHeader lets clients retry creates without duplicating them.
+// Booking and payment services store and replay the first response.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 255
+
+// RequireIdempotencyKey rejects requests to the configured create endpoints
+// that lack a usable Idempotency-Key. It is a no-op unless cfg.Require is set.
+func RequireIdempotencyKey(cfg config.IdempotencyConfig) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        if !cfg.Require {
+            return next
+        }
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if matchEndpoint(cfg.Routes, r) {
+                key := r.Header.Get(IdempotencyKeyHeader)
+                if key == "" {
+                    http.Error(w, "Idempotency-Key header is required", http.StatusPreconditionRequired)
+                    return
+                }
+                if len(key) > maxIdempotencyKeyLen {
+                    http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+                    return
+                }
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+    "bufio"
+    "errors"
+    "log/slog"
+    "net"
+    "net/http"
+    "time"
+
+    "github.com/ansh0014/api/internal"
+    "github.com/ansh0014/api/metrics"
+)
+
+// RequestID makes sure every request entering the gateway carries a unique
+// X-Request-ID. A well-formed ID supplied by the client is kept, anything else
+// is replaced. The ID is echoed on the response and forwarded upstream.
+func RequestID(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(internal.RequestIDHeader)
+        if !internal.ValidRequestID(id) {
+            id = internal.NewRequestID()
+            r.Header.Set(internal.RequestIDHeader, id)
+        }
+        w.Header().Set(internal.RequestIDHeader, id)
+
+        info := internal.RequestInfoFrom(r.Context())
+        info.ID = id
+        next.ServeHTTP(w, r.WithContext(internal.WithRequestInfo(r.Context(), info)))
+    })
+}
+
+// AccessLog writes one structured JSON line per request with the request ID,
+// user ID, matched route, status and latency, and records the request in the
+// HTTP metrics.
+func AccessLog(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        info := internal.RequestInfoFrom(r.Context())
+        r = r.WithContext(internal.WithRequestInfo(r.Context(), info))
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(rec, r)
+        elapsed := time.Since(start)
+
+        userID := info.UserID
+        if userID == "" {
+            userID = r.Header.Get("X-User-ID")
+        }
+        slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+            slog.String("request_id", info.ID),
+            slog.String("user_id", userID),
+            slog.String("method", r.Method),
+            slog.String("path", r.URL.Path),
+            slog.String("route", info.Route),
+            slog.Int("status", rec.status),
+            slog.Int64("bytes", rec.bytes),
+            slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+            slog.String("remote_ip", clientIP(r)),
+        )
+        metrics.ObserveHTTP(r.Method, info.Route, rec.status, elapsed)
+    })
+}
+
+// statusRecorder captures the status code and body size written by a handler.
+// It forwards Flush and Hijack so streaming and upgraded connections keep working.
+type statusRecorder struct {
+    http.ResponseWriter
+    status      int
+    bytes       int64
+    wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+    if !s.wroteHeader {
+        s.status = code
+        s.wroteHeader = true
+    }
+    s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+    s.wroteHeader = true
+    n, err := s.ResponseWriter.Write(b)
+    s.bytes += int64(n)
+    return n, err
+}
+
+func (s *statusRecorder) Flush() {
+    if f, ok := s.ResponseWriter.(http.Flusher); ok {
+        s.wroteHeader = true
+        f.Flush()
+    }
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    if h, ok := s.ResponseWriter.(http.Hijacker); ok {
+        s.status = http.StatusSwitchingProtocols
+        s.wroteHeader = true
+        return h.Hijack()
+    }
+    return nil, nil, errors.New("hijack not supported")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+    return s.ResponseWriter
+}
+
+// clientIP returns the host part of the connection's remote address.
+func clientIP(r *http.Request) string {
+    if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+        return ip
+    }
+    return r.RemoteAddr
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// MaintenanceRule puts every route under PathPrefix into maintenance.
+type MaintenanceRule struct {
+    PathPrefix string    `json:"path_prefix"`
+    Message    string    `json:"message"`
+    Since      time.Time `json:"since"`
+}
+
+// Maintenance answers 503 for routes that operators switched off at runtime.
+// Gateway endpoints such as /health and /metrics are never affected.
+type Maintenance struct {
+    defaultMessage string
+
+    mu    sync.RWMutex
+    rules map[string]MaintenanceRule
+}
+
+// NewMaintenance returns an empty switchboard; msg is used when a rule has
+// no message of its own.
+func NewMaintenance(msg string) *Maintenance {
+    return &Maintenance{defaultMessage: msg, rules: map[string]MaintenanceRule{}}
+}
+
+// Set enables maintenance under prefix.
+func (m *Maintenance) Set(prefix, msg string) MaintenanceRule {
+    if msg == "" {
+        msg = m.defaultMessage
+    }
+    rule := MaintenanceRule{PathPrefix: prefix, Message: msg, Since: time.Now().UTC()}
+    m.mu.Lock()
+    m.rules[prefix] = rule
+    m.mu.Unlock()
+    return rule
+}
+
+// Clear disables maintenance under prefix and reports whether it was on.
+func (m *Maintenance) Clear(prefix string) bool {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    _, ok := m.rules[prefix]
+    delete(m.rules, prefix)
+    return ok
+}
+
+// List returns the active rules ordered by prefix.
+func (m *Maintenance) List() []MaintenanceRule {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    out := make([]MaintenanceRule, 0, len(m.rules))
+    for _, r := range m.rules {
+        out = append(out, r)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].PathPrefix < out[j].PathPrefix })
+    return out
+}
+
+// match returns the longest rule covering path.
+func (m *Maintenance) match(path string) (MaintenanceRule, bool) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    var best MaintenanceRule
+    found := false
+    for prefix, r := range m.rules {
+        if strings.HasPrefix(path, prefix) && len(prefix) >= len(best.PathPrefix) {
+            best, found = r, true
+        }
+    }
+    return best, found
+}
+
+// Middleware rejects requests to routes in maintenance.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        for _, p := range []string{"/health", "/metrics"} {
+            if strings.HasPrefix(r.URL.Path, p) {
+                next.ServeHTTP(w, r)
+                return
+            }
+        }
+        if rule, ok := m.match(r.URL.Path); ok {
+            w.Header().Set("Content-Type", "application/json")
+            w.Header().Set("Retry-After", "120")
+            w.WriteHeader(http.StatusServiceUnavailable)
+            json.NewEncoder(w).Encode(map[string]string{"error": "maintenance", "message": rule.Message})
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
@@ -3,6 +3,7 @@ package middleware
 import (
     "net"
     "net/http"
+    "strconv"
     "sync"
     "time"
 
@@ -31,14 +32,29 @@ func getLimiter(ip string) *rate.Limiter {
     return limiter
 }
 
+// RateLimitStatus reports the per-IP limiter for key, if one exists.
+func RateLimitStatus(key string) (burst int, remaining float64, ok bool) {
+    mu.Lock()
+    limiter, exists := visitors[key]
+    mu.Unlock()
+    if !exists {
+        return 0, 0, false
+    }
+    return limiter.Burst(), limiter.Tokens(), true
+}
+
 func RateLimit(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         ip, _, _ := net.SplitHostPort(r.RemoteAddr)
         limiter := getLimiter(ip)
-        if !limiter.Allow() {
+        allowed := limiter.Allow()
+        w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limiter.Burst()))
+        w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(limiter.Tokens())))
+        if !allowed {
+            w.Header().Set("Retry-After", "1")
             http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
             return
         }
         next.ServeHTTP(w, r)
     })
-}
\ No newline at end of file
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// IsStream reports whether r opens a long-lived connection: a WebSocket
+// upgrade or a Server-Sent Events subscription.
+func IsStream(r *http.Request) bool {
+    if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+        return true
+    }
+    return strings.HasSuffix(r.URL.Path, "/stream") ||
+        strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// StreamToken lets browsers authenticate streams. EventSource and WebSocket
+// clients cannot set an Authorization header, so an access_token query
+// parameter is moved into the header (and out of the URL, so it is neither
+// logged nor forwarded). Must run before JWTExtract.
+func StreamToken(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if IsStream(r) && r.Header.Get("Authorization") == "" {
+            q := r.URL.Query()
+            if tok := q.Get("access_token"); tok != "" {
+                q.Del("access_token")
+                r.URL.RawQuery = q.Encode()
+                r.Header.Set("Authorization", "Bearer "+tok)
+            }
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// StreamLimiter caps concurrent stream connections, overall and per user.
+type StreamLimiter struct {
+    max     int
+    perUser int
+
+    mu      sync.Mutex
+    total   int
+    byUser  map[string]int
+    nextID  uint64
+    cancels map[uint64]context.CancelFunc
+    closed  bool
+}
+
+// NewStreamLimiter returns a limiter. A limit of zero disables that check.
+func NewStreamLimiter(max, perUser int) *StreamLimiter {
+    return &StreamLimiter{
+        max:     max,
+        perUser: perUser,
+        byUser:  make(map[string]int),
+        cancels: make(map[uint64]context.CancelFunc),
+    }
+}
+
+// Open returns the number of streams held by user and across the gateway.
+func (l *StreamLimiter) Open(user string) (int, int) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.byUser[user], l.total
+}
+
+// acquire reserves a slot, returning its id or the HTTP status to reject with.
+func (l *StreamLimiter) acquire(user string, cancel context.CancelFunc) (uint64, int) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.closed {
+        return 0, http.StatusServiceUnavailable
+    }
+    if l.max > 0 && l.total >= l.max {
+        return 0, http.StatusServiceUnavailable
+    }
+    if l.perUser > 0 && l.byUser[user] >= l.perUser {
+        return 0, http.StatusTooManyRequests
+    }
+    l.total++
+    l.byUser[user]++
+    l.nextID++
+    l.cancels[l.nextID] = cancel
+    return l.nextID, 0
+}
+
+func (l *StreamLimiter) release(user string, id uint64) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    delete(l.cancels, id)
+    l.total--
+    if l.byUser[user]--; l.byUser[user] <= 0 {
+        delete(l.byUser, user)
+    }
+}
+
+// Middleware requires an authenticated user on stream requests and enforces
+// the connection limits. Other requests pass through untouched. Must run
+// after JWTExtract.
+func (l *StreamLimiter) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !IsStream(r) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        user := r.Header.Get("X-User-ID")
+        if user == "" {
+            http.Error(w, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+        ctx, cancel := context.WithCancel(r.Context())
+        defer cancel()
+        id, code := l.acquire(user, cancel)
+        if code != 0 {
+            w.Header().Set("Retry-After", "5")
+            http.Error(w, "too many stream connections", code)
+            return
+        }
+        defer l.release(user, id)
+        r = r.WithContext(ctx)
+
+        // streams outlive the server's write timeout
+        _ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+        next.ServeHTTP(w, r)
+    })
+}
+
+// CloseAll ends every open stream and refuses new ones. http.Server.Shutdown
+// does not interrupt active requests, so register this with
+// RegisterOnShutdown to let draining finish; clients reconnect elsewhere.
+func (l *StreamLimiter) CloseAll() {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.closed = true
+    for _, cancel := range l.cancels {
+        cancel()
+    }
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+    "context"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/metrics"
+)
+
+// Version headers. Clients may send Accept-Version instead of a /v{N} path
+// prefix; upstreams receive the resolved version in X-API-Version.
+const (
+    AcceptVersionHeader   = "Accept-Version"
+    APIVersionHeader      = "API-Version"
+    UpstreamVersionHeader = "X-API-Version"
+)
+
+var versionPrefix = regexp.MustCompile(`^/(v[0-9]+)(/|$)`)
+
+// gatewayPaths are served by the gateway itself and are not versioned.
+var gatewayPaths = []string{"/health", "/metrics", "/internal/", "/bff/"}
+
+// publicPathKey holds the request path as clients see it, without the
+// version prefix and before rewrites.
+type publicPathKey struct{}
+
+type apiVersion struct {
+    config.APIVersion
+    deprecation time.Time
+    sunset      time.Time
+}
+
+// Versioning resolves the API version of each request, rewrites the path to
+// the version's upstream layout and adds deprecation and sunset headers.
+// Versions past their sunset answer 410 Gone.
+func Versioning(cfg config.VersionConfig) func(http.Handler) http.Handler {
+    versions := make(map[string]*apiVersion, len(cfg.Versions))
+    for _, v := range cfg.Versions {
+        av := &apiVersion{APIVersion: v}
+        // dates were checked by config.Validate
+        av.deprecation, _ = config.ParseDate(v.Deprecation)
+        av.sunset, _ = config.ParseDate(v.Sunset)
+        versions[v.Name] = av
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            for _, p := range gatewayPaths {
+                if strings.HasPrefix(r.URL.Path, p) {
+                    next.ServeHTTP(w, r)
+                    return
+                }
+            }
+
+            // never trust a client-supplied upstream version
+            r.Header.Del(UpstreamVersionHeader)
+
+            name, source := cfg.Default, "default"
+            if m := versionPrefix.FindStringSubmatch(r.URL.Path); m != nil {
+                name, source = m[1], "path"
+                r.URL.Path = unversionedPath(r.URL.Path)
+                r.URL.RawPath = ""
+            } else if h := strings.TrimSpace(r.Header.Get(AcceptVersionHeader)); h != "" {
+                name, source = normalizeVersion(h), "header"
+            }
+            if name == "" {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            v, ok := versions[name]
+            if !ok {
+                http.Error(w, "unsupported API version "+strconv.Quote(name), http.StatusBadRequest)
+                return
+            }
+
+            now := time.Now()
+            deprecated := !v.deprecation.IsZero() && !now.Before(v.deprecation)
+            metrics.APIVersionRequests.WithLabelValues(name, source, strconv.FormatBool(deprecated)).Inc()
+
+            h := w.Header()
+            h.Set(APIVersionHeader, name)
+            h.Add("Vary", AcceptVersionHeader)
+            if !v.deprecation.IsZero() {
+                h.Set("Deprecation", "@"+strconv.FormatInt(v.deprecation.Unix(), 10))
+            }
+            if !v.sunset.IsZero() {
+                h.Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+            }
+            if v.Link != "" {
+                h.Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+            }
+            if !v.sunset.IsZero() && !now.Before(v.sunset) {
+                http.Error(w, "API version "+name+" has been retired", http.StatusGone)
+                return
+            }
+
+            // security rules are written against the public layout
+            r = r.WithContext(context.WithValue(r.Context(), publicPathKey{}, r.URL.Path))
+            for _, rw := range v.Rewrites {
+                if strings.HasPrefix(r.URL.Path, rw.From) {
+                    r.URL.Path = rw.To + strings.TrimPrefix(r.URL.Path, rw.From)
+                    r.URL.RawPath = ""
+                    break
+                }
+            }
+            r.Header.Set(UpstreamVersionHeader, name)
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// unversionedPath strips a /v{N} prefix from path.
+func unversionedPath(path string) string {
+    m := versionPrefix.FindStringSubmatch(path)
+    if m == nil {
+        return path
+    }
+    return "/" + strings.TrimPrefix(path[len(m[1])+1:], "/")
+}
+
+// publicPath returns the unversioned path the client asked for, or the
+// request path when Versioning did not run.
+func publicPath(r *http.Request) string {
+    if p, ok := r.Context().Value(publicPathKey{}).(string); ok {
+        return p
+    }
+    return r.URL.Path
+}
+
+// normalizeVersion accepts "2", "v2" or "V2".
+func normalizeVersion(s string) string {
+    s = strings.ToLower(s)
+    if !strings.HasPrefix(s, "v") {
+        s = "v" + s
+    }
+    return s
+}
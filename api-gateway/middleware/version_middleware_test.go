@@ -0,0 +1,94 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/ansh0014/api/challenge"
+    "github.com/ansh0014/api/config"
+)
+
+// guarded composes the guards the way main does: Versioning runs first so
+// they see the unversioned path.
+func guarded(t *testing.T, sec config.SecurityConfig, versions config.VersionConfig) http.Handler {
+    t.Helper()
+    verifier, err := challenge.New(sec.Challenge)
+    if err != nil {
+        t.Fatalf("challenge: %v", err)
+    }
+    var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    })
+    h = Challenge(sec, verifier)(h)
+    h = NewBotGuard(sec).Middleware(h)
+    h = IPFilter(sec)(h)
+    return Versioning(versions)(h)
+}
+
+func TestGuardsApplyToVersionedPaths(t *testing.T) {
+    login := []config.EndpointMatch{{Method: "POST", Path: "/auth/login"}}
+    versions := config.VersionConfig{
+        Default: "v1",
+        Versions: []config.APIVersion{
+            {Name: "v1"},
+            {Name: "v2", Rewrites: []config.PathRewrite{{From: "/auth/", To: "/auth/v2/"}}},
+        },
+    }
+
+    tests := []struct {
+        name      string
+        sec       config.SecurityConfig
+        userAgent string
+        captcha   string
+        want      int
+    }{
+        {
+            name: "ip deny",
+            sec: config.SecurityConfig{IPRules: []config.IPRule{
+                {PathPrefix: "/auth/", Deny: []string{"192.0.2.0/24"}},
+            }},
+            want: http.StatusForbidden,
+        },
+        {
+            name:      "blocked user agent",
+            sec:       config.SecurityConfig{Bots: config.BotConfig{Routes: login, BlockedAgents: []string{"scrapy"}}},
+            userAgent: "Scrapy/2.11",
+            want:      http.StatusForbidden,
+        },
+        {
+            name: "missing captcha",
+            sec: config.SecurityConfig{Challenge: config.ChallengeConfig{
+                Provider: "fake", Secret: "solved", Routes: login,
+            }},
+            want: http.StatusForbidden,
+        },
+        {
+            name: "solved captcha",
+            sec: config.SecurityConfig{Challenge: config.ChallengeConfig{
+                Provider: "fake", Secret: "solved", Routes: login,
+            }},
+            captcha: "solved",
+            want:    http.StatusOK,
+        },
+    }
+
+    for _, tt := range tests {
+        for _, path := range []string{"/auth/login", "/v1/auth/login", "/v2/auth/login"} {
+            t.Run(tt.name+" "+path, func(t *testing.T) {
+                h := guarded(t, tt.sec, versions)
+                req := httptest.NewRequest(http.MethodPost, path, nil)
+                req.RemoteAddr = "192.0.2.10:4321"
+                req.Header.Set("User-Agent", tt.userAgent)
+                if tt.captcha != "" {
+                    req.Header.Set(challenge.TokenHeader, tt.captcha)
+                }
+                rec := httptest.NewRecorder()
+                h.ServeHTTP(rec, req)
+                if rec.Code != tt.want {
+                    t.Fatalf("POST %s: status %d, want %d", path, rec.Code, tt.want)
+                }
+            })
+        }
+    }
+}
@@ -1,45 +1,78 @@
 package pkg
 
 import (
+    "context"
+    "errors"
+    "hash/fnv"
+    "log/slog"
+    "net"
     "net/http"
     "net/http/httputil"
     "net/url"
     "sort"
+    "strconv"
     "strings"
+
+    "github.com/ansh0014/api/config"
+    "github.com/ansh0014/api/metrics"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Testers force an upstream group with this header or cookie.
+const (
+    GroupHeader = "X-Upstream-Group"
+    GroupCookie = "upstream_group"
 )
 
-// ProxyMap routes prefixes to reverse proxies
+// StableGroup is the name of each prefix's primary upstream group.
+const StableGroup = "stable"
+
+// ProxyMap routes prefixes to reverse proxies. A prefix may split its traffic
+// between several upstream groups, e.g. a stable build and a canary.
 type ProxyMap struct {
     prefixes []string
-    proxies  map[string]*httputil.ReverseProxy
+    routes   map[string]*Route
+}
+
+// Route is one prefix and its upstream groups; groups[0] is stable.
+type Route struct {
+    Prefix string
+    groups []*Upstream
 }
 
-// NewProxyMap creates reverse proxies for each prefix -> target
-func NewProxyMap(m map[string]string) *ProxyMap {
+// NewProxyMap creates reverse proxies for each prefix -> target, each behind
+// a circuit breaker configured by bc. splits add weighted groups to a prefix.
+func NewProxyMap(m map[string]string, splits []config.SplitConfig, bc config.BreakerConfig) *ProxyMap {
     pm := &ProxyMap{
-        proxies: map[string]*httputil.ReverseProxy{},
+        routes: map[string]*Route{},
     }
     for prefix, target := range m {
-        u, err := url.Parse(target)
+        up, err := newUpstream(prefix, StableGroup, target, bc)
         if err != nil {
             continue
         }
-        rp := httputil.NewSingleHostReverseProxy(u)
-        orig := rp.Director
-        p := prefix
-        baseHost := u.Host
-        rp.Director = func(req *http.Request) {
-            orig(req)
-            // strip prefix so upstream receives path without "/booking" etc.
-            req.URL.Path = strings.TrimPrefix(req.URL.Path, p)
-            if req.URL.Path == "" {
-                req.URL.Path = "/"
+        pm.routes[prefix] = &Route{Prefix: prefix, groups: []*Upstream{up}}
+        pm.prefixes = append(pm.prefixes, prefix)
+    }
+    for _, s := range splits {
+        rt, ok := pm.routes[s.Prefix]
+        if !ok {
+            slog.Warn("traffic split for unknown prefix ignored", "prefix", s.Prefix)
+            continue
+        }
+        for _, g := range s.Groups {
+            up, err := newUpstream(s.Prefix, g.Name, g.Target, bc)
+            if err != nil {
+                slog.Warn("traffic split group ignored", "prefix", s.Prefix, "group", g.Name, "error", err.Error())
+                continue
+            }
+            up.weight = g.Weight
+            up.users = make(map[string]bool, len(g.Users))
+            for _, u := range g.Users {
+                up.users[u] = true
             }
-            // set Host to upstream host
-            req.Host = baseHost
+            rt.groups = append(rt.groups, up)
         }
-        pm.proxies[prefix] = rp
-        pm.prefixes = append(pm.prefixes, prefix)
     }
     // sort prefixes by length desc so longest match wins
     sort.Slice(pm.prefixes, func(i, j int) bool {
@@ -48,13 +81,129 @@ func NewProxyMap(m map[string]string) *ProxyMap {
     return pm
 }
 
-// Route finds best matching proxy for request path
-func (pm *ProxyMap) Route(r *http.Request) *httputil.ReverseProxy {
+// newProxy builds the reverse proxy for one upstream group.
+func newProxy(up *Upstream, u *url.URL) *httputil.ReverseProxy {
+    rp := httputil.NewSingleHostReverseProxy(u)
+    // client spans per upstream call; injects W3C traceparent headers
+    rp.Transport = otelhttp.NewTransport(http.DefaultTransport)
+    orig := rp.Director
+    p := up.Prefix
+    baseHost := u.Host
+    rp.Director = func(req *http.Request) {
+        orig(req)
+        // strip prefix so upstream receives path without "/booking" etc.
+        req.URL.Path = strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(p, "/"))
+        if req.URL.Path == "" {
+            req.URL.Path = "/"
+        }
+        req.URL.RawPath = ""
+        // set Host to upstream host
+        req.Host = baseHost
+    }
+    // CORS is owned by the gateway; upstream copies would duplicate it
+    rp.ModifyResponse = func(resp *http.Response) error {
+        up.breaker.record(up.Name(), resp.StatusCode < http.StatusInternalServerError)
+        metrics.UpstreamResponses.WithLabelValues(p, up.Group, strconv.Itoa(resp.StatusCode/100)+"xx").Inc()
+        for k := range resp.Header {
+            if strings.HasPrefix(k, "Access-Control-") {
+                resp.Header.Del(k)
+            }
+        }
+        return nil
+    }
+    rp.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+        metrics.UpstreamErrors.WithLabelValues(p).Inc()
+        if errors.Is(err, context.Canceled) {
+            // the client went away; says nothing about the upstream
+            up.breaker.abort()
+        } else {
+            up.breaker.record(up.Name(), false)
+            metrics.UpstreamResponses.WithLabelValues(p, up.Group, "error").Inc()
+        }
+        slog.ErrorContext(req.Context(), "upstream error",
+            "upstream", p,
+            "group", up.Group,
+            "request_id", req.Header.Get("X-Request-ID"),
+            "error", err.Error())
+        w.WriteHeader(http.StatusBadGateway)
+    }
+    return rp
+}
+
+// Upstreams returns every upstream group keyed by name: "booking" for the
+// stable group of "/booking/", "booking:canary" for its canary.
+func (pm *ProxyMap) Upstreams() map[string]*Upstream {
+    out := map[string]*Upstream{}
+    for _, rt := range pm.routes {
+        for _, u := range rt.groups {
+            out[u.Name()] = u
+        }
+    }
+    return out
+}
+
+// Match returns the matched prefix together with its route, or "" and nil
+func (pm *ProxyMap) Match(r *http.Request) (string, *Route) {
     path := r.URL.Path
     for _, prefix := range pm.prefixes {
         if strings.HasPrefix(path, prefix) {
-            return pm.proxies[prefix]
+            return prefix, pm.routes[prefix]
+        }
+    }
+    return "", nil
+}
+
+// Stable returns the primary group.
+func (rt *Route) Stable() *Upstream {
+    return rt.groups[0]
+}
+
+// Split reports whether the route has more than one group.
+func (rt *Route) Split() bool {
+    return len(rt.groups) > 1
+}
+
+// Pick chooses the group for r: a tester override (header, then cookie),
+// then users pinned to a group, then a sticky hash of the user ID (the
+// client IP for anonymous requests) against the group weights.
+func (rt *Route) Pick(r *http.Request) *Upstream {
+    if !rt.Split() {
+        return rt.groups[0]
+    }
+    want := r.Header.Get(GroupHeader)
+    if want == "" {
+        if c, err := r.Cookie(GroupCookie); err == nil {
+            want = c.Value
         }
     }
-    return nil
-}
\ No newline at end of file
+    if want != "" {
+        for _, g := range rt.groups {
+            if g.Group == want {
+                return g
+            }
+        }
+    }
+
+    key := r.Header.Get("X-User-ID")
+    if key != "" {
+        for _, g := range rt.groups[1:] {
+            if g.users[key] {
+                return g
+            }
+        }
+    } else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+        key = host
+    }
+
+    h := fnv.New32a()
+    h.Write([]byte(rt.Prefix + key))
+    bucket := int(h.Sum32() % 100)
+    cum := 0
+    for _, g := range rt.groups[1:] {
+        cum += g.weight
+        if bucket < cum {
+            return g
+        }
+    }
+    return rt.groups[0]
+}
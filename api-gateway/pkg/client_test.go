@@ -0,0 +1,184 @@
+package pkg
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/ansh0014/api/config"
+)
+
+// splitRoute returns the /booking/ route with a canary taking weight percent
+// of users, plus the users pinned to it
+func splitRoute(t *testing.T, weight int, users ...string) *Route {
+    t.Helper()
+    pm := NewProxyMap(
+        map[string]string{"/booking/": "http://stable.test"},
+        []config.SplitConfig{{
+            Prefix: "/booking/",
+            Groups: []config.SplitGroup{{Name: "canary", Target: "http://canary.test", Weight: weight, Users: users}},
+        }},
+        config.BreakerConfig{FailureThreshold: 2, CooldownSeconds: 30},
+    )
+    rt := pm.routes["/booking/"]
+    if rt == nil || !rt.Split() {
+        t.Fatal("/booking/ route was not split")
+    }
+    return rt
+}
+
+func userRequest(path, user string) *http.Request {
+    r := httptest.NewRequest(http.MethodGet, path, nil)
+    if user != "" {
+        r.Header.Set("X-User-ID", user)
+    }
+    return r
+}
+
+func TestStable(t *testing.T) {
+    rt := splitRoute(t, 50)
+    if s := rt.Stable(); s.Group != StableGroup || s.Name() != "booking" {
+        t.Fatalf("stable group is %s (%s)", s.Group, s.Name())
+    }
+    if c := rt.groups[1]; c.Name() != "booking:canary" {
+        t.Fatalf("canary named %s", c.Name())
+    }
+
+    pm := NewProxyMap(map[string]string{"/auth/": "http://auth.test"}, nil, config.BreakerConfig{})
+    plain := pm.routes["/auth/"]
+    if plain.Split() || plain.Pick(userRequest("/auth/login", "u1")) != plain.Stable() {
+        t.Fatal("a route without splits must always pick its stable group")
+    }
+}
+
+func TestPickIsSticky(t *testing.T) {
+    rt := splitRoute(t, 50)
+    for i := 0; i < 200; i++ {
+        user := fmt.Sprintf("user-%d", i)
+        first := rt.Pick(userRequest("/booking/api/bookings", user))
+        for _, path := range []string{"/booking/api/seats/lock", "/booking/api/users/me/bookings"} {
+            if got := rt.Pick(userRequest(path, user)); got != first {
+                t.Fatalf("%s moved from %s to %s on %s", user, first.Group, got.Group, path)
+            }
+        }
+    }
+
+    // anonymous requests stick to the client IP, whatever the port
+    a := httptest.NewRequest(http.MethodGet, "/booking/api/search", nil)
+    a.RemoteAddr = "203.0.113.7:1000"
+    b := httptest.NewRequest(http.MethodGet, "/booking/api/search", nil)
+    b.RemoteAddr = "203.0.113.7:2000"
+    if rt.Pick(a) != rt.Pick(b) {
+        t.Fatal("one client IP was split between groups")
+    }
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+    const users = 10000
+    for _, weight := range []int{0, 5, 20, 50, 100} {
+        rt := splitRoute(t, weight)
+        canary := 0
+        for i := 0; i < users; i++ {
+            if rt.Pick(userRequest("/booking/", fmt.Sprintf("user-%d", i))).Group == "canary" {
+                canary++
+            }
+        }
+        share := float64(canary) * 100 / users
+        if share < float64(weight)-2 || share > float64(weight)+2 {
+            t.Errorf("weight %d sent %.1f%% of users to the canary", weight, share)
+        }
+        if (weight == 0 && canary != 0) || (weight == 100 && canary != users) {
+            t.Errorf("weight %d sent %d of %d users to the canary", weight, canary, users)
+        }
+    }
+}
+
+func TestPickOverrides(t *testing.T) {
+    rt := splitRoute(t, 0, "staff-1")
+
+    if g := rt.Pick(userRequest("/booking/", "staff-1")); g.Group != "canary" {
+        t.Fatalf("pinned user got %s", g.Group)
+    }
+
+    r := userRequest("/booking/", "user-1")
+    r.Header.Set(GroupHeader, "canary")
+    if g := rt.Pick(r); g.Group != "canary" {
+        t.Fatalf("group header got %s", g.Group)
+    }
+
+    r = userRequest("/booking/", "staff-1")
+    r.AddCookie(&http.Cookie{Name: GroupCookie, Value: StableGroup})
+    if g := rt.Pick(r); g.Group != StableGroup {
+        t.Fatalf("group cookie got %s, want it to win over pinning", g.Group)
+    }
+
+    // unknown groups are ignored
+    r = userRequest("/booking/", "user-1")
+    r.Header.Set(GroupHeader, "nightly")
+    if g := rt.Pick(r); g.Group != StableGroup {
+        t.Fatalf("unknown group header got %s", g.Group)
+    }
+}
+
+func TestAdmit(t *testing.T) {
+    rt := splitRoute(t, 50)
+    up := rt.groups[1]
+
+    if reason := up.Admit(); reason != "" {
+        t.Fatalf("active upstream refused: %s", reason)
+    }
+    if n := up.Status().InFlight; n != 1 {
+        t.Fatalf("in flight %d after Admit, want 1", n)
+    }
+    up.Done()
+    if n := up.Status().InFlight; n != 0 {
+        t.Fatalf("in flight %d after Done, want 0", n)
+    }
+
+    for _, state := range []string{StateDraining, StateEjected} {
+        up.SetState(state)
+        if reason := up.Admit(); reason != state {
+            t.Fatalf("%s upstream admitted with %q", state, reason)
+        }
+    }
+    up.SetState(StateActive)
+
+    // two failures trip the circuit
+    up.breaker.record(up.Name(), false)
+    up.breaker.record(up.Name(), false)
+    if reason := up.Admit(); reason != "circuit open" {
+        t.Fatalf("tripped upstream admitted with %q", reason)
+    }
+    if rt.Stable().Admit() != "" {
+        t.Fatal("the stable group shares the canary's circuit")
+    }
+    rt.Stable().Done()
+
+    // after the cooldown a single probe goes through
+    up.breaker.mu.Lock()
+    up.breaker.openedAt = time.Now().Add(-time.Minute)
+    up.breaker.mu.Unlock()
+    if reason := up.Admit(); reason != "" {
+        t.Fatalf("probe refused: %s", reason)
+    }
+    if reason := up.Admit(); reason != "circuit open" {
+        t.Fatalf("second probe admitted with %q", reason)
+    }
+    up.breaker.record(up.Name(), true)
+    up.Done()
+    if reason := up.Admit(); reason != "" {
+        t.Fatalf("recovered upstream refused: %s", reason)
+    }
+    up.Done()
+
+    // restoring an upstream resets its circuit
+    up.breaker.record(up.Name(), false)
+    up.breaker.record(up.Name(), false)
+    up.SetState(StateActive)
+    if reason := up.Admit(); reason != "" {
+        t.Fatalf("restored upstream refused: %s", reason)
+    }
+    up.Done()
+}
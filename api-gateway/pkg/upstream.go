@@ -0,0 +1,221 @@
+package pkg
+
+import (
+    "log/slog"
+    "net/http"
+    "net/http/httputil"
+    "net/url"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/ansh0014/api/config"
+)
+
+// Upstream admin states. Draining and ejected upstreams refuse new requests;
+// a draining one still finishes the requests it already has.
+const (
+    StateActive   = "active"
+    StateDraining = "draining"
+    StateEjected  = "ejected"
+)
+
+// Circuit states.
+const (
+    CircuitClosed   = "closed"
+    CircuitOpen     = "open"
+    CircuitHalfOpen = "half_open"
+)
+
+// Upstream is the runtime state of one proxied service group.
+type Upstream struct {
+    Prefix string
+    Group  string
+    Target string
+
+    weight   int
+    users    map[string]bool
+    proxy    *httputil.ReverseProxy
+    state    atomic.Value // string
+    inflight atomic.Int64
+    breaker  *breaker
+}
+
+// UpstreamStatus is a snapshot of an upstream for operators.
+type UpstreamStatus struct {
+    Name     string        `json:"name"`
+    Prefix   string        `json:"prefix"`
+    Group    string        `json:"group"`
+    Weight   int           `json:"weight,omitempty"`
+    Target   string        `json:"target"`
+    State    string        `json:"state"`
+    InFlight int64         `json:"in_flight"`
+    Circuit  CircuitStatus `json:"circuit"`
+}
+
+func newUpstream(prefix, group, target string, cfg config.BreakerConfig) (*Upstream, error) {
+    t, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    u := &Upstream{Prefix: prefix, Group: group, Target: target, breaker: &breaker{
+        threshold: cfg.FailureThreshold,
+        cooldown:  cfg.Cooldown(),
+        state:     CircuitClosed,
+    }}
+    u.state.Store(StateActive)
+    u.proxy = newProxy(u, t)
+    return u, nil
+}
+
+// Name identifies the group: "booking", or "booking:canary" for extra groups.
+func (u *Upstream) Name() string {
+    name := strings.Trim(u.Prefix, "/")
+    if u.Group != StableGroup {
+        name += ":" + u.Group
+    }
+    return name
+}
+
+// ServeHTTP proxies r to this group.
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    u.proxy.ServeHTTP(w, r)
+}
+
+// Admit reserves a slot for a request. It returns "" on success, after which
+// the caller must call Done, or the reason the upstream is unavailable.
+func (u *Upstream) Admit() string {
+    if s := u.State(); s != StateActive {
+        return s
+    }
+    if !u.breaker.allow(time.Now()) {
+        return "circuit open"
+    }
+    u.inflight.Add(1)
+    return ""
+}
+
+// Done releases a slot taken by Admit.
+func (u *Upstream) Done() {
+    u.inflight.Add(-1)
+}
+
+// State returns the admin state.
+func (u *Upstream) State() string {
+    return u.state.Load().(string)
+}
+
+// SetState changes the admin state; restoring to active also resets the circuit.
+func (u *Upstream) SetState(s string) {
+    u.state.Store(s)
+    if s == StateActive {
+        u.breaker.reset()
+    }
+    slog.Info("upstream state changed", "upstream", u.Name(), "state", s)
+}
+
+// Status returns a snapshot for the admin API.
+func (u *Upstream) Status() UpstreamStatus {
+    return UpstreamStatus{
+        Name:     u.Name(),
+        Prefix:   u.Prefix,
+        Group:    u.Group,
+        Weight:   u.weight,
+        Target:   u.Target,
+        State:    u.State(),
+        InFlight: u.inflight.Load(),
+        Circuit:  u.breaker.status(),
+    }
+}
+
+// CircuitStatus describes a breaker.
+type CircuitStatus struct {
+    State     string     `json:"state"`
+    Failures  int        `json:"consecutive_failures"`
+    OpenedAt  *time.Time `json:"opened_at,omitempty"`
+    Threshold int        `json:"threshold"`
+}
+
+// breaker is a consecutive-failure circuit breaker with a single half-open probe.
+type breaker struct {
+    threshold int
+    cooldown  time.Duration
+
+    mu       sync.Mutex
+    state    string
+    failures int
+    openedAt time.Time
+    probing  bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+    if b.threshold <= 0 {
+        return true
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    switch b.state {
+    case CircuitOpen:
+        if now.Sub(b.openedAt) < b.cooldown {
+            return false
+        }
+        b.state, b.probing = CircuitHalfOpen, true
+        return true
+    case CircuitHalfOpen:
+        // one probe at a time
+        if b.probing {
+            return false
+        }
+        b.probing = true
+    }
+    return true
+}
+
+// record feeds the outcome of a proxied request into the breaker.
+func (b *breaker) record(prefix string, ok bool) {
+    if b.threshold <= 0 {
+        return
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.probing = false
+    if ok {
+        if b.state != CircuitClosed {
+            slog.Info("circuit closed", "upstream", prefix)
+        }
+        b.state, b.failures = CircuitClosed, 0
+        return
+    }
+    b.failures++
+    if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+        if b.state != CircuitOpen {
+            slog.Warn("circuit opened", "upstream", prefix, "failures", b.failures)
+        }
+        b.state, b.openedAt = CircuitOpen, time.Now()
+    }
+}
+
+// abort ends a request without an outcome, freeing the half-open probe.
+func (b *breaker) abort() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.probing = false
+}
+
+func (b *breaker) reset() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.state, b.failures, b.probing = CircuitClosed, 0, false
+}
+
+func (b *breaker) status() CircuitStatus {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    st := CircuitStatus{State: b.state, Failures: b.failures, Threshold: b.threshold}
+    if b.state != CircuitClosed {
+        t := b.openedAt
+        st.OpenedAt = &t
+    }
+    return st
+}
@@ -3,30 +3,64 @@ package routes
 import (
     "net/http"
 
+    "github.com/ansh0014/api/bff"
+    "github.com/ansh0014/api/cache"
+    "github.com/ansh0014/api/handler"
+    "github.com/ansh0014/api/health"
+    "github.com/ansh0014/api/internal"
+    "github.com/ansh0014/api/metrics"
     "github.com/ansh0014/api/pkg"
 
     "github.com/gorilla/mux"
 )
 
 // NewRouter returns a router that forwards matching paths to the proxy map.
-func NewRouter(pm *pkg.ProxyMap) http.Handler {
+// When c is non-nil, cacheable catalog routes are served through it.
+// hc reports the readiness of every upstream on /health/full and b serves the
+// composition endpoints under /bff.
+func NewRouter(pm *pkg.ProxyMap, c *cache.Cache, hc *health.Aggregator, b *bff.Handler) http.Handler {
     r := mux.NewRouter()
+    r.Use(routeLabel)
 
     // health
     r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
         w.Write([]byte("ok"))
     }).Methods("GET")
+    r.HandleFunc("/health/live", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+        w.Write([]byte("ok"))
+    }).Methods("GET")
+    r.HandleFunc("/health/full", hc.Handler).Methods("GET")
+
+    // backend-for-frontend composition
+    r.HandleFunc("/bff/shows/{id}", b.ShowPage).Methods("GET")
+    r.HandleFunc("/bff/checkout/{id}", b.CheckoutSummary).Methods("GET")
+
+    // metrics
+    r.Handle("/metrics", metrics.Handler()).Methods("GET")
 
     // catch-all: forward to upstream based on prefix
-    r.PathPrefix("/").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        p := pm.Route(r)
-        if p == nil {
-            http.NotFound(w, r)
-            return
-        }
-        p.ServeHTTP(w, r)
-    }))
+    proxy := handler.New(pm)
+    if c != nil {
+        // services invalidate catalog entries here after writes
+        r.Handle("/internal/cache/purge", c.PurgeHandler()).Methods("POST")
+        proxy = c.Middleware(proxy)
+    }
+    r.PathPrefix("/").Handler(proxy)
 
     return r
-}
\ No newline at end of file
+}
+
+// routeLabel records the matched route template for access logs and metrics.
+// The proxy handler refines it to the upstream prefix.
+func routeLabel(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if route := mux.CurrentRoute(r); route != nil {
+            if tpl, err := route.GetPathTemplate(); err == nil {
+                internal.RequestInfoFrom(r.Context()).Route = tpl
+            }
+        }
+        next.ServeHTTP(w, r)
+    })
+}
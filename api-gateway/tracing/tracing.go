@@ -0,0 +1,79 @@
+// Package tracing configures OpenTelemetry for the gateway.
+//
+// The exporter is chosen with OTEL_TRACES_EXPORTER: "otlp" sends spans over
+// OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (default localhost:4318), "stdout"
+// prints them for local runs, and "none" or unset disables export while still
+// propagating W3C trace context.
+package tracing
+
+import (
+    "context"
+    "fmt"
+    "os"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies this service in exported spans
+const ServiceName = "api-gateway"
+
+// Init installs the global tracer provider and W3C propagators. The returned
+// function flushes and stops the exporter.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+        propagation.TraceContext{},
+        propagation.Baggage{},
+    ))
+
+    var exporter sdktrace.SpanExporter
+    var err error
+    switch mode := os.Getenv("OTEL_TRACES_EXPORTER"); mode {
+    case "otlp":
+        exporter, err = otlptracehttp.New(ctx)
+    case "stdout":
+        exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+    case "", "none":
+        return func(context.Context) error { return nil }, nil
+    default:
+        return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", mode)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+        semconv.SchemaURL,
+        semconv.ServiceName(ServiceName),
+    ))
+    if err != nil {
+        return nil, err
+    }
+
+    tp := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+    )
+    otel.SetTracerProvider(tp)
+    return tp.Shutdown, nil
+}
+
+// Tracer returns a named tracer from the global provider
+func Tracer(name string) trace.Tracer {
+    return otel.Tracer(name)
+}
+
+// RecordError marks the span as failed when err is not nil
+func RecordError(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+}
@@ -0,0 +1,59 @@
+// Package gatewaycache lets the venue service invalidate catalog responses
+// cached by the api-gateway.
+package gatewaycache
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/ansh0014/venue/utils"
+)
+
+// Client calls the gateway's POST /internal/cache/purge endpoint.
+type Client struct {
+    baseURL string
+    token   string
+    http    *http.Client
+}
+
+// NewClient returns a client for the gateway at baseURL. It returns nil when
+// baseURL is empty, and a nil *Client ignores purges.
+func NewClient(baseURL, token string) *Client {
+    if baseURL == "" {
+        return nil
+    }
+    return &Client{baseURL: baseURL, token: token, http: &http.Client{Timeout: 2 * time.Second}}
+}
+
+// Purge drops every cached gateway response carrying one of the tags.
+func (c *Client) Purge(ctx context.Context, tags ...string) error {
+    if c == nil || len(tags) == 0 {
+        return nil
+    }
+    body, err := json.Marshal(map[string][]string{"tags": tags})
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/internal/cache/purge", bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-Internal-Token", c.token)
+    if id := utils.GetRequestIDFromContext(ctx); id != "" {
+        req.Header.Set(utils.RequestIDHeader, id)
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("gateway purge: status %d", resp.StatusCode)
+    }
+    return nil
+}
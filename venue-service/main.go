@@ -3,20 +3,30 @@ package main
 import (
     "context"
     "log"
+    "log/slog"
     "net/http"
     "os"
+    "os/signal"
+    "syscall"
     "time"
 
+    "github.com/ansh0014/venue/gatewaycache"
     "github.com/ansh0014/venue/handler"
+    "github.com/ansh0014/venue/health"
+    "github.com/ansh0014/venue/metrics"
     "github.com/ansh0014/venue/repository"
     "github.com/ansh0014/venue/routes"
     "github.com/ansh0014/venue/service"
+    "github.com/ansh0014/venue/tracing"
 
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
+    "go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 func main() {
+    slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
     mongoURI := os.Getenv("MONGO_URI")
     if mongoURI == "" {
         mongoURI = "mongodb://localhost:27017"
@@ -26,10 +36,16 @@ func main() {
         port = "8004"
     }
 
+    shutdownTracing, err := tracing.Init(context.Background())
+    if err != nil {
+        log.Fatalf("tracing: %v", err)
+    }
+    defer shutdownTracing(context.Background())
+
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+    client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI).SetMonitor(metrics.MongoMonitor(otelmongo.NewMonitor())))
     if err != nil {
         log.Fatalf("mongo connect: %v", err)
     }
@@ -39,10 +55,15 @@ func main() {
     db := client.Database("venue_service")
 
     repo := repository.NewRepository(db)
-    svc := service.NewService(repo)
+    var invalidator service.Invalidator
+    if gc := gatewaycache.NewClient(os.Getenv("GATEWAY_URL"), os.Getenv("GATEWAY_INTERNAL_TOKEN")); gc != nil {
+        invalidator = gc
+    }
+    svc := service.NewService(repo, invalidator)
     h := handler.NewHandler(svc)
 
-    router := routes.NewRouter(h)
+    checker := health.New(tracing.ServiceName).Add("mongo", health.Mongo(client))
+    router := routes.NewRouter(h, checker)
 
     srv := &http.Server{
         Addr:         ":" + port,
@@ -51,8 +72,27 @@ func main() {
         WriteTimeout: 20 * time.Second,
     }
 
-    log.Printf("venue-service listening on :%s (mongo: %s)", port, mongoURI)
-    if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-        log.Fatal(err)
+    sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    go func() {
+        log.Printf("venue-service listening on :%s (mongo: %s)", port, mongoURI)
+        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Fatal(err)
+        }
+    }()
+
+    <-sigCtx.Done()
+    stop()
+
+    // drain in-flight requests, then disconnect mongo
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 15*time.Second)
+    defer shutdownCancel()
+    log.Println("venue-service shutting down")
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        log.Printf("forced shutdown: %v", err)
+    }
+    if err := client.Disconnect(shutdownCtx); err != nil {
+        log.Printf("mongo disconnect: %v", err)
     }
 }
\ No newline at end of file
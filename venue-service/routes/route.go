@@ -4,12 +4,18 @@ import (
     "net/http"
 
     "github.com/gorilla/mux"
+    "go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
     "github.com/ansh0014/venue/handler"
+    "github.com/ansh0014/venue/health"
+    "github.com/ansh0014/venue/metrics"
+    "github.com/ansh0014/venue/middleware"
+    "github.com/ansh0014/venue/tracing"
 )
 
-func NewRouter(h *handler.Handler) http.Handler {
+func NewRouter(h *handler.Handler, checker *health.Checker) http.Handler {
     r := mux.NewRouter()
+    r.Use(otelmux.Middleware(tracing.ServiceName))
 
     // Venue
     r.HandleFunc("/venues", h.CreateVenue).Methods("POST")
@@ -29,6 +35,11 @@ func NewRouter(h *handler.Handler) http.Handler {
         w.WriteHeader(http.StatusOK)
         w.Write([]byte("ok"))
     }).Methods("GET")
+    r.HandleFunc("/health/live", checker.Live).Methods("GET")
+    r.HandleFunc("/health/ready", checker.Ready).Methods("GET")
 
-    return r
+    // metrics
+    r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+    return middleware.RequestID(middleware.Logging(r))
 }
\ No newline at end of file
@@ -2,24 +2,47 @@ package service
 
 import (
     "context"
+    "log/slog"
 
     "github.com/ansh0014/venue/model"
-	"github.com/ansh0014/venue/repository"
+    "github.com/ansh0014/venue/repository"
 
     "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Invalidator drops cached catalog responses (at the api-gateway) by tag.
+type Invalidator interface {
+    Purge(ctx context.Context, tags ...string) error
+}
+
 type Service struct {
-    repo *repository.Repository
+    repo  *repository.Repository
+    cache Invalidator
 }
 
-func NewService(r *repository.Repository) *Service {
-    return &Service{repo: r}
+// NewService creates the venue service. cache may be nil.
+func NewService(r *repository.Repository, cache Invalidator) *Service {
+    return &Service{repo: r, cache: cache}
+}
+
+// invalidate purges cached catalog responses after a write. Failures are
+// logged only: entries still expire by TTL.
+func (s *Service) invalidate(ctx context.Context, tags ...string) {
+    if s.cache == nil {
+        return
+    }
+    if err := s.cache.Purge(ctx, tags...); err != nil {
+        slog.WarnContext(ctx, "cache purge failed", "tags", tags, "error", err.Error())
+    }
 }
 
 // Venue methods
 func (s *Service) CreateVenue(ctx context.Context, v *model.Venue) (*model.Venue, error) {
-    return s.repo.CreateVenue(ctx, v)
+    created, err := s.repo.CreateVenue(ctx, v)
+    if err == nil {
+        s.invalidate(ctx, "venues")
+    }
+    return created, err
 }
 
 func (s *Service) GetVenue(ctx context.Context, id primitive.ObjectID) (*model.Venue, error) {
@@ -36,7 +59,11 @@ func (s *Service) ListVenues(ctx context.Context, filter map[string]interface{},
 
 // Hall methods
 func (s *Service) CreateHall(ctx context.Context, h *model.Hall) (*model.Hall, error) {
-    return s.repo.CreateHall(ctx, h)
+    created, err := s.repo.CreateHall(ctx, h)
+    if err == nil {
+        s.invalidate(ctx, "venues", "halls")
+    }
+    return created, err
 }
 
 func (s *Service) GetHall(ctx context.Context, id primitive.ObjectID) (*model.Hall, error) {
@@ -49,7 +76,11 @@ func (s *Service) ListHalls(ctx context.Context, venueID primitive.ObjectID) ([]
 
 // Seat methods
 func (s *Service) AddSeat(ctx context.Context, seat *model.Seat) (*model.Seat, error) {
-    return s.repo.AddSeat(ctx, seat)
+    created, err := s.repo.AddSeat(ctx, seat)
+    if err == nil {
+        s.invalidate(ctx, "halls")
+    }
+    return created, err
 }
 
 func (s *Service) ListSeats(ctx context.Context, hallID primitive.ObjectID) ([]model.Seat, error) {
@@ -57,5 +88,9 @@ func (s *Service) ListSeats(ctx context.Context, hallID primitive.ObjectID) ([]m
 }
 
 func (s *Service) SetSeatActive(ctx context.Context, seatID primitive.ObjectID, active bool) error {
-    return s.repo.UpdateSeatAvailability(ctx, seatID, active)
+    err := s.repo.UpdateSeatAvailability(ctx, seatID, active)
+    if err == nil {
+        s.invalidate(ctx, "halls")
+    }
+    return err
 }
\ No newline at end of file
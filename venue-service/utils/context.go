@@ -0,0 +1,32 @@
+package utils
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+)
+
+// RequestIDHeader is the header used to correlate a request across services
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID in the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+    return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// GetRequestIDFromContext returns the request ID stored in the context, or ""
+func GetRequestIDFromContext(ctx context.Context) string {
+    requestID, _ := ctx.Value(requestIDKey{}).(string)
+    return requestID
+}
+
+// NewRequestID generates a random request ID for requests that arrive without one
+func NewRequestID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        panic("crypto/rand unavailable: " + err.Error())
+    }
+    return hex.EncodeToString(b)
+}